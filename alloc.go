@@ -36,7 +36,6 @@ func (t *allocTracker) register(obj interface{}, size uint64) {
 	key := reflect.ValueOf(obj).Pointer()
 
 	if _, ok := t.allocs[key]; ok { // object has already been tracked, no need to add it.
-		t.mtx.Unlock()
 		return
 	}
 
@@ -0,0 +1,372 @@
+package goparquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// The split block bloom filter (SBBF) consists of blocks of 256 bits, each of them split into eight 32 bit words.
+// Inserting a value sets one bit in each word of exactly one block. The algorithm is described at
+// https://github.com/apache/parquet-format/blob/master/BloomFilter.md
+
+const (
+	bloomFilterBlockSize   = 32
+	bloomFilterMinNumBytes = bloomFilterBlockSize
+	bloomFilterMaxNumBytes = 128 * 1024 * 1024
+)
+
+var bloomFilterSalt = [8]uint32{
+	0x47b6137b, 0x44974d91, 0x8824ad5b, 0xa2b7289d,
+	0x705495c7, 0x2df1424b, 0x9efc4947, 0x5c6bfb31,
+}
+
+type splitBlockBloomFilter struct {
+	words []uint32
+}
+
+func newSplitBlockBloomFilter(numBytes int) *splitBlockBloomFilter {
+	return &splitBlockBloomFilter{
+		words: make([]uint32, numBytes/4),
+	}
+}
+
+// optimalBloomFilterNumBytes returns the size of a bloom filter in bytes to hold the
+// provided number of distinct values with the provided false positive rate.
+func optimalBloomFilterNumBytes(numDistinctValues int64, falsePositiveRate float64) int {
+	if numDistinctValues < 1 {
+		numDistinctValues = 1
+	}
+
+	numBits := -8 * float64(numDistinctValues) / math.Log(1-math.Pow(falsePositiveRate, 1.0/8))
+
+	numBytes := bloomFilterMinNumBytes
+	for numBytes < bloomFilterMaxNumBytes && float64(numBytes*8) < numBits {
+		numBytes *= 2
+	}
+
+	return numBytes
+}
+
+func (f *splitBlockBloomFilter) numBlocks() uint64 {
+	return uint64(len(f.words) / 8)
+}
+
+func (f *splitBlockBloomFilter) block(hash uint64) []uint32 {
+	idx := ((hash >> 32) * f.numBlocks()) >> 32
+	return f.words[idx*8 : idx*8+8]
+}
+
+func (f *splitBlockBloomFilter) insert(hash uint64) {
+	block := f.block(hash)
+	key := uint32(hash)
+	for i := range block {
+		block[i] |= 1 << ((key * bloomFilterSalt[i]) >> 27)
+	}
+}
+
+func (f *splitBlockBloomFilter) check(hash uint64) bool {
+	block := f.block(hash)
+	key := uint32(hash)
+	for i := range block {
+		if block[i]&(1<<((key*bloomFilterSalt[i])>>27)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *splitBlockBloomFilter) write(ctx context.Context, w io.Writer, encryptor *columnChunkEncryptor) error {
+	header := &parquet.BloomFilterHeader{
+		NumBytes: int32(len(f.words) * 4),
+		Algorithm: &parquet.BloomFilterAlgorithm{
+			BLOCK: parquet.NewSplitBlockAlgorithm(),
+		},
+		Hash: &parquet.BloomFilterHash{
+			XXHASH: parquet.NewXxHash(),
+		},
+		Compression: &parquet.BloomFilterCompression{
+			UNCOMPRESSED: parquet.NewUncompressed(),
+		},
+	}
+
+	if err := encryptor.writeThrift(ctx, moduleBloomFilterHeader, header, w); err != nil {
+		return err
+	}
+
+	bitset := make([]byte, len(f.words)*4)
+	for i, word := range f.words {
+		binary.LittleEndian.PutUint32(bitset[i*4:], word)
+	}
+
+	return encryptor.writeModule(moduleBloomFilterBitset, bitset, w)
+}
+
+// bloomFilterHash computes the hash of a value as it is used in bloom filters, which is the
+// xxHash64 value of the plain encoding of the value. For byte arrays, the length is not part
+// of the hashed data.
+func bloomFilterHash(v interface{}) (uint64, error) {
+	var buf [8]byte
+	switch x := v.(type) {
+	case int32:
+		binary.LittleEndian.PutUint32(buf[:4], uint32(x))
+		return xxhash64(buf[:4]), nil
+	case int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(x))
+		return xxhash64(buf[:]), nil
+	case float32:
+		binary.LittleEndian.PutUint32(buf[:4], math.Float32bits(x))
+		return xxhash64(buf[:4]), nil
+	case float64:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(x))
+		return xxhash64(buf[:]), nil
+	case []byte:
+		return xxhash64(x), nil
+	case [12]byte:
+		return xxhash64(x[:]), nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T for bloom filter", v)
+	}
+}
+
+type bloomFilterOptions struct {
+	path              ColumnPath
+	numDistinctValues int64
+	falsePositiveRate float64
+}
+
+// bloomFilterBuilder collects the hash values of a column chunk to create a bloom filter
+// once all values are known. This allows the filter to be sized according to the actual
+// number of distinct values if no number of distinct values was configured.
+type bloomFilterBuilder struct {
+	opts   *bloomFilterOptions
+	hashes map[uint64]struct{}
+}
+
+func newBloomFilterBuilder(col *Column, opts *bloomFilterOptions) (*bloomFilterBuilder, error) {
+	if *col.Type() == parquet.Type_BOOLEAN {
+		return nil, fmt.Errorf("column %s: bloom filters are not supported for boolean columns", col.path.flatName())
+	}
+
+	if opts.falsePositiveRate <= 0 || opts.falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("column %s: invalid bloom filter false positive rate %f", col.path.flatName(), opts.falsePositiveRate)
+	}
+
+	return &bloomFilterBuilder{
+		opts:   opts,
+		hashes: make(map[uint64]struct{}),
+	}, nil
+}
+
+func (b *bloomFilterBuilder) addValues(values []interface{}) error {
+	if b == nil {
+		return nil
+	}
+
+	for _, v := range values {
+		h, err := bloomFilterHash(v)
+		if err != nil {
+			return err
+		}
+		b.hashes[h] = struct{}{}
+	}
+
+	return nil
+}
+
+func (b *bloomFilterBuilder) build() *splitBlockBloomFilter {
+	ndv := b.opts.numDistinctValues
+	if ndv <= 0 {
+		ndv = int64(len(b.hashes))
+	}
+
+	f := newSplitBlockBloomFilter(optimalBloomFilterNumBytes(ndv, b.opts.falsePositiveRate))
+	for h := range b.hashes {
+		f.insert(h)
+	}
+
+	return f
+}
+
+// newBloomFilterBuilders creates the bloom filter builders for all data columns of a
+// row group. For columns that have no bloom filter configured, the builder is nil.
+func newBloomFilterBuilders(sch *schema, bloomFilters []*bloomFilterOptions) (map[*Column]*bloomFilterBuilder, error) {
+	builders := make(map[*Column]*bloomFilterBuilder)
+	for _, opts := range bloomFilters {
+		col := sch.GetColumnByPath(opts.path)
+		if col == nil || !col.DataColumn() {
+			return nil, fmt.Errorf("bloom filter configured for unknown column %s", opts.path.flatName())
+		}
+		if _, ok := builders[col]; ok {
+			return nil, fmt.Errorf("bloom filter configured multiple times for column %s", opts.path.flatName())
+		}
+
+		b, err := newBloomFilterBuilder(col, opts)
+		if err != nil {
+			return nil, err
+		}
+		builders[col] = b
+	}
+	return builders, nil
+}
+
+// BloomFilter is a split block bloom filter of a column chunk. It can be used to
+// find out whether a column chunk definitely doesn't contain a particular value
+// without having to read the column chunk.
+type BloomFilter struct {
+	typ    parquet.Type
+	filter *splitBlockBloomFilter
+}
+
+// MightContain returns false if the column chunk definitely doesn't contain the value,
+// and true if it may contain the value. The value needs to be provided in the Go type
+// that is used for the column's physical type, i.e. int32, int64, float32, float64,
+// []byte or [12]byte for INT96. Other integer types are converted to int32 or int64
+// depending on the column's type, and strings are accepted for byte array columns.
+// If the value can't be converted to the column's physical type, the value can't be
+// ruled out, and true is returned.
+func (bf *BloomFilter) MightContain(value interface{}) bool {
+	v, ok := bloomFilterValue(bf.typ, value)
+	if !ok {
+		return true
+	}
+
+	h, err := bloomFilterHash(v)
+	if err != nil {
+		return true
+	}
+
+	return bf.filter.check(h)
+}
+
+func bloomFilterValue(typ parquet.Type, value interface{}) (interface{}, bool) {
+	switch typ {
+	case parquet.Type_INT32:
+		switch x := value.(type) {
+		case int32:
+			return x, true
+		case int:
+			return int32(x), int(int32(x)) == x
+		case int8:
+			return int32(x), true
+		case int16:
+			return int32(x), true
+		case uint8:
+			return int32(x), true
+		case uint16:
+			return int32(x), true
+		case uint32:
+			return int32(x), true
+		}
+	case parquet.Type_INT64:
+		switch x := value.(type) {
+		case int64:
+			return x, true
+		case int:
+			return int64(x), true
+		case int8:
+			return int64(x), true
+		case int16:
+			return int64(x), true
+		case int32:
+			return int64(x), true
+		case uint8:
+			return int64(x), true
+		case uint16:
+			return int64(x), true
+		case uint32:
+			return int64(x), true
+		case uint64:
+			return int64(x), true
+		}
+	case parquet.Type_FLOAT:
+		if x, ok := value.(float32); ok {
+			return x, true
+		}
+	case parquet.Type_DOUBLE:
+		switch x := value.(type) {
+		case float64:
+			return x, true
+		case float32:
+			return float64(x), true
+		}
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		switch x := value.(type) {
+		case []byte:
+			return x, true
+		case string:
+			return []byte(x), true
+		}
+	case parquet.Type_INT96:
+		if x, ok := value.([12]byte); ok {
+			return x, true
+		}
+	}
+	return nil, false
+}
+
+// readBloomFilter reads the bloom filter of a column chunk. If the column chunk has no bloom filter,
+// nil is returned.
+func (f *FileReader) readBloomFilter(ctx context.Context, chunk *parquet.ColumnChunk, decryptor *columnChunkDecryptor) (*BloomFilter, error) {
+	if chunk.MetaData == nil || chunk.MetaData.BloomFilterOffset == nil {
+		return nil, nil
+	}
+
+	offset := *chunk.MetaData.BloomFilterOffset
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid bloom filter offset %d", offset)
+	}
+
+	rs := f.newReader()
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	header := &parquet.BloomFilterHeader{}
+	if err := decryptor.readThrift(ctx, moduleBloomFilterHeader, header, rs, f.allocTracker); err != nil {
+		return nil, fmt.Errorf("reading bloom filter header failed: %w", err)
+	}
+
+	if header.Algorithm == nil || header.Algorithm.BLOCK == nil {
+		return nil, errors.New("unsupported bloom filter algorithm")
+	}
+	if header.Hash == nil || header.Hash.XXHASH == nil {
+		return nil, errors.New("unsupported bloom filter hash")
+	}
+	if header.Compression == nil || header.Compression.UNCOMPRESSED == nil {
+		return nil, errors.New("unsupported bloom filter compression")
+	}
+	if header.NumBytes < bloomFilterMinNumBytes || header.NumBytes > bloomFilterMaxNumBytes || header.NumBytes%bloomFilterBlockSize != 0 {
+		return nil, fmt.Errorf("invalid bloom filter size %d", header.NumBytes)
+	}
+
+	f.allocTracker.test(uint64(header.NumBytes))
+
+	var r io.Reader = rs
+	if decryptor != nil {
+		bitset, err := decryptor.readModule(moduleBloomFilterBitset, rs, f.allocTracker)
+		if err != nil {
+			return nil, fmt.Errorf("reading bloom filter failed: %w", err)
+		}
+		if len(bitset) != int(header.NumBytes) {
+			return nil, fmt.Errorf("bloom filter size %d doesn't match the expected size %d", len(bitset), header.NumBytes)
+		}
+		r = bytes.NewReader(bitset)
+	}
+
+	filter := newSplitBlockBloomFilter(int(header.NumBytes))
+	if err := binary.Read(r, binary.LittleEndian, filter.words); err != nil {
+		return nil, fmt.Errorf("reading bloom filter failed: %w", err)
+	}
+
+	return &BloomFilter{
+		typ:    chunk.MetaData.Type,
+		filter: filter,
+	}, nil
+}
@@ -0,0 +1,266 @@
+package goparquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXXHash64(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"message digest", 0x066ed728fceeb3be},
+		{strings.Repeat("abcdefghijklmnopqrstuvwxyz0123456789", 3), 0x873af01d71bd0c23},
+		{"0123456789abcdef0123456789abcdef0", 0xe87684f08d6d0816},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, xxhash64([]byte(tt.input)), "hash of %q doesn't match", tt.input)
+	}
+}
+
+func TestOptimalBloomFilterNumBytes(t *testing.T) {
+	require.Equal(t, bloomFilterMinNumBytes, optimalBloomFilterNumBytes(0, 0.01))
+	require.Equal(t, bloomFilterMinNumBytes, optimalBloomFilterNumBytes(1, 0.01))
+	require.Equal(t, bloomFilterMaxNumBytes, optimalBloomFilterNumBytes(1<<40, 0.01))
+
+	prev := 0
+	for _, ndv := range []int64{10, 100, 1000, 10000, 100000} {
+		n := optimalBloomFilterNumBytes(ndv, 0.01)
+		require.Equal(t, 0, n&(n-1), "%d is not a power of two", n)
+		require.True(t, n >= prev)
+		prev = n
+	}
+
+	require.True(t, optimalBloomFilterNumBytes(10000, 0.001) > optimalBloomFilterNumBytes(10000, 0.1))
+}
+
+func TestSplitBlockBloomFilter(t *testing.T) {
+	const numValues = 10000
+
+	f := newSplitBlockBloomFilter(optimalBloomFilterNumBytes(numValues, 0.01))
+	for i := 0; i < numValues; i++ {
+		h, err := bloomFilterHash(int64(i))
+		require.NoError(t, err)
+		f.insert(h)
+	}
+
+	for i := 0; i < numValues; i++ {
+		h, err := bloomFilterHash(int64(i))
+		require.NoError(t, err)
+		require.True(t, f.check(h), "value %d not found", i)
+	}
+
+	falsePositives := 0
+	for i := numValues; i < 2*numValues; i++ {
+		h, err := bloomFilterHash(int64(i))
+		require.NoError(t, err)
+		if f.check(h) {
+			falsePositives++
+		}
+	}
+	require.True(t, falsePositives < numValues/50, "too many false positives: %d", falsePositives)
+}
+
+func TestBloomFilterHash(t *testing.T) {
+	h1, err := bloomFilterHash([]byte("foo"))
+	require.NoError(t, err)
+	require.Equal(t, xxhash64([]byte("foo")), h1)
+
+	h2, err := bloomFilterHash(int32(42))
+	require.NoError(t, err)
+	require.Equal(t, xxhash64([]byte{42, 0, 0, 0}), h2)
+
+	_, err = bloomFilterHash(true)
+	require.Error(t, err)
+}
+
+func TestWriteBloomFilter(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		required double value;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd),
+		WithBloomFilter(ColumnPath{"id"}, 1000, 0.01),
+		WithBloomFilter(ColumnPath{"name"}, 0, 0.05),
+	)
+
+	for i := 0; i < 1000; i++ {
+		data := map[string]interface{}{"id": int64(i), "value": float64(i) / 10}
+		if i%2 == 0 {
+			data["name"] = []byte(fmt.Sprintf("name %d", i))
+		}
+		require.NoError(t, fw.AddData(data))
+		if i == 499 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	data := buf.Bytes()
+
+	meta, err := ReadFileMetaData(bytes.NewReader(data), true)
+	require.NoError(t, err)
+	require.Len(t, meta.RowGroups, 2)
+
+	readFilter := func(offset int64) *splitBlockBloomFilter {
+		r := bytes.NewReader(data[offset:])
+
+		header := &parquet.BloomFilterHeader{}
+		require.NoError(t, readThrift(context.Background(), header, r))
+		require.NotNil(t, header.Algorithm.BLOCK)
+		require.NotNil(t, header.Hash.XXHASH)
+		require.NotNil(t, header.Compression.UNCOMPRESSED)
+
+		f := newSplitBlockBloomFilter(int(header.NumBytes))
+		require.NoError(t, binary.Read(r, binary.LittleEndian, f.words))
+		return f
+	}
+
+	for i, rg := range meta.RowGroups {
+		require.Nil(t, rg.Columns[2].MetaData.BloomFilterOffset)
+
+		require.NotNil(t, rg.Columns[0].MetaData.BloomFilterOffset)
+		idFilter := readFilter(*rg.Columns[0].MetaData.BloomFilterOffset)
+		require.Equal(t, optimalBloomFilterNumBytes(1000, 0.01), len(idFilter.words)*4)
+
+		require.NotNil(t, rg.Columns[1].MetaData.BloomFilterOffset)
+		nameFilter := readFilter(*rg.Columns[1].MetaData.BloomFilterOffset)
+		require.Equal(t, optimalBloomFilterNumBytes(250, 0.05), len(nameFilter.words)*4)
+
+		for j := i * 500; j < (i+1)*500; j++ {
+			h, err := bloomFilterHash(int64(j))
+			require.NoError(t, err)
+			require.True(t, idFilter.check(h), "id %d not found in row group %d", j, i)
+
+			if j%2 == 0 {
+				h, err := bloomFilterHash([]byte(fmt.Sprintf("name %d", j)))
+				require.NoError(t, err)
+				require.True(t, nameFilter.check(h), "name %d not found in row group %d", j, i)
+			}
+		}
+	}
+}
+
+func TestWriteBloomFilterInvalidColumns(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		required boolean flag;
+	}`)
+	require.NoError(t, err)
+
+	for _, opt := range []FileWriterOption{
+		WithBloomFilter(ColumnPath{"flag"}, 0, 0.01),
+		WithBloomFilter(ColumnPath{"foo"}, 0, 0.01),
+		WithBloomFilter(ColumnPath{"id"}, 0, 0),
+		WithBloomFilter(ColumnPath{"id"}, 0, 1),
+	} {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, WithSchemaDefinition(sd), opt)
+		require.NoError(t, fw.AddData(map[string]interface{}{"id": int64(1), "flag": true}))
+		require.Error(t, fw.Close())
+	}
+}
+
+func TestFileReaderBloomFilter(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		required binary name (STRING);
+		required int32 num;
+		required float value;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd),
+		WithBloomFilter(ColumnPath{"id"}, 0, 0.01),
+		WithBloomFilter(ColumnPath{"name"}, 0, 0.01),
+		WithBloomFilter(ColumnPath{"num"}, 0, 0.01),
+	)
+
+	for i := 0; i < 200; i++ {
+		require.NoError(t, fw.AddData(map[string]interface{}{
+			"id":    int64(i),
+			"name":  []byte(fmt.Sprintf("name %d", i)),
+			"num":   int32(i * 3),
+			"value": float32(i),
+		}))
+		if i == 99 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	for rg := 0; rg < 2; rg++ {
+		idFilter, err := r.BloomFilter(rg, ColumnPath{"id"})
+		require.NoError(t, err)
+		require.NotNil(t, idFilter)
+
+		nameFilter, err := r.BloomFilter(rg, ColumnPath{"name"})
+		require.NoError(t, err)
+		require.NotNil(t, nameFilter)
+
+		numFilter, err := r.BloomFilter(rg, ColumnPath{"num"})
+		require.NoError(t, err)
+		require.NotNil(t, numFilter)
+
+		for i := rg * 100; i < (rg+1)*100; i++ {
+			require.True(t, idFilter.MightContain(int64(i)))
+			require.True(t, idFilter.MightContain(i))
+			require.True(t, nameFilter.MightContain([]byte(fmt.Sprintf("name %d", i))))
+			require.True(t, nameFilter.MightContain(fmt.Sprintf("name %d", i)))
+			require.True(t, numFilter.MightContain(int32(i*3)))
+			require.True(t, numFilter.MightContain(i*3))
+		}
+
+		// values of the other row group should mostly be ruled out.
+		found := 0
+		for i := (1 - rg) * 100; i < (2-rg)*100; i++ {
+			if idFilter.MightContain(int64(i)) {
+				found++
+			}
+		}
+		require.True(t, found < 10, "too many false positives: %d", found)
+
+		// values of an incompatible type can't be ruled out.
+		require.True(t, idFilter.MightContain("foo"))
+
+		valueFilter, err := r.BloomFilter(rg, ColumnPath{"value"})
+		require.NoError(t, err)
+		require.Nil(t, valueFilter)
+	}
+
+	_, err = r.BloomFilter(2, ColumnPath{"id"})
+	require.Error(t, err)
+
+	_, err = r.BloomFilter(0, ColumnPath{"foo"})
+	require.Error(t, err)
+
+	// reading the bloom filter must not interfere with reading rows.
+	for i := 0; i < 200; i++ {
+		row, err := r.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, int64(i), row["id"])
+	}
+}
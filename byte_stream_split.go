@@ -0,0 +1,81 @@
+package goparquet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// The BYTE_STREAM_SPLIT encoding scatters the bytes of the plain encoded values into one stream
+// per byte position, i.e. the first stream contains the first byte of all values, the second stream
+// the second byte of all values and so on. The streams are concatenated without any padding. This
+// doesn't reduce the size of the data, but usually improves the compression ratio of floating
+// point data.
+
+type byteStreamSplitDecoder struct {
+	data  []byte
+	width int
+	count int
+	pos   int
+}
+
+func (d *byteStreamSplitDecoder) init(r io.Reader, width int) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if len(data)%width != 0 {
+		return fmt.Errorf("byte stream split data size %d is not a multiple of %d", len(data), width)
+	}
+
+	d.data = data
+	d.width = width
+	d.count = len(data) / width
+	d.pos = 0
+
+	return nil
+}
+
+// next gathers the bytes of the next value into buf. It returns false if all values were read.
+func (d *byteStreamSplitDecoder) next(buf []byte) bool {
+	if d.pos >= d.count {
+		return false
+	}
+
+	for i := 0; i < d.width; i++ {
+		buf[i] = d.data[i*d.count+d.pos]
+	}
+	d.pos++
+
+	return true
+}
+
+type byteStreamSplitEncoder struct {
+	w     io.Writer
+	width int
+	buf   bytes.Buffer
+}
+
+func (e *byteStreamSplitEncoder) init(w io.Writer, width int) error {
+	e.w = w
+	e.width = width
+	e.buf.Reset()
+
+	return nil
+}
+
+// Close scatters the bytes of all values that were added to the encoder and writes the streams.
+func (e *byteStreamSplitEncoder) Close() error {
+	data := e.buf.Bytes()
+	count := len(data) / e.width
+
+	out := make([]byte, len(data))
+	for i := 0; i < count; i++ {
+		for j := 0; j < e.width; j++ {
+			out[j*count+i] = data[i*e.width+j]
+		}
+	}
+
+	return writeFull(e.w, out)
+}
@@ -0,0 +1,33 @@
+package goparquet
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteStreamSplitLayout(t *testing.T) {
+	values := []interface{}{math.Float32frombits(0x04030201), math.Float32frombits(0x14131211), math.Float32frombits(0x24232221)}
+
+	var buf bytes.Buffer
+	enc := &floatByteStreamSplitEncoder{}
+	require.NoError(t, encodeValue(&buf, enc, values))
+	require.Equal(t, []byte{
+		0x01, 0x11, 0x21,
+		0x02, 0x12, 0x22,
+		0x03, 0x13, 0x23,
+		0x04, 0x14, 0x24,
+	}, buf.Bytes())
+
+	dec := &floatByteStreamSplitDecoder{}
+	require.NoError(t, dec.init(bytes.NewReader(buf.Bytes())))
+	dst := make([]interface{}, 3)
+	n, err := dec.decodeValues(dst)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, values, dst)
+
+	require.Error(t, (&doubleByteStreamSplitDecoder{}).init(bytes.NewReader(buf.Bytes())))
+}
@@ -125,6 +125,8 @@ func getValuesDecoder(pageEncoding parquet.Encoding, typ *parquet.SchemaElement,
 		switch pageEncoding {
 		case parquet.Encoding_PLAIN:
 			return &floatPlainDecoder{}, nil
+		case parquet.Encoding_BYTE_STREAM_SPLIT:
+			return &floatByteStreamSplitDecoder{}, nil
 		case parquet.Encoding_RLE_DICTIONARY:
 			return &dictDecoder{uniqueValues: dictValues}, nil
 		}
@@ -133,6 +135,8 @@ func getValuesDecoder(pageEncoding parquet.Encoding, typ *parquet.SchemaElement,
 		switch pageEncoding {
 		case parquet.Encoding_PLAIN:
 			return &doublePlainDecoder{}, nil
+		case parquet.Encoding_BYTE_STREAM_SPLIT:
+			return &doubleByteStreamSplitDecoder{}, nil
 		case parquet.Encoding_RLE_DICTIONARY:
 			return &dictDecoder{uniqueValues: dictValues}, nil
 		}
@@ -179,17 +183,43 @@ func readPageBlock(r io.Reader, codec parquet.CompressionCodec, compressedSize i
 	return dataPageBlock, nil
 }
 
-func (f *FileReader) readPages(ctx context.Context, r *offsetReader, col *Column, chunkMeta *parquet.ColumnMetaData, dDecoder, rDecoder getLevelDecoder) (pages []pageReader, useDict bool, err error) {
+func (f *FileReader) readPages(ctx context.Context, r *offsetReader, col *Column, chunkMeta *parquet.ColumnMetaData, dataPageOffset int64, firstPageOrdinal int, decryptor *columnChunkDecryptor, dDecoder, rDecoder getLevelDecoder) (pages []pageReader, useDict bool, err error) {
 	var (
-		dictPage *dictPageReader
+		dictPage    *dictPageReader
+		pageOrdinal int
 	)
 
 	for {
 		if chunkMeta.TotalCompressedSize-r.Count() <= 0 {
 			break
 		}
+		pageOffset := r.offset
+
+		// the page header needs to be decrypted before the page type is known, so the dictionary
+		// page is identified by its offset.
+		isDict := dictPage == nil && chunkMeta.DictionaryPageOffset != nil && *chunkMeta.DictionaryPageOffset == pageOffset
+		pd, err := decryptor.pageDecryptor(isDict, pageOrdinal)
+		if err != nil {
+			return nil, false, err
+		}
+
 		ph := &parquet.PageHeader{}
-		if err := readThrift(ctx, ph, r); err != nil {
+		if err := pd.readHeader(ctx, ph, r, f.allocTracker); err != nil {
+			return nil, false, err
+		}
+
+		// data pages before the requested data page are skipped without reading them.
+		if ph.Type != parquet.PageType_DICTIONARY_PAGE && pageOffset < dataPageOffset {
+			if _, err := r.Seek(dataPageOffset, io.SeekStart); err != nil {
+				return nil, false, err
+			}
+			pageOrdinal = firstPageOrdinal
+			continue
+		}
+
+		var pr io.Reader
+		ph, pr, err = pd.decryptPage(r, ph, f.schemaReader.validateCRC, f.allocTracker)
+		if err != nil {
 			return nil, false, err
 		}
 
@@ -209,21 +239,21 @@ func (f *FileReader) readPages(ctx context.Context, r *offsetReader, col *Column
 				return nil, false, err
 			}
 
-			if err := p.read(r, ph, chunkMeta.Codec); err != nil {
+			if err := p.read(pr, ph, chunkMeta.Codec); err != nil {
 				return nil, false, err
 			}
 
 			dictPage = p
 
 			// Go to the next data Page
-			// if we have a DictionaryPageOffset we should return to DataPageOffset
-			if chunkMeta.DictionaryPageOffset != nil {
-				if *chunkMeta.DictionaryPageOffset != r.offset {
-					if _, err := r.Seek(chunkMeta.DataPageOffset, io.SeekStart); err != nil {
-						return nil, false, err
-					}
+			// if we have a data page offset we should go there, either because the dictionary page is
+			// followed by something else, or because we want to start at a later data page.
+			if dataPageOffset > 0 && dataPageOffset != r.offset {
+				if _, err := r.Seek(dataPageOffset, io.SeekStart); err != nil {
+					return nil, false, err
 				}
 			}
+			pageOrdinal = firstPageOrdinal
 			continue // go to next page
 		}
 
@@ -253,10 +283,11 @@ func (f *FileReader) readPages(ctx context.Context, r *offsetReader, col *Column
 			return nil, false, err
 		}
 
-		if err := p.read(r, ph, chunkMeta.Codec, f.schemaReader.validateCRC); err != nil {
+		if err := p.read(pr, ph, chunkMeta.Codec, f.schemaReader.validateCRC); err != nil {
 			return nil, false, err
 		}
 		pages = append(pages, p)
+		pageOrdinal++
 	}
 
 	return pages, dictPage != nil, nil
@@ -268,6 +299,8 @@ func clone(in []interface{}) []interface{} {
 	return out
 }
 
+// skipChunk validates the meta data of a column chunk that is not read. As every column chunk
+// is read from its own offset, nothing needs to be read to skip it.
 func (f *FileReader) skipChunk(col *Column, chunk *parquet.ColumnChunk) error {
 	if chunk.FilePath != nil {
 		return fmt.Errorf("nyi: data is in another file: '%s'", *chunk.FilePath)
@@ -286,17 +319,13 @@ func (f *FileReader) skipChunk(col *Column, chunk *parquet.ColumnChunk) error {
 			typ, chunk.MetaData.Type)
 	}
 
-	offset := chunk.MetaData.DataPageOffset
-	if chunk.MetaData.DictionaryPageOffset != nil {
-		offset = *chunk.MetaData.DictionaryPageOffset
-	}
-
-	offset += chunk.MetaData.TotalCompressedSize
-	_, err := f.reader.Seek(offset, io.SeekStart)
-	return err
+	return nil
 }
 
-func (f *FileReader) readChunk(ctx context.Context, col *Column, chunk *parquet.ColumnChunk) (pages []pageReader, useDict bool, err error) {
+// readChunk reads the pages of a column chunk. If firstPage is not nil, all data pages before the
+// provided page location are skipped, firstPageOrdinal is the ordinal of that page within the column
+// chunk. The dictionary page is always read.
+func (f *FileReader) readChunk(ctx context.Context, r io.ReadSeeker, col *Column, chunk *parquet.ColumnChunk, firstPage *parquet.PageLocation, firstPageOrdinal int, decryptor *columnChunkDecryptor) (pages []pageReader, useDict bool, err error) {
 	if chunk.FilePath != nil {
 		return nil, false, fmt.Errorf("nyi: data is in another file: '%s'", *chunk.FilePath)
 	}
@@ -314,18 +343,25 @@ func (f *FileReader) readChunk(ctx context.Context, col *Column, chunk *parquet.
 			typ, chunk.MetaData.Type)
 	}
 
-	offset := chunk.MetaData.DataPageOffset
+	chunkOffset := chunk.MetaData.DataPageOffset
+	var dataPageOffset int64
 	if chunk.MetaData.DictionaryPageOffset != nil {
-		offset = *chunk.MetaData.DictionaryPageOffset
+		chunkOffset = *chunk.MetaData.DictionaryPageOffset
+		dataPageOffset = chunk.MetaData.DataPageOffset
 	}
+
+	if firstPage != nil {
+		dataPageOffset = firstPage.Offset
+	}
+
 	// Seek to the beginning of the first Page
-	if _, err := f.reader.Seek(offset, io.SeekStart); err != nil {
+	if _, err := r.Seek(chunkOffset, io.SeekStart); err != nil {
 		return nil, false, err
 	}
 
 	reader := &offsetReader{
-		inner:  f.reader,
-		offset: offset,
+		inner:  r,
+		offset: chunkOffset,
 		count:  0,
 	}
 
@@ -358,13 +394,18 @@ func (f *FileReader) readChunk(ctx context.Context, col *Column, chunk *parquet.
 			return &levelDecoderWrapper{decoder: constDecoder(0), max: col.MaxDefinitionLevel()}, nil
 		}
 	}
-	return f.readPages(ctx, reader, col, chunk.MetaData, dDecoder, rDecoder)
+	return f.readPages(ctx, reader, col, chunk.MetaData, dataPageOffset, firstPageOrdinal, decryptor, dDecoder, rDecoder)
 }
 
-func readPageData(col *Column, pages []pageReader, useDict bool) error {
+// readPageData sets the pages of the column store. Unless the pages are decoded lazily, the levels
+// of the first page are decoded right away.
+func readPageData(col *Column, pages []pageReader, useDict bool, lazy bool) error {
 	s := col.getColumnStore()
 	s.pageIdx, s.pages = 0, pages
 	s.useDict = useDict
+	if lazy {
+		return nil
+	}
 	if err := s.readNextPage(); err != nil {
 		return nil
 	}
@@ -372,33 +413,122 @@ func readPageData(col *Column, pages []pageReader, useDict bool) error {
 	return nil
 }
 
-func (f *FileReader) readRowGroupData(ctx context.Context) error {
+// readRowGroupData reads the data of the current row group. If firstRow is greater than zero, the
+// reader is positioned at that row within the row group. If a column chunk comes with an offset index,
+// the data pages that only contain rows before firstRow are not read at all. If concurrency is enabled,
+// the column chunks are read and decoded concurrently.
+func (f *FileReader) readRowGroupData(ctx context.Context, firstRow int64) error {
 	rowGroup := f.meta.RowGroups[f.rowGroupPosition-1]
 	dataCols := f.schemaReader.Columns()
 
 	f.schemaReader.resetData()
 	f.schemaReader.setNumRecords(rowGroup.NumRows)
+
+	var chunks []*columnChunkRead
 	for _, c := range dataCols {
 		idx := c.Index()
 		if len(rowGroup.Columns) <= idx {
 			return fmt.Errorf("column index %d is out of bounds", idx)
 		}
 		chunk := rowGroup.Columns[c.Index()]
-		if !f.schemaReader.isSelectedByPath(c.path) {
-			if err := f.skipChunk(c, chunk); err != nil {
-				return err
+		selected := f.schemaReader.isSelectedByPath(c.path)
+		if !selected && !f.isPredicateColumn(c.path) {
+			// inaccessible column chunks may lack the meta data to validate them.
+			if !f.decryptor.inaccessibleColumn(c.path) {
+				if err := f.skipChunk(c, chunk); err != nil {
+					return err
+				}
 			}
 			c.data.skipped = true
 			continue
 		}
-		pages, useDict, err := f.readChunk(ctx, c, chunk)
+
+		decryptor, err := f.decryptor.columnChunkDecryptor(chunk, f.rowGroupPosition-1, idx)
 		if err != nil {
 			return err
 		}
-		if err := readPageData(c, pages, useDict); err != nil {
+
+		// columns that are only read to evaluate the predicate are not part of the rows.
+		c.data.hidden = !selected
+
+		chunks = append(chunks, &columnChunkRead{
+			col:       c,
+			chunk:     chunk,
+			decryptor: decryptor,
+		})
+	}
+
+	ra, err := f.rowGroupReaderAt(ctx, f.rowGroupPosition-1)
+	if err != nil {
+		return err
+	}
+
+	// every column chunk is read from its own position, and decoded into the column store of its own
+	// column, so that no state is shared when reading them concurrently except for the allocation tracker.
+	return forEachConcurrently(f.concurrency, len(chunks), func(i int) (err error) {
+		defer f.recover(&err)
+		return f.readColumnChunk(ctx, &readerAtSeeker{r: ra}, chunks[i], firstRow)
+	})
+}
+
+// columnChunkRead describes a column chunk of the current row group that needs to be read.
+type columnChunkRead struct {
+	col       *Column
+	chunk     *parquet.ColumnChunk
+	decryptor *columnChunkDecryptor
+}
+
+// readColumnChunk reads the pages of a column chunk into its column store, and skips all rows before firstRow.
+// If a predicate is configured, the pages of columns the predicate doesn't refer to are decompressed and
+// decoded lazily, as only the values of matching rows are read from them.
+func (f *FileReader) readColumnChunk(ctx context.Context, r io.ReadSeeker, cr *columnChunkRead, firstRow int64) error {
+	lazy := f.predicate != nil && !f.isPredicateColumn(cr.col.path)
+
+	var (
+		offsetIndex      *parquet.OffsetIndex
+		firstPage        *parquet.PageLocation
+		firstPageOrdinal int
+	)
+	if firstRow > 0 || lazy {
+		var err error
+		offsetIndex, err = f.readOffsetIndex(ctx, r, cr.chunk, cr.decryptor)
+		if err != nil {
 			return err
 		}
+		if offsetIndex != nil && firstRow > 0 {
+			firstPage, firstPageOrdinal = findPageLocation(offsetIndex, firstRow)
+		}
+	}
+
+	pages, useDict, err := f.readChunk(ctx, r, cr.col, cr.chunk, firstPage, firstPageOrdinal, cr.decryptor)
+	if err != nil {
+		return err
+	}
+	if !lazy {
+		// the pages are decompressed right away, so that this is done concurrently if concurrency is enabled.
+		for _, p := range pages {
+			if err := p.decompress(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := readPageData(cr.col, pages, useDict, lazy); err != nil {
+		return err
+	}
+
+	// the first rows of the pages allow to skip pages without matching rows without decoding them.
+	if offsetIndex != nil && len(offsetIndex.PageLocations)-firstPageOrdinal == len(pages) {
+		cr.col.data.pageFirstRows = make([]int64, len(pages))
+		for i, loc := range offsetIndex.PageLocations[firstPageOrdinal:] {
+			cr.col.data.pageFirstRows[i] = loc.FirstRowIndex
+		}
+	}
+
+	skipRows := firstRow
+	if firstPage != nil {
+		skipRows -= firstPage.FirstRowIndex
 	}
+	cr.col.data.skipRows(skipRows)
 
 	return nil
 }
@@ -100,12 +100,16 @@ func getValuesEncoder(pageEncoding parquet.Encoding, typ *parquet.SchemaElement,
 		switch pageEncoding {
 		case parquet.Encoding_PLAIN:
 			return &floatPlainEncoder{}, nil
+		case parquet.Encoding_BYTE_STREAM_SPLIT:
+			return &floatByteStreamSplitEncoder{}, nil
 		}
 
 	case parquet.Type_DOUBLE:
 		switch pageEncoding {
 		case parquet.Encoding_PLAIN:
 			return &doublePlainEncoder{}, nil
+		case parquet.Encoding_BYTE_STREAM_SPLIT:
+			return &doubleByteStreamSplitEncoder{}, nil
 		}
 
 	case parquet.Type_INT32:
@@ -151,7 +155,7 @@ func getDictValuesEncoder(typ *parquet.SchemaElement) (valuesEncoder, error) {
 	return nil, fmt.Errorf("type %s is not supported for dict value encoder", typ)
 }
 
-func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec parquet.CompressionCodec, pageFn newDataPageFunc, kvMetaData map[string]string) (*parquet.ColumnChunk, error) {
+func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec parquet.CompressionCodec, level *int, pageFn newDataPageFunc, kvMetaData map[string]string, bloomFilter *bloomFilterBuilder, encryptor *columnChunkEncryptor) (*parquet.ColumnChunk, *columnChunkIndex, error) {
 	pos := w.Pos() // Save the position before writing data
 	chunkOffset := pos
 	var (
@@ -167,8 +171,8 @@ func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec
 	)
 
 	// flush final data page before writing dictionary page (if applicable) and all data pages.
-	if err := col.data.flushPage(sch, true); err != nil {
-		return nil, err
+	if err := col.data.flushPage(true); err != nil {
+		return nil, nil, err
 	}
 
 	dictValues := []interface{}{}
@@ -208,16 +212,28 @@ func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec
 		}
 	}
 
+	var sortedDict bool
+	if useDict && sch.sortDictionaries {
+		if remap := sortDictValues(col.data.parquetType(), columnStatsOrder(col.data.parquetType(), col.data.params()), dictValues); remap != nil {
+			for _, page := range col.data.dataPages {
+				for i, idx := range page.indexList {
+					page.indexList[i] = remap[idx]
+				}
+			}
+			sortedDict = true
+		}
+	}
+
 	if useDict {
 		tmp := pos // make a copy, do not use the pos here
 		dictPageOffset = &tmp
-		dict := &dictPageWriter{}
-		if err := dict.init(sch, col, codec, dictValues); err != nil {
-			return nil, err
+		dict := &dictPageWriter{sorted: sortedDict}
+		if err := dict.init(sch, col, codec, level, dictValues); err != nil {
+			return nil, nil, err
 		}
-		compSize, unCompSize, err := dict.write(ctx, w)
+		compSize, unCompSize, err := dict.write(ctx, w, encryptor.dictionaryPageEncryptor())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		totalComp = w.Pos() - pos
 		// Header size plus the rLevel and dLevel size
@@ -229,31 +245,50 @@ func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec
 	var (
 		compSize, unCompSize  int
 		numValues, nullValues int64
+		firstRowIndex         int64
 	)
 
-	for _, page := range col.data.dataPages {
+	index := newColumnChunkIndex(col.data.parquetType(), columnStatsOrder(col.data.parquetType(), col.data.params()))
+	index.encryptor = encryptor
+
+	for i, page := range col.data.dataPages {
 		pw := pageFn(useDict, dictValues, page, sch.enableCRC)
 
-		if err := pw.init(col, codec); err != nil {
-			return nil, err
+		if err := pw.init(col, codec, level); err != nil {
+			return nil, nil, err
+		}
+
+		if err := bloomFilter.addValues(page.values); err != nil {
+			return nil, nil, err
+		}
+
+		pageEncryptor, err := encryptor.dataPageEncryptor(i)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		var buf bytes.Buffer
 
-		compressed, uncompressed, err := pw.write(ctx, &buf)
+		compressed, uncompressed, err := pw.write(ctx, &buf, pageEncryptor)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		compSize += compressed
 		unCompSize += uncompressed
 		numValues += page.numValues
 		nullValues += page.nullValues
+
+		index.addPage(w.Pos(), int32(buf.Len()), firstRowIndex, page)
+		firstRowIndex += page.numRows
+
 		if _, err := w.Write(buf.Bytes()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
+	index.finish()
+
 	col.data.dataPages = nil
 
 	totalComp += w.Pos() - pos
@@ -282,9 +317,10 @@ func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec
 
 	distinctCount := int64(len(dictValues))
 
+	minValue, maxValue := col.data.minMaxValues(col.data.getStats())
 	stats := &parquet.Statistics{
-		MinValue:      col.data.getStats().minValue(),
-		MaxValue:      col.data.getStats().maxValue(),
+		MinValue:      minValue,
+		MaxValue:      maxValue,
 		NullCount:     &nullValues,
 		DistinctCount: &distinctCount,
 	}
@@ -313,20 +349,130 @@ func writeChunk(ctx context.Context, w writePos, sch *schema, col *Column, codec
 		ColumnIndexLength: nil,
 	}
 
-	return ch, nil
+	return ch, index, nil
 }
 
-func writeRowGroup(ctx context.Context, w writePos, sch *schema, codec parquet.CompressionCodec, pageFn newDataPageFunc, h *flushRowGroupOptionHandle) ([]*parquet.ColumnChunk, error) {
+type columnCompressionLevel struct {
+	path  ColumnPath
+	level int
+}
+
+// columnCompressionLevelMap returns the compression levels of all data columns. For columns that have
+// neither a compression level configured nor a compression level for the codec, the level is nil.
+func (fw *FileWriter) columnCompressionLevelMap(sch *schema) (map[*Column]*int, error) {
+	levels := make(map[*Column]*int)
+
+	if level, ok := fw.compressionLevels[fw.codec]; ok {
+		for _, col := range sch.Columns() {
+			levels[col] = intPtr(level)
+		}
+	}
+
+	for _, cl := range fw.columnCompressionLevels {
+		col := sch.GetColumnByPath(cl.path)
+		if col == nil || !col.DataColumn() {
+			return nil, fmt.Errorf("compression level configured for unknown column %s", cl.path.flatName())
+		}
+		levels[col] = intPtr(cl.level)
+	}
+
+	return levels, nil
+}
+
+// recoverColumnChunk turns a panic while writing a column chunk concurrently into an error. As the
+// panic happens in another goroutine, the caller of FlushRowGroup couldn't recover from it.
+func recoverColumnChunk(col *Column, errp *error) {
+	if e := recover(); e != nil {
+		*errp = fmt.Errorf("writing column chunk %s failed: panic: %v", col.path.flatName(), e)
+	}
+}
+
+func (fw *FileWriter) writeRowGroup(ctx context.Context, h *flushRowGroupOptionHandle) ([]*parquet.ColumnChunk, []*columnChunkIndex, error) {
+	sch := fw.schemaWriter
+	rowGroup := len(fw.rowGroups)
+
+	builders, err := newBloomFilterBuilders(sch, fw.bloomFilters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	levels, err := fw.columnCompressionLevelMap(sch)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	dataCols := sch.Columns()
-	var res = make([]*parquet.ColumnChunk, 0, len(dataCols))
-	for _, ci := range dataCols {
-		ch, err := writeChunk(ctx, w, sch, ci, codec, pageFn, h.getMetaData(ci.Path()))
+	var (
+		res        = make([]*parquet.ColumnChunk, len(dataCols))
+		indexes    = make([]*columnChunkIndex, len(dataCols))
+		encryptors = make([]*columnChunkEncryptor, len(dataCols))
+	)
+	for i, ci := range dataCols {
+		encryptor, err := fw.encryptor.columnChunkEncryptor(ci.Path(), rowGroup, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		encryptors[i] = encryptor
+	}
+
+	writeColumnChunk := func(w writePos, i int) (err error) {
+		ci := dataCols[i]
+		res[i], indexes[i], err = writeChunk(ctx, w, sch, ci, fw.codec, levels[ci], fw.newPageFunc, h.getMetaData(ci.Path()), builders[ci], encryptors[i])
+		return err
+	}
+
+	if fw.concurrency > 1 && len(dataCols) > 1 {
+		// every column chunk is written to its own buffer, starting at offset 0. The buffers are then
+		// written in schema order, so that the file is identical to a file written without concurrency.
+		bufs := make([]*bytes.Buffer, len(dataCols))
+		err := forEachConcurrently(fw.concurrency, len(dataCols), func(i int) (err error) {
+			defer recoverColumnChunk(dataCols[i], &err)
+			bufs[i] = &bytes.Buffer{}
+			return writeColumnChunk(&writePosStruct{w: bufs[i]}, i)
+		})
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		for i, buf := range bufs {
+			relocateColumnChunk(res[i], indexes[i], fw.w.Pos())
+			if err := writeFull(fw.w, buf.Bytes()); err != nil {
+				return nil, nil, err
+			}
 		}
+	} else {
+		for i := range dataCols {
+			if err := writeColumnChunk(fw.w, i); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
 
-		res = append(res, ch)
+	// the bloom filters are written after all column chunks of the row group so that
+	// the column chunks themselves remain contiguous.
+	for i, ci := range dataCols {
+		b := builders[ci]
+		if b == nil {
+			continue
+		}
+
+		pos := fw.w.Pos()
+		if err := b.build().write(ctx, fw.w, encryptors[i]); err != nil {
+			return nil, nil, fmt.Errorf("writing bloom filter for column %s failed: %w", ci.path.flatName(), err)
+		}
+		res[i].MetaData.BloomFilterOffset = int64Ptr(pos)
 	}
 
-	return res, nil
+	return res, indexes, nil
+}
+
+// relocateColumnChunk moves all offsets of a column chunk that was written starting at offset 0 by offset.
+func relocateColumnChunk(ch *parquet.ColumnChunk, index *columnChunkIndex, offset int64) {
+	ch.FileOffset += offset
+	ch.MetaData.DataPageOffset += offset
+	if ch.MetaData.DictionaryPageOffset != nil {
+		ch.MetaData.DictionaryPageOffset = int64Ptr(*ch.MetaData.DictionaryPageOffset + offset)
+	}
+	for _, loc := range index.offsetIndex.PageLocations {
+		loc.Offset += offset
+	}
 }
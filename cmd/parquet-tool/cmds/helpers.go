@@ -2,8 +2,13 @@ package cmds
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/httpreader"
 )
 
 var acceptableSuffix = map[string]int64{
@@ -38,3 +43,36 @@ func humanToByte(in string) (int64, error) {
 
 	return 0, fmt.Errorf("invalid format")
 }
+
+// openFileReader opens the parquet file at the address, which is either a file name, or an http or https URL.
+// Files served by HTTP servers are read using range requests, so that only the required parts are downloaded.
+// The returned io.Closer needs to be closed once the file reader is no longer used.
+func openFileReader(address string) (*goparquet.FileReader, io.Closer, error) {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		r, err := httpreader.New(address)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not open the file: %q", err)
+		}
+
+		reader, err := goparquet.NewFileReaderAt(r, r.Size(), goparquet.WithCoalescedReads(64*1024))
+		if err != nil {
+			_ = r.Close()
+			return nil, nil, fmt.Errorf("failed to read the parquet header: %q", err)
+		}
+
+		return reader, r, nil
+	}
+
+	fl, err := os.Open(address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not open the file: %q", err)
+	}
+
+	reader, err := goparquet.NewFileReader(fl)
+	if err != nil {
+		_ = fl.Close()
+		return nil, nil, fmt.Errorf("failed to read the parquet header: %q", err)
+	}
+
+	return reader, fl, nil
+}
@@ -1,7 +1,12 @@
 package cmds
 
 import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -76,3 +81,32 @@ func TestHumanToByte(t *testing.T) {
 		require.Equal(t, fix.Out, v, fix.In)
 	}
 }
+
+func TestOpenFileReaderHTTP(t *testing.T) {
+	const file = "../../../files/test1_datapagev1.parquet"
+
+	data, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.parquet", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	var local, remote bytes.Buffer
+	require.NoError(t, metaFile(&local, file))
+	require.NoError(t, metaFile(&remote, srv.URL+"/test.parquet"))
+	require.NotEmpty(t, local.String())
+	require.Equal(t, local.String(), remote.String())
+
+	reader, closer, err := openFileReader(srv.URL + "/test.parquet")
+	require.NoError(t, err)
+	defer closer.Close()
+
+	localReader, localCloser, err := openFileReader(file)
+	require.NoError(t, err)
+	defer localCloser.Close()
+
+	require.Equal(t, localReader.NumRows(), reader.NumRows())
+	require.Equal(t, localReader.GetSchemaDefinition().String(), reader.GetSchemaDefinition().String())
+}
@@ -12,7 +12,7 @@ func init() {
 }
 
 var metaCmd = &cobra.Command{
-	Use:   "meta file-name.parquet",
+	Use:   "meta file-name.parquet|url",
 	Short: "print the metadata of the parquet file",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
@@ -109,16 +109,11 @@ func printData(w io.Writer, m map[string]interface{}, ident string, columnOrder
 }
 
 func metaFile(w io.Writer, address string) error {
-	fl, err := os.Open(address)
-	if err != nil {
-		return fmt.Errorf("can not open the file: %q", err)
-	}
-	defer fl.Close()
-
-	reader, err := goparquet.NewFileReader(fl)
+	reader, closer, err := openFileReader(address)
 	if err != nil {
-		return fmt.Errorf("failed to read the parquet header: %q", err)
+		return err
 	}
+	defer closer.Close()
 
 	cols := reader.Columns()
 	writer := tabwriter.NewWriter(w, 8, 8, 0, '\t', 0)
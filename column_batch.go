@@ -0,0 +1,357 @@
+package goparquet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// ColumnBatch contains the values of a number of consecutive rows of a single column, as returned
+// by ReadColumnBatch.
+type ColumnBatch struct {
+	// NumRows is the number of rows in the batch.
+	NumRows int
+
+	// Values contains the non-null values of the column. Its type depends on the physical type of
+	// the column: []bool for BOOLEAN, []int32 for INT32, []int64 for INT64, [][12]byte for INT96,
+	// []float32 for FLOAT, []float64 for DOUBLE and [][]byte for BYTE_ARRAY and FIXED_LEN_BYTE_ARRAY.
+	Values interface{}
+
+	// DefinitionLevels contains the definition level of every value, including null values. A value
+	// is only contained in Values if its definition level equals the max definition level of the
+	// column. It is nil if the max definition level is 0, i.e. if all values are defined.
+	DefinitionLevels []int32
+
+	// RepetitionLevels contains the repetition level of every value, including null values. Every
+	// row starts with a value with repetition level 0. It is nil if the max repetition level is 0,
+	// i.e. if every row consists of exactly one value.
+	RepetitionLevels []int32
+}
+
+// ReadColumnBatch reads the values of up to n rows of the column with the provided path from the current
+// row group, which avoids assembling the values of all columns into rows. If no row group has been read
+// yet, or SkipRowGroup has been called, the next row group is read first. The batch contains fewer than
+// n rows if the end of the column chunk is reached, and io.EOF is returned if there are no rows left in it.
+// Use SeekToRowGroup to continue with another row group. Predicates configured using WithPredicate only
+// skip row groups, but don't filter the rows of the batches. The columns of the file are read as they
+// are, so an error is returned if a target schema definition is configured using WithTargetSchemaDefinition.
+//
+// Every column is read independently, so ReadColumnBatch and NextRow can't be mixed within a row group.
+// Plain and delta encoded INT32 and INT64 values, and plain encoded FLOAT and DOUBLE values, are decoded
+// into the batch directly, all other values are decoded into interface values first.
+func (f *FileReader) ReadColumnBatch(path ColumnPath, n int) (*ColumnBatch, error) {
+	return f.ReadColumnBatchWithContext(f.ctx, path, n)
+}
+
+// ReadColumnBatchWithContext reads the values of up to n rows of the column with the provided path from
+// the current row group, see ReadColumnBatch.
+func (f *FileReader) ReadColumnBatchWithContext(ctx context.Context, path ColumnPath, n int) (batch *ColumnBatch, err error) {
+	defer f.recover(&err)
+
+	if f.projection != nil {
+		return nil, errors.New("column batches can't be read if a target schema definition is configured")
+	}
+
+	if n < 1 {
+		return nil, fmt.Errorf("invalid batch size %d", n)
+	}
+
+	col := f.schemaReader.GetColumnByPath(path)
+	if col == nil {
+		return nil, fmt.Errorf("column %s not found", path.flatName())
+	}
+	if !col.DataColumn() {
+		return nil, fmt.Errorf("column %s is not a data column", path.flatName())
+	}
+
+	if f.rowGroupPosition == 0 || f.skipRowGroup {
+		if err := f.advanceIfNeeded(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if col.data.skipped {
+		return nil, fmt.Errorf("column %s is not read", path.flatName())
+	}
+
+	values, dLevels, rLevels, rows, err := col.data.readRows(n, int32(col.MaxDefinitionLevel()))
+	if err != nil {
+		return nil, fmt.Errorf("reading column %s failed: %w", path.flatName(), err)
+	}
+	if rows == 0 {
+		return nil, io.EOF
+	}
+
+	batch = &ColumnBatch{NumRows: rows}
+	if col.MaxDefinitionLevel() > 0 {
+		batch.DefinitionLevels = dLevels
+	}
+	if col.MaxRepetitionLevel() > 0 {
+		batch.RepetitionLevels = rLevels
+	}
+
+	batch.Values = values
+	if values == nil {
+		if batch.Values, err = typedValues(col.data.parquetType(), nil); err != nil {
+			return nil, fmt.Errorf("reading column %s failed: %w", path.flatName(), err)
+		}
+	}
+
+	return batch, nil
+}
+
+// AddColumnBatch appends the values of a number of rows to the column with the provided path in the current
+// row group, which avoids building a map for every row if the data is already in columnar form. The batch uses
+// the same format as the batches returned by ReadColumnBatch, but DefinitionLevels may also be nil if all values
+// are defined, and RepetitionLevels may also be nil if every value is a row of its own. The number of rows is
+// determined by the repetition levels, NumRows is ignored.
+//
+// All data columns need to contain the same number of rows when the row group is flushed, so the row group
+// isn't flushed automatically by AddColumnBatch, even if WithMaxRowGroupSize is used. AddData can be used in
+// between if all columns contain the same number of rows.
+func (fw *FileWriter) AddColumnBatch(path ColumnPath, batch *ColumnBatch) error {
+	return fw.schemaWriter.addColumnBatch(path, batch)
+}
+
+// typedValues converts the values read from a column store into a slice of the Go type used for
+// the parquet type.
+func typedValues(typ parquet.Type, values []interface{}) (interface{}, error) {
+	var ok bool
+	switch typ {
+	case parquet.Type_BOOLEAN:
+		res := make([]bool, len(values))
+		for i, v := range values {
+			if res[i], ok = v.(bool); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	case parquet.Type_INT32:
+		res := make([]int32, len(values))
+		for i, v := range values {
+			if res[i], ok = v.(int32); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	case parquet.Type_INT64:
+		res := make([]int64, len(values))
+		for i, v := range values {
+			if res[i], ok = v.(int64); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	case parquet.Type_INT96:
+		res := make([][12]byte, len(values))
+		for i, v := range values {
+			if res[i], ok = v.([12]byte); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	case parquet.Type_FLOAT:
+		res := make([]float32, len(values))
+		for i, v := range values {
+			if res[i], ok = v.(float32); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	case parquet.Type_DOUBLE:
+		res := make([]float64, len(values))
+		for i, v := range values {
+			if res[i], ok = v.(float64); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		res := make([][]byte, len(values))
+		for i, v := range values {
+			if res[i], ok = v.([]byte); !ok {
+				return nil, fmt.Errorf("unexpected %T value for type %s", v, typ)
+			}
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+// newTypedValues returns a slice of n values of the Go type used for the parquet type, for the types
+// that have values decoders which can decode values without boxing them, or nil for all other types.
+func newTypedValues(typ parquet.Type, n int) interface{} {
+	switch typ {
+	case parquet.Type_INT32:
+		return make([]int32, n)
+	case parquet.Type_INT64:
+		return make([]int64, n)
+	case parquet.Type_FLOAT:
+		return make([]float32, n)
+	case parquet.Type_DOUBLE:
+		return make([]float64, n)
+	default:
+		return nil
+	}
+}
+
+// typedLen returns the length of a slice of values as returned by typedValues.
+func typedLen(values interface{}) int {
+	switch typed := values.(type) {
+	case []bool:
+		return len(typed)
+	case []int32:
+		return len(typed)
+	case []int64:
+		return len(typed)
+	case [][12]byte:
+		return len(typed)
+	case []float32:
+		return len(typed)
+	case []float64:
+		return len(typed)
+	case [][]byte:
+		return len(typed)
+	default:
+		return 0
+	}
+}
+
+// appendTypedValues appends src[from:to] to dst, which is nil or a slice of the same type as src.
+func appendTypedValues(dst, src interface{}, from, to int) interface{} {
+	switch typed := src.(type) {
+	case []bool:
+		res, _ := dst.([]bool)
+		return append(res, typed[from:to]...)
+	case []int32:
+		res, _ := dst.([]int32)
+		return append(res, typed[from:to]...)
+	case []int64:
+		res, _ := dst.([]int64)
+		return append(res, typed[from:to]...)
+	case [][12]byte:
+		res, _ := dst.([][12]byte)
+		return append(res, typed[from:to]...)
+	case []float32:
+		res, _ := dst.([]float32)
+		return append(res, typed[from:to]...)
+	case []float64:
+		res, _ := dst.([]float64)
+		return append(res, typed[from:to]...)
+	case [][]byte:
+		res, _ := dst.([][]byte)
+		return append(res, typed[from:to]...)
+	default:
+		return dst
+	}
+}
+
+// checkBatchValues checks that the Go type of the values of a column batch matches the parquet type, and
+// that byte arrays have the type length if it is set, so that adding the values to a column store can't
+// fail halfway through the batch. It returns the number of values.
+func checkBatchValues(typ parquet.Type, typeLength *int32, values interface{}) (int, error) {
+	var ok bool
+	switch typed := values.(type) {
+	case nil:
+		return 0, nil
+	case []bool:
+		ok = typ == parquet.Type_BOOLEAN
+	case []int32:
+		ok = typ == parquet.Type_INT32
+	case []int64:
+		ok = typ == parquet.Type_INT64
+	case [][12]byte:
+		ok = typ == parquet.Type_INT96
+		if ok && typeLength != nil && *typeLength > 0 && *typeLength != 12 {
+			return 0, fmt.Errorf("the size of data should be %d but is 12", *typeLength)
+		}
+	case []float32:
+		ok = typ == parquet.Type_FLOAT
+	case []float64:
+		ok = typ == parquet.Type_DOUBLE
+	case [][]byte:
+		ok = typ == parquet.Type_BYTE_ARRAY || typ == parquet.Type_FIXED_LEN_BYTE_ARRAY
+		if ok && typeLength != nil && *typeLength > 0 {
+			for i := range typed {
+				if int32(len(typed[i])) != *typeLength {
+					return 0, fmt.Errorf("the size of value %d should be %d but is %d", i, *typeLength, len(typed[i]))
+				}
+			}
+		}
+	default:
+		return 0, fmt.Errorf("unsupported type %T for values", values)
+	}
+
+	if !ok {
+		return 0, fmt.Errorf("values of type %T can't be stored in %s column", values, typ)
+	}
+
+	return typedLen(values), nil
+}
+
+// untypedValues converts the values decoded into a slice of the Go type used for the parquet type into
+// interface values, and checks that the Go type of the values matches the parquet type.
+func untypedValues(typ parquet.Type, values interface{}) ([]interface{}, error) {
+	var (
+		res []interface{}
+		ok  bool
+	)
+	switch typed := values.(type) {
+	case nil:
+		return nil, nil
+	case []bool:
+		ok = typ == parquet.Type_BOOLEAN
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	case []int32:
+		ok = typ == parquet.Type_INT32
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	case []int64:
+		ok = typ == parquet.Type_INT64
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	case [][12]byte:
+		ok = typ == parquet.Type_INT96
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	case []float32:
+		ok = typ == parquet.Type_FLOAT
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	case []float64:
+		ok = typ == parquet.Type_DOUBLE
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	case [][]byte:
+		ok = typ == parquet.Type_BYTE_ARRAY || typ == parquet.Type_FIXED_LEN_BYTE_ARRAY
+		res = make([]interface{}, len(typed))
+		for i := range typed {
+			res[i] = typed[i]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %T for values", values)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("values of type %T can't be stored in %s column", values, typ)
+	}
+
+	return res, nil
+}
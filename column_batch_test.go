@@ -0,0 +1,443 @@
+package goparquet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadColumnBatch(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		optional group tags (LIST) {
+			repeated group list {
+				required int32 element;
+			}
+		}
+		required double score;
+		required boolean flag;
+		required float ratio;
+		required int96 ts;
+		required fixed_len_byte_array(2) code;
+	}`)
+	require.NoError(t, err)
+
+	const numRows = 1000
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithMaxPageSize(512))
+	for i := 0; i < numRows; i++ {
+		row := map[string]interface{}{
+			"id":    int64(i),
+			"score": float64(i) / 4,
+			"flag":  i%3 == 0,
+			"ratio": float32(i) / 8,
+			"ts":    [12]byte{byte(i)},
+			"code":  []byte{byte(i), byte(i >> 8)},
+		}
+		if i%5 != 0 {
+			row["name"] = []byte(fmt.Sprintf("name-%d", i%7))
+		}
+		switch i % 3 {
+		case 1:
+			row["tags"] = map[string]interface{}{}
+		case 2:
+			var list []map[string]interface{}
+			for j := 0; j <= i%4; j++ {
+				list = append(list, map[string]interface{}{"element": int32(i + j)})
+			}
+			row["tags"] = map[string]interface{}{"list": list}
+		}
+		require.NoError(t, fw.AddData(row))
+		if i%400 == 399 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 3, r.RowGroupCount())
+
+	// readColumn reads the column of all row groups using batches of 37 rows.
+	readColumn := func(path ColumnPath) []*ColumnBatch {
+		var batches []*ColumnBatch
+		rows := 0
+		for rg := 0; rg < r.RowGroupCount(); rg++ {
+			require.NoError(t, r.SeekToRowGroup(rg))
+			for {
+				batch, err := r.ReadColumnBatch(path, 37)
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				require.LessOrEqual(t, batch.NumRows, 37)
+				rows += batch.NumRows
+				batches = append(batches, batch)
+			}
+		}
+		require.Equal(t, numRows, rows)
+		return batches
+	}
+
+	var (
+		ids    []int64
+		scores []float64
+		flags  []bool
+		ratios []float32
+		ts     [][12]byte
+		codes  [][]byte
+	)
+	for _, batch := range readColumn(ColumnPath{"id"}) {
+		require.Nil(t, batch.DefinitionLevels)
+		require.Nil(t, batch.RepetitionLevels)
+		ids = append(ids, batch.Values.([]int64)...)
+	}
+	for _, batch := range readColumn(ColumnPath{"score"}) {
+		scores = append(scores, batch.Values.([]float64)...)
+	}
+	for _, batch := range readColumn(ColumnPath{"flag"}) {
+		flags = append(flags, batch.Values.([]bool)...)
+	}
+	for _, batch := range readColumn(ColumnPath{"ratio"}) {
+		ratios = append(ratios, batch.Values.([]float32)...)
+	}
+	for _, batch := range readColumn(ColumnPath{"ts"}) {
+		ts = append(ts, batch.Values.([][12]byte)...)
+	}
+	for _, batch := range readColumn(ColumnPath{"code"}) {
+		codes = append(codes, batch.Values.([][]byte)...)
+	}
+	for i := 0; i < numRows; i++ {
+		require.Equal(t, int64(i), ids[i])
+		require.Equal(t, float64(i)/4, scores[i])
+		require.Equal(t, i%3 == 0, flags[i])
+		require.Equal(t, float32(i)/8, ratios[i])
+		require.Equal(t, [12]byte{byte(i)}, ts[i])
+		require.Equal(t, []byte{byte(i), byte(i >> 8)}, codes[i])
+	}
+
+	row := 0
+	for _, batch := range readColumn(ColumnPath{"name"}) {
+		require.Len(t, batch.DefinitionLevels, batch.NumRows)
+		require.Nil(t, batch.RepetitionLevels)
+		names := batch.Values.([][]byte)
+		for _, dl := range batch.DefinitionLevels {
+			if row%5 == 0 {
+				require.Equal(t, int32(0), dl)
+			} else {
+				require.Equal(t, int32(1), dl)
+				require.Equal(t, fmt.Sprintf("name-%d", row%7), string(names[0]))
+				names = names[1:]
+			}
+			row++
+		}
+		require.Empty(t, names)
+	}
+
+	row = -1
+	for _, batch := range readColumn(ColumnPath{"tags", "list", "element"}) {
+		require.Len(t, batch.RepetitionLevels, len(batch.DefinitionLevels))
+		elements := batch.Values.([]int32)
+		j := 0
+		for k, dl := range batch.DefinitionLevels {
+			if batch.RepetitionLevels[k] == 0 {
+				row++
+				j = 0
+			} else {
+				require.Equal(t, int32(1), batch.RepetitionLevels[k])
+			}
+			switch row % 3 {
+			case 0:
+				require.Equal(t, int32(0), dl)
+			case 1:
+				require.Equal(t, int32(1), dl)
+			case 2:
+				require.Equal(t, int32(2), dl)
+				require.Equal(t, int32(row+j), elements[0])
+				elements = elements[1:]
+				j++
+			}
+		}
+		require.Empty(t, elements)
+	}
+	require.Equal(t, numRows-1, row)
+
+	// the first row group is read if none has been read yet.
+	r, err = NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	batch, err := r.ReadColumnBatch(ColumnPath{"id"}, 1000)
+	require.NoError(t, err)
+	require.Equal(t, 400, batch.NumRows)
+	_, err = r.ReadColumnBatch(ColumnPath{"id"}, 1000)
+	require.Equal(t, io.EOF, err)
+
+	r.SkipRowGroup()
+	batch, err = r.ReadColumnBatch(ColumnPath{"id"}, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int64{400, 401, 402, 403, 404, 405, 406, 407, 408, 409}, batch.Values)
+
+	_, err = r.ReadColumnBatch(ColumnPath{"id"}, 0)
+	require.Error(t, err)
+	_, err = r.ReadColumnBatch(ColumnPath{"unknown"}, 10)
+	require.Error(t, err)
+	_, err = r.ReadColumnBatch(ColumnPath{"tags"}, 10)
+	require.Error(t, err)
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()), WithColumnPaths(ColumnPath{"id"}))
+	require.NoError(t, err)
+	_, err = r.ReadColumnBatch(ColumnPath{"score"}, 10)
+	require.Error(t, err)
+
+	// the target schema isn't applied to column batches.
+	r, err = NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()), WithTargetSchemaDefinition(sd))
+	require.NoError(t, err)
+	_, err = r.ReadColumnBatch(ColumnPath{"id"}, 10)
+	require.Error(t, err)
+}
+
+func TestReadColumnBatchTypedValues(t *testing.T) {
+	const numRows = 10000
+
+	var buf bytes.Buffer
+	w := NewFileWriter(&buf, WithMaxPageSize(1<<20))
+
+	idStore, err := NewInt64Store(parquet.Encoding_PLAIN, false, &ColumnParameters{})
+	require.NoError(t, err)
+	require.NoError(t, w.AddColumnByPath(ColumnPath{"id"}, NewDataColumn(idStore, parquet.FieldRepetitionType_REQUIRED)))
+
+	scoreStore, err := NewDoubleStore(parquet.Encoding_PLAIN, true, &ColumnParameters{})
+	require.NoError(t, err)
+	require.NoError(t, w.AddColumnByPath(ColumnPath{"score"}, NewDataColumn(scoreStore, parquet.FieldRepetitionType_OPTIONAL)))
+
+	for i := 0; i < numRows; i++ {
+		data := map[string]interface{}{"id": int64(i) << 20}
+		if i%2 == 0 {
+			data["score"] = float64(i % 100)
+		}
+		require.NoError(t, w.AddData(data))
+	}
+	require.NoError(t, w.Close())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.NoError(t, r.PreLoad())
+
+	// the values of the plain encoded column are decoded without boxing each of them.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	batch, err := r.ReadColumnBatch(ColumnPath{"id"}, numRows)
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+	require.Less(t, after.Mallocs-before.Mallocs, uint64(numRows/10))
+
+	ids := batch.Values.([]int64)
+	require.Len(t, ids, numRows)
+	for i, id := range ids {
+		require.Equal(t, int64(i)<<20, id)
+	}
+
+	batch, err = r.ReadColumnBatch(ColumnPath{"score"}, numRows)
+	require.NoError(t, err)
+	scores := batch.Values.([]float64)
+	require.Len(t, scores, numRows/2)
+	for i, score := range scores {
+		require.Equal(t, float64(2*i%100), score)
+	}
+}
+
+func TestAddColumnBatch(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		optional group tags (LIST) {
+			repeated group list {
+				required int32 element;
+			}
+		}
+		required boolean flag;
+	}`)
+	require.NoError(t, err)
+
+	const numRows = 1000
+
+	var (
+		rows     []map[string]interface{}
+		ids      []int64
+		names    [][]byte
+		nameDefs []int32
+		elements []int32
+		tagDefs  []int32
+		tagReps  []int32
+		flags    []bool
+	)
+	for i := 0; i < numRows; i++ {
+		row := map[string]interface{}{
+			"id":   int64(i),
+			"flag": i%2 == 0,
+		}
+		ids = append(ids, int64(i))
+		flags = append(flags, i%2 == 0)
+
+		if i%5 != 0 {
+			row["name"] = []byte(fmt.Sprintf("name-%d", i%7))
+			names = append(names, []byte(fmt.Sprintf("name-%d", i%7)))
+			nameDefs = append(nameDefs, 1)
+		} else {
+			nameDefs = append(nameDefs, 0)
+		}
+
+		switch i % 3 {
+		case 0:
+			tagDefs = append(tagDefs, 0)
+			tagReps = append(tagReps, 0)
+		case 1:
+			row["tags"] = map[string]interface{}{}
+			tagDefs = append(tagDefs, 1)
+			tagReps = append(tagReps, 0)
+		case 2:
+			var list []map[string]interface{}
+			for j := 0; j <= i%4; j++ {
+				list = append(list, map[string]interface{}{"element": int32(i + j)})
+				elements = append(elements, int32(i+j))
+				tagDefs = append(tagDefs, 2)
+				if j == 0 {
+					tagReps = append(tagReps, 0)
+				} else {
+					tagReps = append(tagReps, 1)
+				}
+			}
+			row["tags"] = map[string]interface{}{"list": list}
+		}
+		rows = append(rows, row)
+	}
+
+	opts := []FileWriterOption{WithSchemaDefinition(sd), WithMaxPageSize(256), WithPageIndex(true)}
+
+	var expected bytes.Buffer
+	fw := NewFileWriter(&expected, opts...)
+	for _, row := range rows {
+		require.NoError(t, fw.AddData(row))
+	}
+	require.NoError(t, fw.Close())
+
+	var buf bytes.Buffer
+	fw = NewFileWriter(&buf, opts...)
+	for i := 0; i < numRows; i += 100 {
+		require.NoError(t, fw.AddColumnBatch(ColumnPath{"id"}, &ColumnBatch{Values: ids[i : i+100]}))
+		require.NoError(t, fw.AddColumnBatch(ColumnPath{"flag"}, &ColumnBatch{Values: flags[i : i+100]}))
+	}
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"name"}, &ColumnBatch{Values: names, DefinitionLevels: nameDefs}))
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"tags", "list", "element"}, &ColumnBatch{Values: elements, DefinitionLevels: tagDefs, RepetitionLevels: tagReps}))
+	require.NoError(t, fw.Close())
+
+	// the columns are split into the same pages and have the same statistics.
+	require.Equal(t, expected.Bytes(), buf.Bytes())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, rows, readAllRows(t, r))
+}
+
+func TestAddColumnBatchErrors(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		repeated int32 values;
+	}`)
+	require.NoError(t, err)
+
+	fw := NewFileWriter(ioutil.Discard, WithSchemaDefinition(sd))
+
+	for _, tt := range []struct {
+		name  string
+		path  ColumnPath
+		batch *ColumnBatch
+	}{
+		{name: "unknown_column", path: ColumnPath{"unknown"}, batch: &ColumnBatch{Values: []int64{1}}},
+		{name: "wrong_type", path: ColumnPath{"id"}, batch: &ColumnBatch{Values: []int32{1}}},
+		{name: "unsupported_type", path: ColumnPath{"id"}, batch: &ColumnBatch{Values: []int{1}}},
+		{name: "missing_values", path: ColumnPath{"name"}, batch: &ColumnBatch{Values: [][]byte{[]byte("a")}, DefinitionLevels: []int32{1, 1}}},
+		{name: "invalid_definition_level", path: ColumnPath{"name"}, batch: &ColumnBatch{DefinitionLevels: []int32{2}}},
+		{name: "invalid_repetition_level", path: ColumnPath{"values"}, batch: &ColumnBatch{Values: []int32{1, 2}, RepetitionLevels: []int32{0, 2}}},
+		{name: "first_repetition_level", path: ColumnPath{"values"}, batch: &ColumnBatch{Values: []int32{1, 2}, RepetitionLevels: []int32{1, 0}}},
+		{name: "level_count", path: ColumnPath{"values"}, batch: &ColumnBatch{Values: []int32{1, 2}, DefinitionLevels: []int32{1, 1}, RepetitionLevels: []int32{0}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, fw.AddColumnBatch(tt.path, tt.batch))
+		})
+	}
+
+	// all columns need to contain the same number of rows.
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"id"}, &ColumnBatch{Values: []int64{1, 2}}))
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"name"}, &ColumnBatch{DefinitionLevels: []int32{0, 0}}))
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"values"}, &ColumnBatch{Values: []int32{1, 2, 3}, RepetitionLevels: []int32{0, 1, 1}}))
+	require.Error(t, fw.FlushRowGroup())
+
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"values"}, &ColumnBatch{DefinitionLevels: []int32{0}}))
+	require.NoError(t, fw.FlushRowGroup())
+
+	// a batch that is rejected doesn't leave any of its values in the column.
+	sd, err = parquetschema.ParseSchemaDefinition(`message test {
+		required fixed_len_byte_array(2) code;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw = NewFileWriter(&buf, WithSchemaDefinition(sd))
+	require.Error(t, fw.AddColumnBatch(ColumnPath{"code"}, &ColumnBatch{Values: [][]byte{[]byte("ab"), []byte("abc")}}))
+	require.NoError(t, fw.AddColumnBatch(ColumnPath{"code"}, &ColumnBatch{Values: [][]byte{[]byte("cd"), []byte("ef")}}))
+	require.NoError(t, fw.Close())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{"code": []byte("cd")}, {"code": []byte("ef")}}, readAllRows(t, r))
+
+	meta, err := ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+	stats := meta.RowGroups[0].Columns[0].MetaData.Statistics
+	require.Equal(t, []byte("cd"), stats.MinValue)
+	require.Equal(t, []byte("ef"), stats.MaxValue)
+}
+
+func BenchmarkAddColumnBatch(b *testing.B) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+	}`)
+	require.NoError(b, err)
+
+	const numRows = 10000
+
+	ids := make([]int64, numRows)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+
+	b.Run("AddData", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fw := NewFileWriter(ioutil.Discard, WithSchemaDefinition(sd))
+			for _, id := range ids {
+				require.NoError(b, fw.AddData(map[string]interface{}{"id": id}))
+			}
+			require.NoError(b, fw.Close())
+		}
+	})
+
+	b.Run("AddColumnBatch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fw := NewFileWriter(ioutil.Discard, WithSchemaDefinition(sd))
+			require.NoError(b, fw.AddColumnBatch(ColumnPath{"id"}, &ColumnBatch{Values: ids}))
+			require.NoError(b, fw.Close())
+		}
+	})
+}
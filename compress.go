@@ -3,14 +3,19 @@ package goparquet
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"sync"
 
+	"github.com/andybalholm/brotli"
 	"github.com/fraugster/parquet-go/parquet"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 )
 
 var (
@@ -26,9 +31,54 @@ type (
 		DecompressBlock([]byte) ([]byte, error)
 	}
 
+	// LeveledBlockCompressor is a block compressor that supports different compression levels. The
+	// compression level that is used when writing files can be configured using the FileWriter options
+	// WithCompressionLevel and WithColumnCompressionLevel, which requires the block compressor that is
+	// registered for the compression codec to implement this interface.
+	LeveledBlockCompressor interface {
+		BlockCompressor
+		CompressBlockWithLevel(block []byte, level int) ([]byte, error)
+	}
+
+	// sizedBlockDecompressor is implemented by block compressors that need to know the size of
+	// the decompressed block, which is always known when reading pages.
+	sizedBlockDecompressor interface {
+		decompressBlockWithSize(block []byte, size int) ([]byte, error)
+	}
+
 	plainCompressor  struct{}
 	snappyCompressor struct{}
 	gzipCompressor   struct{}
+
+	lz4RawCompressor    struct{}
+	lz4HadoopCompressor struct{}
+
+	zstdCompressor struct {
+		level zstd.EncoderLevel
+
+		// encoders contains the encoders for all levels that were used so far, as they are safe
+		// to be used concurrently, but expensive to create.
+		encoders    map[zstd.EncoderLevel]*zstd.Encoder
+		encoderLock sync.Mutex
+
+		once    sync.Once
+		decoder *zstd.Decoder
+		err     error
+	}
+
+	brotliCompressor struct {
+		quality int
+	}
+)
+
+const (
+	// ZstdDefaultLevel is the compression level that is used by the ZSTD block compressor that is
+	// registered by default.
+	ZstdDefaultLevel = 3
+
+	// BrotliDefaultQuality is the quality level that is used by the BROTLI block compressor that is
+	// registered by default.
+	BrotliDefaultQuality = brotli.DefaultCompression
 )
 
 func (plainCompressor) CompressBlock(block []byte) ([]byte, error) {
@@ -47,9 +97,16 @@ func (snappyCompressor) DecompressBlock(block []byte) ([]byte, error) {
 	return snappy.Decode(nil, block)
 }
 
-func (gzipCompressor) CompressBlock(block []byte) ([]byte, error) {
+func (c gzipCompressor) CompressBlock(block []byte) ([]byte, error) {
+	return c.CompressBlockWithLevel(block, gzip.DefaultCompression)
+}
+
+func (gzipCompressor) CompressBlockWithLevel(block []byte, level int) ([]byte, error) {
 	buf := &bytes.Buffer{}
-	w := gzip.NewWriter(buf)
+	w, err := gzip.NewWriterLevel(buf, level)
+	if err != nil {
+		return nil, err
+	}
 	if _, err := w.Write(block); err != nil {
 		return nil, err
 	}
@@ -75,7 +132,289 @@ func (gzipCompressor) DecompressBlock(block []byte) ([]byte, error) {
 	return ret, r.Close()
 }
 
-func compressBlock(block []byte, method parquet.CompressionCodec) ([]byte, error) {
+// NewZstdBlockCompressor returns a block compressor for ZSTD that compresses blocks using the
+// provided compression level. The level corresponds to the levels of the zstd command line
+// tool from 1 (fastest) to 22 (best compression), but as the underlying pure Go implementation
+// only supports a few distinct speed settings, levels are mapped to the closest setting. To
+// change the compression level that is used when writing files, register the returned block
+// compressor using RegisterBlockCompressor.
+func NewZstdBlockCompressor(level int) BlockCompressor {
+	return &zstdCompressor{
+		level:    zstd.EncoderLevelFromZstd(level),
+		encoders: make(map[zstd.EncoderLevel]*zstd.Encoder),
+	}
+}
+
+func (c *zstdCompressor) encoder(level zstd.EncoderLevel) (*zstd.Encoder, error) {
+	c.encoderLock.Lock()
+	defer c.encoderLock.Unlock()
+
+	if enc, ok := c.encoders[level]; ok {
+		return enc, nil
+	}
+
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	c.encoders[level] = enc
+
+	return enc, nil
+}
+
+func (c *zstdCompressor) CompressBlock(block []byte) ([]byte, error) {
+	enc, err := c.encoder(c.level)
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.EncodeAll(block, nil), nil
+}
+
+func (c *zstdCompressor) CompressBlockWithLevel(block []byte, level int) ([]byte, error) {
+	enc, err := c.encoder(zstd.EncoderLevelFromZstd(level))
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.EncodeAll(block, nil), nil
+}
+
+// getDecoder returns the decoder, which is safe to be used concurrently. As pages can't be larger
+// than math.MaxInt32 bytes, larger blocks are rejected by the decoder.
+func (c *zstdCompressor) getDecoder() (*zstd.Decoder, error) {
+	c.once.Do(func() {
+		c.decoder, c.err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(math.MaxInt32))
+	})
+	return c.decoder, c.err
+}
+
+func (c *zstdCompressor) DecompressBlock(block []byte) ([]byte, error) {
+	dec, err := c.getDecoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return dec.DecodeAll(block, nil)
+}
+
+// decompressBlockWithSize decompresses the block into a buffer of the provided size. Frames whose
+// header contains a larger content size are rejected before they are decompressed, so that they
+// can't allocate more memory than the allocation tracker allowed for the page.
+func (c *zstdCompressor) decompressBlockWithSize(block []byte, size int) ([]byte, error) {
+	dec, err := c.getDecoder()
+	if err != nil {
+		return nil, err
+	}
+
+	var header zstd.Header
+	if err := header.Decode(block); err == nil && header.HasFCS && header.FrameContentSize > uint64(size) {
+		return nil, fmt.Errorf("frame content size %d exceeds the decompressed size %d", header.FrameContentSize, size)
+	}
+
+	data, err := dec.DecodeAll(block, make([]byte, 0, size))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > size {
+		return nil, fmt.Errorf("decompressed data exceeds %d bytes", size)
+	}
+
+	return data, nil
+}
+
+// NewBrotliBlockCompressor returns a block compressor for BROTLI that compresses blocks using the
+// provided quality level from 0 (fastest) to 11 (best compression). Levels outside of this range
+// are clamped. To change the quality level that is used when writing files, register the returned
+// block compressor using RegisterBlockCompressor.
+func NewBrotliBlockCompressor(quality int) BlockCompressor {
+	return brotliCompressor{quality: brotliQuality(quality)}
+}
+
+func brotliQuality(quality int) int {
+	if quality < brotli.BestSpeed {
+		return brotli.BestSpeed
+	}
+	if quality > brotli.BestCompression {
+		return brotli.BestCompression
+	}
+	return quality
+}
+
+func (c brotliCompressor) CompressBlock(block []byte) ([]byte, error) {
+	return c.CompressBlockWithLevel(block, c.quality)
+}
+
+func (brotliCompressor) CompressBlockWithLevel(block []byte, quality int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := brotli.NewWriterLevel(buf, brotliQuality(quality))
+	if _, err := w.Write(block); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (brotliCompressor) DecompressBlock(block []byte) ([]byte, error) {
+	return ioutil.ReadAll(brotli.NewReader(bytes.NewReader(block)))
+}
+
+// decompressBlockWithSize decompresses the block into a buffer of the provided size, and fails if the
+// stream contains more data, so that it can't allocate more memory than the allocation tracker allowed
+// for the page.
+func (brotliCompressor) decompressBlockWithSize(block []byte, size int) ([]byte, error) {
+	r := brotli.NewReader(bytes.NewReader(block))
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	var extra [1]byte
+	switch _, err := io.ReadFull(r, extra[:]); err {
+	case io.EOF:
+		return buf, nil
+	case nil:
+		return nil, fmt.Errorf("decompressed data exceeds %d bytes", size)
+	default:
+		return nil, err
+	}
+}
+
+var lz4CompressorPool = sync.Pool{
+	New: func() interface{} {
+		return &lz4.Compressor{}
+	},
+}
+
+// lz4CompressBlock compresses the block into a single LZ4 block without any framing.
+func lz4CompressBlock(block []byte) ([]byte, error) {
+	c := lz4CompressorPool.Get().(*lz4.Compressor)
+	defer lz4CompressorPool.Put(c)
+
+	buf := make([]byte, lz4.CompressBlockBound(len(block)))
+	n, err := c.CompressBlock(block, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+// lz4MaxCompressionRatio is the maximum ratio between the decompressed and the compressed size of an LZ4 block.
+const lz4MaxCompressionRatio = 255
+
+// lz4DecompressBlock decompresses a single LZ4 block. As the size of the decompressed block is not
+// part of the LZ4 block format, the output buffer is grown until the decompressed block fits.
+func lz4DecompressBlock(block []byte) ([]byte, error) {
+	for size := 4 * len(block); ; size *= 2 {
+		if size > lz4MaxCompressionRatio*len(block) {
+			size = lz4MaxCompressionRatio * len(block)
+		}
+
+		buf, err := lz4DecompressBlockWithSize(block, size)
+		if err == nil || !errors.Is(err, lz4.ErrInvalidSourceShortBuffer) || size == lz4MaxCompressionRatio*len(block) {
+			return buf, err
+		}
+	}
+}
+
+func lz4DecompressBlockWithSize(block []byte, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := lz4.UncompressBlock(block, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func (lz4RawCompressor) CompressBlock(block []byte) ([]byte, error) {
+	return lz4CompressBlock(block)
+}
+
+func (lz4RawCompressor) DecompressBlock(block []byte) ([]byte, error) {
+	return lz4DecompressBlock(block)
+}
+
+func (lz4RawCompressor) decompressBlockWithSize(block []byte, size int) ([]byte, error) {
+	return lz4DecompressBlockWithSize(block, size)
+}
+
+// The deprecated LZ4 codec has been written with two different formats: Hadoop based writers
+// use the Hadoop framing, i.e. the data consists of frames that start with the big-endian
+// decompressed and compressed size, followed by the compressed LZ4 block, while other writers
+// wrote the plain LZ4 block without any framing. The reader tries to decompress the data
+// using the Hadoop framing first, and falls back to the plain LZ4 block if that fails.
+
+func (lz4HadoopCompressor) CompressBlock(block []byte) ([]byte, error) {
+	return nil, errors.New("writing LZ4 is not supported as it has been deprecated, use LZ4_RAW instead")
+}
+
+func (lz4HadoopCompressor) DecompressBlock(block []byte) ([]byte, error) {
+	if data, ok := lz4DecompressHadoop(block, -1); ok {
+		return data, nil
+	}
+
+	return lz4DecompressBlock(block)
+}
+
+func (lz4HadoopCompressor) decompressBlockWithSize(block []byte, size int) ([]byte, error) {
+	if data, ok := lz4DecompressHadoop(block, size); ok {
+		return data, nil
+	}
+
+	return lz4DecompressBlockWithSize(block, size)
+}
+
+// lz4DecompressHadoop decompresses data that uses the Hadoop framing. If size is not negative,
+// the decompressed data needs to have exactly this size. If the data doesn't use the Hadoop
+// framing, false is returned.
+func lz4DecompressHadoop(block []byte, size int) ([]byte, bool) {
+	const headerSize = 8
+
+	var result []byte
+	for len(block) > 0 {
+		if len(block) < headerSize {
+			return nil, false
+		}
+
+		decompressedSize := int(binary.BigEndian.Uint32(block))
+		compressedSize := int(binary.BigEndian.Uint32(block[4:]))
+		block = block[headerSize:]
+
+		if compressedSize > len(block) || (size >= 0 && len(result)+decompressedSize > size) {
+			return nil, false
+		}
+		// the decompressed size is only trusted if it's possible for the compressed size, so that
+		// corrupt frames can't cause huge allocations.
+		if decompressedSize > lz4MaxCompressionRatio*compressedSize {
+			return nil, false
+		}
+
+		data, err := lz4DecompressBlockWithSize(block[:compressedSize], decompressedSize)
+		if err != nil || len(data) != decompressedSize {
+			return nil, false
+		}
+
+		result = append(result, data...)
+		block = block[compressedSize:]
+	}
+
+	if size >= 0 && len(result) != size {
+		return nil, false
+	}
+
+	return result, true
+}
+
+// compressBlock compresses the block using the provided method. If level is not nil, the block is compressed
+// with this compression level, which requires the block compressor to support compression levels.
+func compressBlock(block []byte, method parquet.CompressionCodec, level *int) ([]byte, error) {
 	compressorLock.RLock()
 	defer compressorLock.RUnlock()
 
@@ -84,10 +423,19 @@ func compressBlock(block []byte, method parquet.CompressionCodec) ([]byte, error
 		return nil, fmt.Errorf("method %q is not supported", method.String())
 	}
 
-	return c.CompressBlock(block)
+	if level == nil {
+		return c.CompressBlock(block)
+	}
+
+	lc, ok := c.(LeveledBlockCompressor)
+	if !ok {
+		return nil, fmt.Errorf("method %q doesn't support compression levels", method.String())
+	}
+
+	return lc.CompressBlockWithLevel(block, *level)
 }
 
-func decompressBlock(block []byte, method parquet.CompressionCodec) ([]byte, error) {
+func decompressBlock(block []byte, method parquet.CompressionCodec, size int) ([]byte, error) {
 	compressorLock.RLock()
 	defer compressorLock.RUnlock()
 
@@ -96,6 +444,10 @@ func decompressBlock(block []byte, method parquet.CompressionCodec) ([]byte, err
 		return nil, fmt.Errorf("method %q is not supported", method.String())
 	}
 
+	if sc, ok := c.(sizedBlockDecompressor); ok {
+		return sc.decompressBlockWithSize(block, size)
+	}
+
 	return c.DecompressBlock(block)
 }
 
@@ -109,7 +461,7 @@ func newBlockReader(buf []byte, codec parquet.CompressionCodec, compressedSize i
 	}
 
 	alloc.test(uint64(uncompressedSize))
-	res, err := decompressBlock(buf, codec)
+	res, err := decompressBlock(buf, codec, int(uncompressedSize))
 	if err != nil {
 		return nil, fmt.Errorf("decompression failed: %w", err)
 	}
@@ -123,10 +475,10 @@ func newBlockReader(buf []byte, codec parquet.CompressionCodec, compressedSize i
 }
 
 // RegisterBlockCompressor is a function to to register additional block compressors to the package. By default,
-// only UNCOMPRESSED, GZIP and SNAPPY are supported as parquet compression algorithms. The parquet file format
-// supports more compression algorithms, such as LZO, BROTLI, LZ4 and ZSTD. To limit the amount of external dependencies,
-// the number of supported algorithms was reduced to a core set. If you want to use any of the other compression
-// algorithms, please provide your own implementation of it in a way that satisfies the BlockCompressor interface,
+// only UNCOMPRESSED, GZIP, SNAPPY, ZSTD, LZ4_RAW and BROTLI are supported as parquet compression algorithms, and the
+// deprecated LZ4 is supported for reading. The parquet file format supports more compression algorithms, such as LZO.
+// To limit the amount of external dependencies, the number of supported algorithms was reduced to a core set. If you
+// want to use any of the other compression algorithms, please provide your own implementation of it in a way that satisfies the BlockCompressor interface,
 // and register it using this function from your code.
 func RegisterBlockCompressor(method parquet.CompressionCodec, compressor BlockCompressor) {
 	compressorLock.Lock()
@@ -154,4 +506,8 @@ func init() {
 	RegisterBlockCompressor(parquet.CompressionCodec_UNCOMPRESSED, plainCompressor{})
 	RegisterBlockCompressor(parquet.CompressionCodec_GZIP, gzipCompressor{})
 	RegisterBlockCompressor(parquet.CompressionCodec_SNAPPY, snappyCompressor{})
+	RegisterBlockCompressor(parquet.CompressionCodec_ZSTD, NewZstdBlockCompressor(ZstdDefaultLevel))
+	RegisterBlockCompressor(parquet.CompressionCodec_LZ4_RAW, lz4RawCompressor{})
+	RegisterBlockCompressor(parquet.CompressionCodec_LZ4, lz4HadoopCompressor{})
+	RegisterBlockCompressor(parquet.CompressionCodec_BROTLI, NewBrotliBlockCompressor(BrotliDefaultQuality))
 }
@@ -1,9 +1,13 @@
 package goparquet
 
 import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
 	"testing"
 
 	"github.com/fraugster/parquet-go/parquet"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,13 +24,196 @@ occaecat cupidatat non proident, sunt in culpa qui officia deserunt mollit anim
 		parquet.CompressionCodec_GZIP,
 		parquet.CompressionCodec_SNAPPY,
 		parquet.CompressionCodec_UNCOMPRESSED,
+		parquet.CompressionCodec_ZSTD,
+		parquet.CompressionCodec_LZ4_RAW,
+		parquet.CompressionCodec_BROTLI,
 	}
 
 	for _, m := range methods {
-		b, err := compressBlock(block, m)
+		b, err := compressBlock(block, m, nil)
 		require.NoError(t, err)
-		b2, err := decompressBlock(b, m)
+		b2, err := decompressBlock(b, m, len(block))
 		require.NoError(t, err)
 		assert.Equal(t, block, b2)
 	}
 }
+
+func TestZstdCompressionLevels(t *testing.T) {
+	block := bytes.Repeat([]byte("lorem ipsum dolor sit amet, consectetur adipiscing elit. "), 1000)
+
+	var sizes []int
+	for _, level := range []int{1, ZstdDefaultLevel, 22} {
+		c := NewZstdBlockCompressor(level)
+		b, err := c.CompressBlock(block)
+		require.NoError(t, err)
+		require.True(t, len(b) < len(block))
+		sizes = append(sizes, len(b))
+
+		// blocks can be decompressed independent of the level they were compressed with.
+		b2, err := decompressBlock(b, parquet.CompressionCodec_ZSTD, len(block))
+		require.NoError(t, err)
+		require.Equal(t, block, b2)
+	}
+	require.True(t, sizes[2] <= sizes[0], "best compression %d is larger than fastest %d", sizes[2], sizes[0])
+
+	_, err := NewZstdBlockCompressor(ZstdDefaultLevel).DecompressBlock([]byte("not zstd"))
+	require.Error(t, err)
+}
+
+func TestZstdDecompressedSize(t *testing.T) {
+	large := make([]byte, 64<<20)
+
+	b, err := compressBlock(large, parquet.CompressionCodec_ZSTD, nil)
+	require.NoError(t, err)
+
+	// frames that are larger than the page are rejected before they are decompressed.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = decompressBlock(b, parquet.CompressionCodec_ZSTD, 100)
+	runtime.ReadMemStats(&after)
+	require.Error(t, err)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+
+	// frames without content size are rejected once they are decompressed.
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(large[:1000])
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	_, err = decompressBlock(buf.Bytes(), parquet.CompressionCodec_ZSTD, 100)
+	require.Error(t, err)
+
+	b, err = decompressBlock(buf.Bytes(), parquet.CompressionCodec_ZSTD, 1000)
+	require.NoError(t, err)
+	require.Equal(t, large[:1000], b)
+}
+
+func TestLZ4Compressors(t *testing.T) {
+	block := bytes.Repeat([]byte("lorem ipsum dolor sit amet, consectetur adipiscing elit. "), 1000)
+
+	raw, err := compressBlock(block, parquet.CompressionCodec_LZ4_RAW, nil)
+	require.NoError(t, err)
+	require.True(t, len(raw) < len(block))
+
+	// the size of the decompressed block is not required.
+	b, err := lz4RawCompressor{}.DecompressBlock(raw)
+	require.NoError(t, err)
+	require.Equal(t, block, b)
+
+	empty, err := compressBlock(nil, parquet.CompressionCodec_LZ4_RAW, nil)
+	require.NoError(t, err)
+	b, err = decompressBlock(empty, parquet.CompressionCodec_LZ4_RAW, 0)
+	require.NoError(t, err)
+	require.Empty(t, b)
+
+	// LZ4 can't be written, but it can be read with and without Hadoop framing.
+	_, err = compressBlock(block, parquet.CompressionCodec_LZ4, nil)
+	require.Error(t, err)
+
+	b, err = decompressBlock(raw, parquet.CompressionCodec_LZ4, len(block))
+	require.NoError(t, err)
+	require.Equal(t, block, b)
+
+	b, err = lz4HadoopCompressor{}.DecompressBlock(raw)
+	require.NoError(t, err)
+	require.Equal(t, block, b)
+
+	var hadoop []byte
+	for _, part := range [][]byte{block[:20000], block[20000:]} {
+		comp, err := lz4CompressBlock(part)
+		require.NoError(t, err)
+		var header [8]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(part)))
+		binary.BigEndian.PutUint32(header[4:], uint32(len(comp)))
+		hadoop = append(append(hadoop, header[:]...), comp...)
+	}
+
+	b, err = decompressBlock(hadoop, parquet.CompressionCodec_LZ4, len(block))
+	require.NoError(t, err)
+	require.Equal(t, block, b)
+
+	b, err = lz4HadoopCompressor{}.DecompressBlock(hadoop)
+	require.NoError(t, err)
+	require.Equal(t, block, b)
+
+	_, err = decompressBlock([]byte("not lz4 at all"), parquet.CompressionCodec_LZ4, 100)
+	require.Error(t, err)
+
+	// a corrupt frame header must not result in a huge allocation.
+	corrupt := []byte{0xff, 0xff, 0xff, 0xf0, 0, 0, 0, 8, 0x80, 0, 0, 0, 0, 0, 0, 0}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = lz4HadoopCompressor{}.DecompressBlock(corrupt)
+	runtime.ReadMemStats(&after)
+	require.Error(t, err)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
+
+func TestBrotliQualityLevels(t *testing.T) {
+	block := bytes.Repeat([]byte("lorem ipsum dolor sit amet, consectetur adipiscing elit. "), 1000)
+
+	var sizes []int
+	for _, quality := range []int{-1, 0, BrotliDefaultQuality, 11, 12} {
+		b, err := NewBrotliBlockCompressor(quality).CompressBlock(block)
+		require.NoError(t, err)
+		sizes = append(sizes, len(b))
+
+		b2, err := decompressBlock(b, parquet.CompressionCodec_BROTLI, len(block))
+		require.NoError(t, err)
+		require.Equal(t, block, b2)
+	}
+	require.Equal(t, sizes[0], sizes[1], "quality below 0 is not clamped")
+	require.Equal(t, sizes[3], sizes[4], "quality above 11 is not clamped")
+	require.True(t, sizes[3] <= sizes[1], "best compression %d is larger than fastest %d", sizes[3], sizes[1])
+
+	_, err := NewBrotliBlockCompressor(BrotliDefaultQuality).DecompressBlock([]byte("not brotli"))
+	require.Error(t, err)
+}
+
+func TestBrotliDecompressedSize(t *testing.T) {
+	large := make([]byte, 64<<20)
+
+	b, err := compressBlock(large, parquet.CompressionCodec_BROTLI, nil)
+	require.NoError(t, err)
+
+	// streams that are larger than the page are rejected without decompressing them completely.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = decompressBlock(b, parquet.CompressionCodec_BROTLI, 100)
+	runtime.ReadMemStats(&after)
+	require.Error(t, err)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(8<<20))
+
+	_, err = decompressBlock(b[:len(b)/2], parquet.CompressionCodec_BROTLI, len(large))
+	require.Error(t, err)
+}
+
+func TestCompressionLevels(t *testing.T) {
+	block := bytes.Repeat([]byte("lorem ipsum dolor sit amet, consectetur adipiscing elit. "), 1000)
+
+	for _, codec := range []parquet.CompressionCodec{
+		parquet.CompressionCodec_GZIP,
+		parquet.CompressionCodec_ZSTD,
+		parquet.CompressionCodec_BROTLI,
+	} {
+		fast, err := compressBlock(block, codec, intPtr(1))
+		require.NoError(t, err)
+		best, err := compressBlock(block, codec, intPtr(9))
+		require.NoError(t, err)
+		require.True(t, len(best) <= len(fast), "%s: level 9 (%d bytes) is larger than level 1 (%d bytes)", codec, len(best), len(fast))
+
+		for _, b := range [][]byte{fast, best} {
+			b2, err := decompressBlock(b, codec, len(block))
+			require.NoError(t, err)
+			require.Equal(t, block, b2)
+		}
+	}
+
+	_, err := compressBlock(block, parquet.CompressionCodec_GZIP, intPtr(42))
+	require.Error(t, err)
+
+	_, err = compressBlock(block, parquet.CompressionCodec_SNAPPY, intPtr(1))
+	require.Error(t, err)
+}
@@ -32,10 +32,31 @@ type ColumnStore struct {
 
 	skipped bool
 
+	// hidden is true if the column is only read to evaluate a predicate, but not returned.
+	hidden bool
+
+	// pageFirstRows contains the index of the first row of every page within the row group, if
+	// the column chunk has an offset index. It is used to skip pages without decoding them.
+	pageFirstRows []int64
+
+	// pendingRows is the number of rows that need to be skipped before the next value is read.
+	pendingRows int64
+
+	// pendingValues is the number of non-null values of the current page that aren't decoded yet.
+	pendingValues int
+
+	// typedValues holds the values of the current page as a slice of their Go type, e.g. []int64,
+	// if they were read as a column batch.
+	typedValues interface{}
+
 	dataPages []*dataPage
 
 	maxPageSize int64
 
+	// statsTruncateLength is the maximum length of min and max values of byte array columns.
+	statsTruncateLength int
+
+	numRows        int64 // the number of rows in the current row group.
 	prevNumRecords int64 // this is just for correctly calculating how many rows are in a data page.
 
 	alloc *allocTracker
@@ -80,12 +101,21 @@ func (cs *ColumnStore) reset(rep parquet.FieldRepetitionType, maxR, maxD uint16)
 	cs.dLevels.reset(bits.Len16(maxD))
 	cs.readPos = 0
 	cs.skipped = false
+	cs.hidden = false
+	cs.pageFirstRows = nil
+	cs.pendingRows = 0
+	cs.pendingValues = 0
+	cs.typedValues = nil
+	cs.numRows = 0
 	cs.prevNumRecords = 0
 
 	cs.typedColumnStore.reset(rep)
 }
 
 func (cs *ColumnStore) appendRDLevel(rl, dl uint16) {
+	if rl == 0 {
+		cs.numRows++
+	}
 	cs.rLevels.appendSingle(int32(rl))
 	cs.dLevels.appendSingle(int32(dl))
 }
@@ -135,6 +165,91 @@ func (cs *ColumnStore) add(v interface{}, dL uint16, maxRL, rL uint16) error {
 	return nil
 }
 
+// addBatch appends the values and levels of a number of rows. The levels of every value, including
+// null values, are provided by dLevels and rLevels. If dLevels is nil, all values are defined, if
+// rLevels is nil, every value is a row of its own. Pages are flushed at row boundaries if required.
+func (cs *ColumnStore) addBatch(values interface{}, dLevels, rLevels []int32, numLevels int, maxD uint16) error {
+	for i, j := 0, 0; i < numLevels; i++ {
+		rl, dl := uint16(0), maxD
+		if rLevels != nil {
+			rl = uint16(rLevels[i])
+		}
+		if dLevels != nil {
+			dl = uint16(dLevels[i])
+		}
+
+		if rl == 0 && i > 0 {
+			if err := cs.flushPage(false); err != nil {
+				return err
+			}
+		}
+
+		if dl == maxD {
+			v, err := cs.batchValue(values, j)
+			if err != nil {
+				return err
+			}
+			cs.values.addValue(v, cs.sizeOf(v))
+			j++
+		} else {
+			cs.values.addValue(nil, 0)
+		}
+		cs.appendRDLevel(rl, dl)
+	}
+
+	return cs.flushPage(false)
+}
+
+// batchValue returns the value at index i of the values of a column batch and updates the statistics
+// with it. The values need to be checked using checkBatchValues first. Values of the types that have
+// statistics are only boxed once, the others are passed to getValues.
+func (cs *ColumnStore) batchValue(values interface{}, i int) (interface{}, error) {
+	var v interface{}
+	switch typed := values.(type) {
+	case []bool:
+		v = typed[i]
+	case []int32:
+		v = typed[i]
+		if store, ok := cs.typedColumnStore.(*int32Store); ok {
+			store.setMinMax(typed[i])
+			return v, nil
+		}
+	case []int64:
+		v = typed[i]
+		if store, ok := cs.typedColumnStore.(*int64Store); ok {
+			store.setMinMax(typed[i])
+			return v, nil
+		}
+	case [][12]byte:
+		v = typed[i]
+	case []float32:
+		v = typed[i]
+		if store, ok := cs.typedColumnStore.(*floatStore); ok {
+			store.setMinMax(typed[i])
+			return v, nil
+		}
+	case []float64:
+		v = typed[i]
+		if store, ok := cs.typedColumnStore.(*doubleStore); ok {
+			store.setMinMax(typed[i])
+			return v, nil
+		}
+	case [][]byte:
+		v = typed[i]
+		if store, ok := cs.typedColumnStore.(*byteArrayStore); ok {
+			return v, store.setMinMax(typed[i])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %T for values", values)
+	}
+
+	vals, err := cs.getValues(v)
+	if err != nil {
+		return nil, err
+	}
+	return vals[0], nil
+}
+
 func (cs *ColumnStore) estimateSize() (total int64) {
 	dictSize, noDictSize := cs.values.sizes()
 	if cs.useDictionary() {
@@ -153,15 +268,37 @@ func (cs *ColumnStore) getMaxPageSize() int64 {
 	return cs.maxPageSize
 }
 
-func (cs *ColumnStore) flushPage(sch *schema, force bool) error {
+func (cs *ColumnStore) getStatsTruncateLength() int {
+	if cs.statsTruncateLength == 0 {
+		return defaultStatsTruncateLength
+	}
+	return cs.statsTruncateLength
+}
+
+// minMaxValues returns the min and max values of the statistics, truncated to the configured length.
+func (cs *ColumnStore) minMaxValues(stats minMaxValues) (min, max []byte) {
+	typ := cs.parquetType()
+	order := columnStatsOrder(typ, cs.params())
+	length := cs.getStatsTruncateLength()
+	return truncateMinValue(typ, order, stats.minValue(), length), truncateMaxValue(typ, order, stats.maxValue(), length)
+}
+
+func (cs *ColumnStore) flushPage(force bool) error {
 	size := cs.estimateSize()
 
 	if !force && size < cs.getMaxPageSize() {
 		return nil
 	}
 
-	numRows := sch.numRecords - cs.prevNumRecords
-	cs.prevNumRecords = sch.numRecords
+	// don't write an empty trailing page if the previous page was flushed right after the last record.
+	if cs.dLevels.count == 0 && len(cs.dataPages) > 0 {
+		return nil
+	}
+
+	numRows := cs.numRows - cs.prevNumRecords
+	cs.prevNumRecords = cs.numRows
+
+	minValue, maxValue := cs.minMaxValues(cs.getPageStats())
 
 	cs.dataPages = append(cs.dataPages, &dataPage{
 		values:     cs.values.getValues(),
@@ -173,8 +310,8 @@ func (cs *ColumnStore) flushPage(sch *schema, force bool) error {
 		stats: &parquet.Statistics{
 			NullCount:     int64Ptr(int64(cs.values.nullValueCount())),
 			DistinctCount: int64Ptr(cs.values.distinctValueCount()),
-			MaxValue:      cs.getPageStats().maxValue(),
-			MinValue:      cs.getPageStats().minValue(),
+			MaxValue:      maxValue,
+			MinValue:      minValue,
 		},
 	})
 
@@ -187,6 +324,18 @@ func int64Ptr(v int64) *int64 {
 	return &v
 }
 
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
 // getRDLevelAt return the next rLevel in the read position, if there is no value left, it returns true
 // if the position is less than zero, then it returns the current position
 // NOTE: make sure always r is before d, in any function
@@ -210,6 +359,9 @@ func (cs *ColumnStore) getRDLevelAt(pos int) (int32, int32, bool) {
 }
 
 func (cs *ColumnStore) getNext() (v interface{}, err error) {
+	if err := cs.decodeValues(); err != nil {
+		return nil, err
+	}
 	v, err = cs.values.getNextValue()
 	if err != nil {
 		return nil, err
@@ -233,12 +385,13 @@ func (cs *ColumnStore) resetData() {
 	cs.getPageStats().reset()
 }
 
+// readNextPage decodes the levels of the next page. Its values are only decoded when they are read.
 func (cs *ColumnStore) readNextPage() error {
 	if cs.pageIdx >= len(cs.pages) {
 		return fmt.Errorf("out of range: requested page index = %d total number of pages = %d", cs.pageIdx, len(cs.pages))
 	}
 
-	data, dl, rl, err := cs.pages[cs.pageIdx].readValues(int(cs.pages[cs.pageIdx].numValues()))
+	dl, rl, notNull, err := cs.pages[cs.pageIdx].readLevels()
 	if err != nil {
 		return err
 	}
@@ -248,22 +401,234 @@ func (cs *ColumnStore) readNextPage() error {
 	cs.resetData()
 
 	cs.values.readPos = 0
+	cs.pendingValues = notNull
+	cs.typedValues = nil
+
+	cs.rLevels.appendArray(rl)
+	cs.dLevels.appendArray(dl)
+
+	return nil
+}
+
+// decodeValues decodes the values of the current page if they aren't decoded yet. Values that were
+// skipped before remain skipped.
+func (cs *ColumnStore) decodeValues() error {
+	var (
+		data []interface{}
+		err  error
+	)
+	switch {
+	case cs.pendingValues > 0:
+		data, err = cs.pages[cs.pageIdx-1].readValues(cs.pendingValues)
+	case cs.typedValues != nil && len(cs.values.valueList) == 0:
+		// the values were decoded by reading a column batch.
+		data, err = untypedValues(cs.parquetType(), cs.typedValues)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cs.pendingValues = 0
 
 	for _, v := range data {
 		cs.values.addValue(v, cs.sizeOf(v))
 	}
 
-	cs.rLevels.appendArray(rl)
-	cs.dLevels.appendArray(dl)
+	return nil
+}
+
+// skipRows skips the next n rows of the column store without returning them. A row consists
+// of all values from one repetition level 0 up to the next one. The rows are only skipped when
+// the column store is read the next time, so that rows skipped one at a time add up.
+func (cs *ColumnStore) skipRows(n int64) {
+	if cs.skipped {
+		return
+	}
+	cs.pendingRows += n
+}
+
+// skipPendingRows skips the rows that were skipped using skipRows. Only the levels of the skipped
+// rows are decoded, but not their values. If the first row of every page is known, pages that only
+// contain skipped rows are neither decompressed nor decoded.
+func (cs *ColumnStore) skipPendingRows(maxD int32) error {
+	for cs.pendingRows > 0 {
+		if cs.readPos >= cs.rLevels.count || cs.readPos >= cs.dLevels.count {
+			cs.skipPages()
+			if cs.pendingRows == 0 {
+				break
+			}
+			if err := cs.readNextPage(); err != nil {
+				return err
+			}
+		}
+
+		for first := true; cs.readPos < cs.rLevels.count && cs.readPos < cs.dLevels.count; first = false {
+			rl, dl, _ := cs.getRDLevelAt(cs.readPos)
+			if !first && rl == 0 {
+				break
+			}
+			if dl == maxD {
+				cs.values.readPos++
+			}
+			cs.readPos++
+		}
+		cs.pendingRows--
+	}
 
 	return nil
 }
 
+// skipPages skips the pages that only contain pending rows, if the first row of every page is known.
+// It must only be called when all values of the current page have been read.
+func (cs *ColumnStore) skipPages() {
+	if cs.pageFirstRows == nil || cs.pageIdx >= len(cs.pages) {
+		return
+	}
+
+	row := cs.pageFirstRows[cs.pageIdx]
+	for cs.pageIdx+1 < len(cs.pages) && cs.pageFirstRows[cs.pageIdx+1]-row <= cs.pendingRows {
+		cs.pageIdx++
+	}
+	cs.pendingRows -= cs.pageFirstRows[cs.pageIdx] - row
+}
+
+// readRows reads the next n rows of the column store, or fewer if the column chunk ends before. It
+// returns the non-null values as a slice of their Go type, which is nil if there are none, the levels
+// of all values, including null values, and the number of rows that were read.
+func (cs *ColumnStore) readRows(n int, maxD int32) (values interface{}, dLevels, rLevels []int32, rows int, err error) {
+	if err := cs.skipPendingRows(maxD); err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	for done := false; !done; {
+		if cs.readPos >= cs.rLevels.count || cs.readPos >= cs.dLevels.count {
+			if cs.pageIdx >= len(cs.pages) {
+				break
+			}
+			if err := cs.readNextPage(); err != nil {
+				return nil, nil, nil, 0, err
+			}
+			continue
+		}
+
+		numValues := 0
+		for ; cs.readPos < cs.rLevels.count && cs.readPos < cs.dLevels.count; cs.readPos++ {
+			rl, dl, _ := cs.getRDLevelAt(cs.readPos)
+			if rl == 0 {
+				if rows == n {
+					done = true
+					break
+				}
+				rows++
+			}
+
+			if dl == maxD {
+				numValues++
+			}
+			dLevels = append(dLevels, dl)
+			rLevels = append(rLevels, rl)
+		}
+
+		if numValues == 0 {
+			continue
+		}
+
+		pageValues, err := cs.typedPageValues()
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		from := cs.values.readPos
+		if from+numValues > typedLen(pageValues) {
+			return nil, nil, nil, 0, errors.New("out of range")
+		}
+		values = appendTypedValues(values, pageValues, from, from+numValues)
+		cs.values.readPos += numValues
+	}
+
+	return values, dLevels, rLevels, rows, nil
+}
+
+// typedPageValues returns the values of the current page as a slice of their Go type. If they aren't
+// decoded yet, they are decoded without boxing every value if the values decoder of the page supports it.
+func (cs *ColumnStore) typedPageValues() (interface{}, error) {
+	if cs.typedValues != nil {
+		return cs.typedValues, nil
+	}
+
+	if cs.pendingValues == 0 {
+		// the values were decoded by reading rows.
+		typed, err := typedValues(cs.parquetType(), cs.values.valueList)
+		if err != nil {
+			return nil, err
+		}
+		cs.typedValues = typed
+		return typed, nil
+	}
+
+	page := cs.pages[cs.pageIdx-1]
+	typed := newTypedValues(cs.parquetType(), cs.pendingValues)
+	ok, err := page.readTypedValues(typed)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		data, err := page.readValues(cs.pendingValues)
+		if err != nil {
+			return nil, err
+		}
+		if typed, err = typedValues(cs.parquetType(), data); err != nil {
+			return nil, err
+		}
+	}
+
+	cs.pendingValues = 0
+	cs.typedValues = typed
+	return typed, nil
+}
+
+// peekRow appends the non-null values of the next row to dst without changing the read position.
+func (cs *ColumnStore) peekRow(dst []interface{}, maxD int32) ([]interface{}, error) {
+	if err := cs.skipPendingRows(maxD); err != nil {
+		return nil, err
+	}
+
+	if cs.readPos >= cs.rLevels.count || cs.readPos >= cs.dLevels.count {
+		if err := cs.readNextPage(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cs.decodeValues(); err != nil {
+		return nil, err
+	}
+
+	valuePos := cs.values.readPos
+	for pos := cs.readPos; ; pos++ {
+		rl, dl, last := cs.getRDLevelAt(pos)
+		if last || (pos > cs.readPos && rl == 0) {
+			return dst, nil
+		}
+		if dl < maxD {
+			continue
+		}
+		if valuePos >= len(cs.values.valueList) {
+			return nil, errors.New("out of range")
+		}
+		dst = append(dst, cs.values.valueList[valuePos])
+		valuePos++
+	}
+}
+
 func (cs *ColumnStore) get(maxD, maxR int32) (interface{}, int32, error) {
-	if cs.skipped {
+	if cs.skipped || cs.hidden {
 		return nil, 0, nil
 	}
 
+	if err := cs.skipPendingRows(maxD); err != nil {
+		return nil, 0, err
+	}
+
 	if cs.readPos >= cs.rLevels.count || cs.readPos >= cs.dLevels.count {
 		if err := cs.readNextPage(); err != nil {
 			return nil, 0, err
@@ -410,7 +775,7 @@ func NewInt96Store(enc parquet.Encoding, useDict bool, params *ColumnParameters)
 // then a dictionary is used, otherwise a dictionary will never be used to encode the data.
 func NewFloatStore(enc parquet.Encoding, useDict bool, params *ColumnParameters) (*ColumnStore, error) {
 	switch enc {
-	case parquet.Encoding_PLAIN:
+	case parquet.Encoding_PLAIN, parquet.Encoding_BYTE_STREAM_SPLIT:
 	default:
 		return nil, fmt.Errorf("encoding %q is not supported on this type", enc)
 	}
@@ -421,7 +786,7 @@ func NewFloatStore(enc parquet.Encoding, useDict bool, params *ColumnParameters)
 // then a dictionary is used, otherwise a dictionary will never be used to encode the data.
 func NewDoubleStore(enc parquet.Encoding, useDict bool, params *ColumnParameters) (*ColumnStore, error) {
 	switch enc {
-	case parquet.Encoding_PLAIN:
+	case parquet.Encoding_PLAIN, parquet.Encoding_BYTE_STREAM_SPLIT:
 	default:
 		return nil, fmt.Errorf("encoding %q is not supported on this type", enc)
 	}
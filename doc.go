@@ -63,6 +63,10 @@
 // to predict the compressed data size, so the actual row groups written to disk may be a lot
 // smaller than uncompressed, depending on how efficiently your data can be compressed.
 //
+// If the data is already in columnar form, the values of each column can be added in batches of
+// typed slices together with their definition and repetition levels using AddColumnBatch instead.
+// All columns need to contain the same number of rows when the row group is flushed.
+//
 // When you're done writing, always use the Close method to flush any remaining data and to
 // write the file's footer.
 //
@@ -75,6 +79,26 @@
 // and iterate through the row data in each row group (using NextRow). To find out how many rows
 // to expect in total and per row group, use the NumRows and RowGroupNumRows methods. The number
 // of row groups can be determined using the RowGroupCount method.
+//
+// For analytical scans that only need a few columns, the values of a single column can be read
+// in batches of typed slices together with their definition and repetition levels using
+// ReadColumnBatch, which avoids assembling rows:
+//
+//	for rg := 0; rg < r.RowGroupCount(); rg++ {
+//		if err := r.SeekToRowGroup(rg); err != nil {
+//			// ...
+//		}
+//		for {
+//			batch, err := r.ReadColumnBatch(goparquet.ColumnPath{"amount"}, 1024)
+//			if err == io.EOF {
+//				break
+//			}
+//			// ...
+//			for _, v := range batch.Values.([]int64) {
+//				sum += v
+//			}
+//		}
+//	}
 package goparquet
 
 //go:generate go run bitpack_gen.go
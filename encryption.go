@@ -0,0 +1,990 @@
+package goparquet
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// This file implements the parquet modular encryption as specified in
+// https://github.com/apache/parquet-format/blob/master/Encryption.md
+//
+// Every encrypted part of a parquet file is called a module. Modules are encrypted
+// with AES-GCM, except for data and dictionary pages, which are encrypted with AES-CTR
+// if the AES_GCM_CTR_V1 algorithm is used. Every module is bound to its position in the
+// file by using additional authenticated data (AAD) that consists of the file AAD, the
+// module type and the ordinals of the row group, column and page that the module belongs to.
+
+var magicEncrypted = []byte{'P', 'A', 'R', 'E'}
+
+// EncryptionAlgorithm describes the algorithm that is used to encrypt a parquet file.
+type EncryptionAlgorithm int
+
+const (
+	// EncryptionAlgorithmAESGCM encrypts all modules of the file using AES-GCM. This is
+	// the default algorithm.
+	EncryptionAlgorithmAESGCM EncryptionAlgorithm = iota
+	// EncryptionAlgorithmAESGCMCTR encrypts data and dictionary pages using AES-CTR and
+	// all other modules using AES-GCM. This is faster than EncryptionAlgorithmAESGCM but
+	// doesn't protect the integrity of the pages.
+	EncryptionAlgorithmAESGCMCTR
+)
+
+const (
+	moduleFooter byte = iota
+	moduleColumnMetaData
+	moduleDataPage
+	moduleDictionaryPage
+	moduleDataPageHeader
+	moduleDictionaryPageHeader
+	moduleColumnIndex
+	moduleOffsetIndex
+	moduleBloomFilterHeader
+	moduleBloomFilterBitset
+)
+
+const (
+	encryptionNonceSize   = 12
+	encryptionTagSize     = 16
+	encryptionLengthSize  = 4
+	aadFileUniqueSize     = 8
+	encryptionCTRInitial  = 1
+	encryptionModuleLimit = math.MaxInt16
+)
+
+func validateEncryptionKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("invalid key length %d, needs to be 16, 24 or 32 bytes", len(key))
+	}
+}
+
+// moduleAAD returns the additional authenticated data of a module, consisting of the file AAD,
+// the module type and the ordinals of the module, each of them as 2 byte little-endian value.
+func moduleAAD(fileAAD []byte, moduleType byte, ordinals ...int16) []byte {
+	aad := make([]byte, 0, len(fileAAD)+1+2*len(ordinals))
+	aad = append(aad, fileAAD...)
+	aad = append(aad, moduleType)
+	for _, o := range ordinals {
+		aad = append(aad, byte(o), byte(o>>8))
+	}
+	return aad
+}
+
+type moduleCipher struct {
+	block cipher.Block
+	gcm   cipher.AEAD
+}
+
+func newModuleCipher(key []byte) (*moduleCipher, error) {
+	if err := validateEncryptionKey(key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &moduleCipher{block: block, gcm: gcm}, nil
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, encryptionNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("creating nonce failed: %w", err)
+	}
+	return nonce, nil
+}
+
+// encryptGCM encrypts the data with AES-GCM and returns the module, consisting of the
+// length, the nonce, the cipher text and the tag.
+func (c *moduleCipher) encryptGCM(data, aad []byte) ([]byte, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, encryptionLengthSize, encryptionLengthSize+encryptionNonceSize+len(data)+encryptionTagSize)
+	binary.LittleEndian.PutUint32(buf, uint32(encryptionNonceSize+len(data)+encryptionTagSize))
+	buf = append(buf, nonce...)
+	return c.gcm.Seal(buf, nonce, data, aad), nil
+}
+
+// encryptCTR encrypts the data with AES-CTR and returns the module, consisting of the
+// length, the nonce and the cipher text.
+func (c *moduleCipher) encryptCTR(data []byte) ([]byte, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, encryptionLengthSize+encryptionNonceSize+len(data))
+	binary.LittleEndian.PutUint32(buf, uint32(encryptionNonceSize+len(data)))
+	copy(buf[encryptionLengthSize:], nonce)
+
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, nonce)
+	binary.BigEndian.PutUint32(iv[encryptionNonceSize:], encryptionCTRInitial)
+	cipher.NewCTR(c.block, iv).XORKeyStream(buf[encryptionLengthSize+encryptionNonceSize:], data)
+
+	return buf, nil
+}
+
+// signFooter returns the nonce and the tag of the AES-GCM encrypted footer. They are appended to the
+// footer in plaintext footer mode to allow readers to verify the integrity of the footer.
+func (c *moduleCipher) signFooter(footer, aad []byte) ([]byte, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := c.gcm.Seal(nil, nonce, footer, aad)
+	return append(nonce, sealed[len(footer):]...), nil
+}
+
+func serializeThrift(ctx context.Context, tw thriftWriter) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeThrift(ctx, tw, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type columnKeyOptions struct {
+	path        ColumnPath
+	key         []byte
+	keyMetadata []byte
+}
+
+type encryptionOptions struct {
+	algorithm         EncryptionAlgorithm
+	footerKey         []byte
+	footerKeyMetadata []byte
+	columnKeys        []*columnKeyOptions
+	plaintextFooter   bool
+	aadPrefix         []byte
+	storeAADPrefix    bool
+}
+
+type columnKey struct {
+	cipher   *moduleCipher
+	metaData *parquet.ColumnCryptoMetaData
+}
+
+// fileEncryptor holds everything required to encrypt a parquet file.
+type fileEncryptor struct {
+	opts *encryptionOptions
+
+	footerCipher  *moduleCipher
+	aadFileUnique []byte
+	fileAAD       []byte
+
+	// columnKeys contains the keys of all encrypted columns, indexed by their flat name.
+	// If no column keys were configured, all columns are encrypted with the footer key.
+	columnKeys map[string]*columnKey
+}
+
+func newFileEncryptor(sch *schema, opts *encryptionOptions) (*fileEncryptor, error) {
+	if opts.footerKey == nil {
+		return nil, errors.New("no footer key provided")
+	}
+
+	footerCipher, err := newModuleCipher(opts.footerKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid footer key: %w", err)
+	}
+
+	aadFileUnique := make([]byte, aadFileUniqueSize)
+	if _, err := io.ReadFull(rand.Reader, aadFileUnique); err != nil {
+		return nil, fmt.Errorf("creating file AAD failed: %w", err)
+	}
+
+	fe := &fileEncryptor{
+		opts:          opts,
+		footerCipher:  footerCipher,
+		aadFileUnique: aadFileUnique,
+		fileAAD:       append(append([]byte{}, opts.aadPrefix...), aadFileUnique...),
+	}
+
+	if len(opts.columnKeys) == 0 {
+		return fe, nil
+	}
+
+	fe.columnKeys = make(map[string]*columnKey)
+	for _, ck := range opts.columnKeys {
+		col := sch.GetColumnByPath(ck.path)
+		if col == nil || !col.DataColumn() {
+			return nil, fmt.Errorf("encryption key provided for unknown column %s", ck.path.flatName())
+		}
+
+		c, err := newModuleCipher(ck.key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key for column %s: %w", ck.path.flatName(), err)
+		}
+
+		fe.columnKeys[ck.path.flatName()] = &columnKey{
+			cipher: c,
+			metaData: &parquet.ColumnCryptoMetaData{
+				ENCRYPTION_WITH_COLUMN_KEY: &parquet.EncryptionWithColumnKey{
+					PathInSchema: ck.path,
+					KeyMetadata:  ck.keyMetadata,
+				},
+			},
+		}
+	}
+
+	return fe, nil
+}
+
+func (fe *fileEncryptor) magic() []byte {
+	if fe == nil || fe.opts.plaintextFooter {
+		return magic
+	}
+	return magicEncrypted
+}
+
+func (fe *fileEncryptor) algorithm() *parquet.EncryptionAlgorithm {
+	var aadPrefix []byte
+	var supplyAADPrefix *bool
+	if len(fe.opts.aadPrefix) > 0 {
+		if fe.opts.storeAADPrefix {
+			aadPrefix = fe.opts.aadPrefix
+		} else {
+			supplyAADPrefix = boolPtr(true)
+		}
+	}
+
+	if fe.opts.algorithm == EncryptionAlgorithmAESGCMCTR {
+		return &parquet.EncryptionAlgorithm{
+			AES_GCM_CTR_V1: &parquet.AesGcmCtrV1{
+				AadPrefix:       aadPrefix,
+				AadFileUnique:   fe.aadFileUnique,
+				SupplyAadPrefix: supplyAADPrefix,
+			},
+		}
+	}
+
+	return &parquet.EncryptionAlgorithm{
+		AES_GCM_V1: &parquet.AesGcmV1{
+			AadPrefix:       aadPrefix,
+			AadFileUnique:   fe.aadFileUnique,
+			SupplyAadPrefix: supplyAADPrefix,
+		},
+	}
+}
+
+// columnChunkEncryptor returns the encryptor for a column chunk, identified by its row group
+// and column ordinal. If the column is not encrypted, nil is returned.
+func (fe *fileEncryptor) columnChunkEncryptor(path ColumnPath, rowGroup, column int) (*columnChunkEncryptor, error) {
+	if fe == nil {
+		return nil, nil
+	}
+
+	key := &columnKey{
+		cipher: fe.footerCipher,
+		metaData: &parquet.ColumnCryptoMetaData{
+			ENCRYPTION_WITH_FOOTER_KEY: parquet.NewEncryptionWithFooterKey(),
+		},
+	}
+	if fe.columnKeys != nil {
+		key = fe.columnKeys[path.flatName()]
+		if key == nil {
+			return nil, nil
+		}
+	}
+
+	if rowGroup > encryptionModuleLimit {
+		return nil, fmt.Errorf("encrypted files can't contain more than %d row groups", encryptionModuleLimit+1)
+	}
+	if column > encryptionModuleLimit {
+		return nil, fmt.Errorf("encrypted files can't contain more than %d columns", encryptionModuleLimit+1)
+	}
+
+	return &columnChunkEncryptor{
+		file:     fe,
+		key:      key,
+		rowGroup: int16(rowGroup),
+		column:   int16(column),
+	}, nil
+}
+
+// encryptColumnMetaData sets the crypto meta data of all encrypted column chunks and encrypts their
+// column meta data if required. In plaintext footer mode, the column meta data remains in the footer
+// but without the statistics. In encrypted footer mode, the column meta data of columns that are
+// encrypted with the footer key doesn't need to be encrypted separately.
+func (fe *fileEncryptor) encryptColumnMetaData(ctx context.Context, rowGroups []*parquet.RowGroup) error {
+	for i, rg := range rowGroups {
+		for j, chunk := range rg.Columns {
+			enc, err := fe.columnChunkEncryptor(chunk.MetaData.PathInSchema, i, j)
+			if err != nil {
+				return err
+			}
+			if enc == nil {
+				continue
+			}
+
+			chunk.CryptoMetadata = enc.key.metaData
+
+			if !fe.opts.plaintextFooter && enc.key.metaData.ENCRYPTION_WITH_FOOTER_KEY != nil {
+				continue
+			}
+
+			data, err := serializeThrift(ctx, chunk.MetaData)
+			if err != nil {
+				return err
+			}
+
+			chunk.EncryptedColumnMetadata, err = enc.encryptModule(moduleColumnMetaData, data)
+			if err != nil {
+				return err
+			}
+
+			if fe.opts.plaintextFooter {
+				redacted := *chunk.MetaData
+				redacted.Statistics = nil
+				redacted.EncodingStats = nil
+				chunk.MetaData = &redacted
+			} else {
+				chunk.MetaData = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeFooter writes the file meta data. In encrypted footer mode, the file crypto meta data is
+// written followed by the encrypted file meta data. In plaintext footer mode, the file meta data
+// is written in plaintext, followed by the nonce and tag to verify its integrity.
+func (fe *fileEncryptor) writeFooter(ctx context.Context, w io.Writer, meta *parquet.FileMetaData) error {
+	footerAAD := moduleAAD(fe.fileAAD, moduleFooter)
+
+	if fe.opts.plaintextFooter {
+		meta.EncryptionAlgorithm = fe.algorithm()
+		meta.FooterSigningKeyMetadata = fe.opts.footerKeyMetadata
+
+		data, err := serializeThrift(ctx, meta)
+		if err != nil {
+			return err
+		}
+
+		signature, err := fe.footerCipher.signFooter(data, footerAAD)
+		if err != nil {
+			return err
+		}
+
+		return writeFull(w, append(data, signature...))
+	}
+
+	cryptoMeta := &parquet.FileCryptoMetaData{
+		EncryptionAlgorithm: fe.algorithm(),
+		KeyMetadata:         fe.opts.footerKeyMetadata,
+	}
+	if err := writeThrift(ctx, cryptoMeta, w); err != nil {
+		return err
+	}
+
+	data, err := serializeThrift(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	module, err := fe.footerCipher.encryptGCM(data, footerAAD)
+	if err != nil {
+		return err
+	}
+
+	return writeFull(w, module)
+}
+
+// columnChunkEncryptor encrypts the modules of a single column chunk. All methods can be called on
+// a nil columnChunkEncryptor, in which case the modules are written unencrypted.
+type columnChunkEncryptor struct {
+	file     *fileEncryptor
+	key      *columnKey
+	rowGroup int16
+	column   int16
+}
+
+func (e *columnChunkEncryptor) encryptModule(moduleType byte, data []byte) ([]byte, error) {
+	return e.key.cipher.encryptGCM(data, moduleAAD(e.file.fileAAD, moduleType, e.rowGroup, e.column))
+}
+
+// writeModule writes the data as a module of the provided type.
+func (e *columnChunkEncryptor) writeModule(moduleType byte, data []byte, w io.Writer) error {
+	if e == nil {
+		return writeFull(w, data)
+	}
+
+	module, err := e.encryptModule(moduleType, data)
+	if err != nil {
+		return err
+	}
+
+	return writeFull(w, module)
+}
+
+// writeThrift writes the thrift structure as a module of the provided type.
+func (e *columnChunkEncryptor) writeThrift(ctx context.Context, moduleType byte, tw thriftWriter, w io.Writer) error {
+	if e == nil {
+		return writeThrift(ctx, tw, w)
+	}
+
+	data, err := serializeThrift(ctx, tw)
+	if err != nil {
+		return err
+	}
+
+	return e.writeModule(moduleType, data, w)
+}
+
+// dataPageEncryptor returns the encryptor for the data page with the provided ordinal.
+func (e *columnChunkEncryptor) dataPageEncryptor(ordinal int) (*pageEncryptor, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	if ordinal > encryptionModuleLimit {
+		return nil, fmt.Errorf("encrypted column chunks can't contain more than %d pages", encryptionModuleLimit+1)
+	}
+
+	return &pageEncryptor{
+		chunk:      e,
+		pageType:   moduleDataPage,
+		headerType: moduleDataPageHeader,
+		ordinals:   []int16{e.rowGroup, e.column, int16(ordinal)},
+	}, nil
+}
+
+// dictionaryPageEncryptor returns the encryptor for the dictionary page.
+func (e *columnChunkEncryptor) dictionaryPageEncryptor() *pageEncryptor {
+	if e == nil {
+		return nil
+	}
+
+	return &pageEncryptor{
+		chunk:      e,
+		pageType:   moduleDictionaryPage,
+		headerType: moduleDictionaryPageHeader,
+		ordinals:   []int16{e.rowGroup, e.column},
+	}
+}
+
+// pageEncryptor encrypts a single page and its header. All methods can be called on a nil
+// pageEncryptor, in which case the page is written unencrypted.
+type pageEncryptor struct {
+	chunk      *columnChunkEncryptor
+	pageType   byte
+	headerType byte
+	ordinals   []int16
+}
+
+// encryptPage encrypts the page data. This needs to happen before the page header is created,
+// as the compressed page size in the header is the size of the encrypted page.
+func (e *pageEncryptor) encryptPage(data []byte) ([]byte, error) {
+	if e == nil {
+		return data, nil
+	}
+
+	c := e.chunk.key.cipher
+	if e.chunk.file.opts.algorithm == EncryptionAlgorithmAESGCMCTR {
+		return c.encryptCTR(data)
+	}
+	return c.encryptGCM(data, moduleAAD(e.chunk.file.fileAAD, e.pageType, e.ordinals...))
+}
+
+func (e *pageEncryptor) writeHeader(ctx context.Context, header *parquet.PageHeader, w io.Writer) error {
+	if e == nil {
+		return writeThrift(ctx, header, w)
+	}
+
+	data, err := serializeThrift(ctx, header)
+	if err != nil {
+		return err
+	}
+
+	module, err := e.chunk.key.cipher.encryptGCM(data, moduleAAD(e.chunk.file.fileAAD, e.headerType, e.ordinals...))
+	if err != nil {
+		return err
+	}
+
+	return writeFull(w, module)
+}
+
+// ErrKeyNotAvailable is returned, possibly wrapped, by a KeyRetriever if it doesn't have the requested
+// key. Only then can files with a plaintext footer be read without verifying the footer, and only then
+// are columns reported as inaccessible instead of failing to open the file.
+var ErrKeyNotAvailable = errors.New("key not available")
+
+// KeyRetriever is used to retrieve the keys that are required to decrypt encrypted parquet
+// files, based on the key meta data that is stored in the file.
+type KeyRetriever interface {
+	// RetrieveKey returns the key for the provided key meta data. If the key is not
+	// available, an error that wraps ErrKeyNotAvailable is returned.
+	RetrieveKey(keyMetadata []byte) ([]byte, error)
+}
+
+// KeyRetrieverFunc is an adapter to allow the use of ordinary functions as KeyRetriever.
+type KeyRetrieverFunc func(keyMetadata []byte) ([]byte, error)
+
+// RetrieveKey calls f(keyMetadata).
+func (f KeyRetrieverFunc) RetrieveKey(keyMetadata []byte) ([]byte, error) {
+	return f(keyMetadata)
+}
+
+// readModule reads a module that is prefixed with its length.
+func readModule(r io.Reader, alloc *allocTracker) ([]byte, error) {
+	var buf [encryptionLengthSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, fmt.Errorf("reading module length failed: %w", err)
+	}
+
+	ln := binary.LittleEndian.Uint32(buf[:])
+	if ln > math.MaxInt32-encryptionLengthSize {
+		return nil, fmt.Errorf("invalid module length %d", ln)
+	}
+
+	alloc.test(uint64(ln))
+
+	module := make([]byte, encryptionLengthSize+int(ln))
+	copy(module, buf[:])
+	if _, err := io.ReadFull(r, module[encryptionLengthSize:]); err != nil {
+		return nil, fmt.Errorf("reading module failed: %w", err)
+	}
+
+	return module, nil
+}
+
+func moduleLength(module []byte, minLength int) error {
+	if len(module) < encryptionLengthSize+minLength {
+		return fmt.Errorf("module of %d bytes is too short", len(module))
+	}
+	if ln := binary.LittleEndian.Uint32(module); int64(ln) != int64(len(module)-encryptionLengthSize) {
+		return fmt.Errorf("module length %d doesn't match the actual length %d", ln, len(module)-encryptionLengthSize)
+	}
+	return nil
+}
+
+// decryptGCM decrypts a module that was encrypted with AES-GCM.
+func (c *moduleCipher) decryptGCM(module, aad []byte) ([]byte, error) {
+	if err := moduleLength(module, encryptionNonceSize+encryptionTagSize); err != nil {
+		return nil, err
+	}
+
+	nonce := module[encryptionLengthSize : encryptionLengthSize+encryptionNonceSize]
+	data, err := c.gcm.Open(nil, nonce, module[encryptionLengthSize+encryptionNonceSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting module failed: %w", err)
+	}
+
+	return data, nil
+}
+
+// decryptCTR decrypts a module that was encrypted with AES-CTR.
+func (c *moduleCipher) decryptCTR(module []byte) ([]byte, error) {
+	if err := moduleLength(module, encryptionNonceSize); err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, module[encryptionLengthSize:encryptionLengthSize+encryptionNonceSize])
+	binary.BigEndian.PutUint32(iv[encryptionNonceSize:], encryptionCTRInitial)
+
+	data := make([]byte, len(module)-encryptionLengthSize-encryptionNonceSize)
+	cipher.NewCTR(c.block, iv).XORKeyStream(data, module[encryptionLengthSize+encryptionNonceSize:])
+
+	return data, nil
+}
+
+// verifyFooter verifies the signature of a plaintext footer.
+func (c *moduleCipher) verifyFooter(footer, signature, aad []byte) error {
+	if len(signature) != encryptionNonceSize+encryptionTagSize {
+		return fmt.Errorf("invalid footer signature length %d", len(signature))
+	}
+
+	sealed := c.gcm.Seal(nil, signature[:encryptionNonceSize], footer, aad)
+	if subtle.ConstantTimeCompare(sealed[len(footer):], signature[encryptionNonceSize:]) != 1 {
+		return errors.New("footer signature verification failed")
+	}
+
+	return nil
+}
+
+type decryptionOptions struct {
+	keyRetriever KeyRetriever
+	aadPrefix    []byte
+}
+
+// fileDecryptor holds everything required to decrypt a parquet file.
+type fileDecryptor struct {
+	opts *decryptionOptions
+
+	algorithm EncryptionAlgorithm
+	fileAAD   []byte
+
+	// footerCipher is nil if the footer key is not available, which is only possible
+	// for files with a plaintext footer.
+	footerCipher *moduleCipher
+
+	// footerVerified is true if the footer was decrypted or its signature was verified.
+	footerVerified bool
+
+	// columnCiphers contains the ciphers of all accessible encrypted columns, inaccessible
+	// contains the reasons why encrypted columns are inaccessible. Both are indexed by the
+	// column's flat name.
+	columnCiphers map[string]*moduleCipher
+	inaccessible  map[string]error
+
+	// mtx protects columnCiphers and inaccessible once the file meta data has been decrypted.
+	mtx sync.Mutex
+}
+
+func newFileDecryptor(alg *parquet.EncryptionAlgorithm, opts *decryptionOptions) (*fileDecryptor, error) {
+	if opts == nil {
+		opts = &decryptionOptions{}
+	}
+
+	fd := &fileDecryptor{
+		opts:          opts,
+		columnCiphers: make(map[string]*moduleCipher),
+		inaccessible:  make(map[string]error),
+	}
+
+	var (
+		aadPrefix       []byte
+		aadFileUnique   []byte
+		supplyAADPrefix bool
+	)
+	switch {
+	case alg == nil:
+		return nil, errors.New("missing encryption algorithm")
+	case alg.AES_GCM_V1 != nil:
+		fd.algorithm = EncryptionAlgorithmAESGCM
+		aadPrefix, aadFileUnique, supplyAADPrefix = alg.AES_GCM_V1.AadPrefix, alg.AES_GCM_V1.AadFileUnique, alg.AES_GCM_V1.GetSupplyAadPrefix()
+	case alg.AES_GCM_CTR_V1 != nil:
+		fd.algorithm = EncryptionAlgorithmAESGCMCTR
+		aadPrefix, aadFileUnique, supplyAADPrefix = alg.AES_GCM_CTR_V1.AadPrefix, alg.AES_GCM_CTR_V1.AadFileUnique, alg.AES_GCM_CTR_V1.GetSupplyAadPrefix()
+	default:
+		return nil, errors.New("unsupported encryption algorithm")
+	}
+
+	if opts.aadPrefix != nil {
+		if aadPrefix != nil && !bytes.Equal(aadPrefix, opts.aadPrefix) {
+			return nil, errors.New("provided AAD prefix doesn't match the AAD prefix stored in the file")
+		}
+		aadPrefix = opts.aadPrefix
+	} else if supplyAADPrefix {
+		return nil, errors.New("file requires an AAD prefix, but none was provided")
+	}
+
+	fd.fileAAD = append(append([]byte{}, aadPrefix...), aadFileUnique...)
+
+	return fd, nil
+}
+
+func (fd *fileDecryptor) retrieveCipher(keyMetadata []byte) (*moduleCipher, error) {
+	if fd.opts.keyRetriever == nil {
+		return nil, fmt.Errorf("no key retriever provided: %w", ErrKeyNotAvailable)
+	}
+
+	key, err := fd.opts.keyRetriever.RetrieveKey(keyMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving key failed: %w", err)
+	}
+
+	return newModuleCipher(key)
+}
+
+// setFooterKey retrieves the footer key. If the key is not available, an error is returned.
+func (fd *fileDecryptor) setFooterKey(keyMetadata []byte) error {
+	c, err := fd.retrieveCipher(keyMetadata)
+	if err != nil {
+		return fmt.Errorf("footer key: %w", err)
+	}
+	fd.footerCipher = c
+	return nil
+}
+
+// decryptFooter decrypts the encrypted file meta data.
+func (fd *fileDecryptor) decryptFooter(ctx context.Context, module []byte) (*parquet.FileMetaData, error) {
+	data, err := fd.footerCipher.decryptGCM(module, moduleAAD(fd.fileAAD, moduleFooter))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting footer failed: %w", err)
+	}
+
+	meta := &parquet.FileMetaData{}
+	if err := readThrift(ctx, meta, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	fd.footerVerified = true
+
+	return meta, nil
+}
+
+// verifyFooter verifies the signature of a plaintext footer. Footers can only be verified if the
+// footer key is available.
+func (fd *fileDecryptor) verifyFooter(footer, signature []byte) error {
+	if fd.footerCipher == nil {
+		return nil
+	}
+	if err := fd.footerCipher.verifyFooter(footer, signature, moduleAAD(fd.fileAAD, moduleFooter)); err != nil {
+		return err
+	}
+	fd.footerVerified = true
+	return nil
+}
+
+// decryptColumnMetaData retrieves the keys of all encrypted columns and decrypts their column meta
+// data. Columns whose keys aren't available are marked as inaccessible, all other errors, e.g. failing
+// to retrieve a key or to decrypt the column meta data, are returned.
+func (fd *fileDecryptor) decryptColumnMetaData(ctx context.Context, meta *parquet.FileMetaData) error {
+	for i, rg := range meta.RowGroups {
+		for j, chunk := range rg.Columns {
+			if chunk.CryptoMetadata == nil {
+				continue
+			}
+
+			path, c, err := fd.columnCipher(chunk)
+			if err == nil && chunk.EncryptedColumnMetadata != nil {
+				err = fd.decryptChunkMetaData(ctx, c, chunk, i, j)
+			}
+			if err == nil {
+				continue
+			}
+
+			name := ColumnPath(path).flatName()
+			if !errors.Is(err, ErrKeyNotAvailable) {
+				return fmt.Errorf("column %s: %w", name, err)
+			}
+
+			delete(fd.columnCiphers, name)
+			if _, ok := fd.inaccessible[name]; !ok {
+				fd.inaccessible[name] = err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (fd *fileDecryptor) columnCipher(chunk *parquet.ColumnChunk) ([]string, *moduleCipher, error) {
+	cm := chunk.CryptoMetadata
+	switch {
+	case cm.ENCRYPTION_WITH_FOOTER_KEY != nil:
+		if chunk.MetaData == nil {
+			return nil, nil, errors.New("missing meta data for column encrypted with footer key")
+		}
+		if fd.footerCipher == nil {
+			return chunk.MetaData.PathInSchema, nil, fmt.Errorf("footer key: %w", ErrKeyNotAvailable)
+		}
+		fd.columnCiphers[ColumnPath(chunk.MetaData.PathInSchema).flatName()] = fd.footerCipher
+		return chunk.MetaData.PathInSchema, fd.footerCipher, nil
+	case cm.ENCRYPTION_WITH_COLUMN_KEY != nil:
+		path := cm.ENCRYPTION_WITH_COLUMN_KEY.PathInSchema
+		name := ColumnPath(path).flatName()
+		if err, ok := fd.inaccessible[name]; ok {
+			return path, nil, err
+		}
+		if c, ok := fd.columnCiphers[name]; ok {
+			return path, c, nil
+		}
+		c, err := fd.retrieveCipher(cm.ENCRYPTION_WITH_COLUMN_KEY.KeyMetadata)
+		if err != nil {
+			return path, nil, err
+		}
+		fd.columnCiphers[name] = c
+		return path, c, nil
+	default:
+		return nil, nil, errors.New("unsupported column crypto meta data")
+	}
+}
+
+func (fd *fileDecryptor) decryptChunkMetaData(ctx context.Context, c *moduleCipher, chunk *parquet.ColumnChunk, rowGroup, column int) error {
+	data, err := c.decryptGCM(chunk.EncryptedColumnMetadata, moduleAAD(fd.fileAAD, moduleColumnMetaData, int16(rowGroup), int16(column)))
+	if err != nil {
+		return fmt.Errorf("decrypting column meta data failed: %w", err)
+	}
+
+	meta := &parquet.ColumnMetaData{}
+	if err := readThrift(ctx, meta, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("reading column meta data failed: %w", err)
+	}
+
+	chunk.MetaData = meta
+	return nil
+}
+
+// columnChunkDecryptor returns the decryptor of a column chunk. If the column chunk is not encrypted,
+// nil is returned. If the column chunk is encrypted but inaccessible, an error is returned.
+func (fd *fileDecryptor) columnChunkDecryptor(chunk *parquet.ColumnChunk, rowGroup, column int) (*columnChunkDecryptor, error) {
+	if chunk.CryptoMetadata == nil {
+		return nil, nil
+	}
+
+	if fd == nil {
+		return nil, errors.New("column chunk is encrypted, but the file can't be decrypted")
+	}
+
+	fd.mtx.Lock()
+	path, c, err := fd.columnCipher(chunk)
+	fd.mtx.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("column %s is inaccessible: %w", ColumnPath(path).flatName(), err)
+	}
+
+	return &columnChunkDecryptor{
+		file:     fd,
+		cipher:   c,
+		rowGroup: int16(rowGroup),
+		column:   int16(column),
+	}, nil
+}
+
+func (fd *fileDecryptor) inaccessibleColumn(path ColumnPath) bool {
+	if fd == nil {
+		return false
+	}
+
+	fd.mtx.Lock()
+	defer fd.mtx.Unlock()
+
+	_, ok := fd.inaccessible[path.flatName()]
+	return ok
+}
+
+// columnChunkDecryptor decrypts the modules of a single column chunk. All methods can be called on
+// a nil columnChunkDecryptor, in which case the modules are read unencrypted.
+type columnChunkDecryptor struct {
+	file     *fileDecryptor
+	cipher   *moduleCipher
+	rowGroup int16
+	column   int16
+}
+
+// readModule reads and decrypts a module of the provided type.
+func (d *columnChunkDecryptor) readModule(moduleType byte, r io.Reader, alloc *allocTracker) ([]byte, error) {
+	module, err := readModule(r, alloc)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.cipher.decryptGCM(module, moduleAAD(d.file.fileAAD, moduleType, d.rowGroup, d.column))
+}
+
+// readThrift reads a thrift structure from a module of the provided type.
+func (d *columnChunkDecryptor) readThrift(ctx context.Context, moduleType byte, tr thriftReader, r io.Reader, alloc *allocTracker) error {
+	if d == nil {
+		return readThrift(ctx, tr, r)
+	}
+
+	data, err := d.readModule(moduleType, r, alloc)
+	if err != nil {
+		return err
+	}
+
+	return readThrift(ctx, tr, bytes.NewReader(data))
+}
+
+// pageDecryptor returns the decryptor for either the dictionary page or the data page with the provided ordinal.
+func (d *columnChunkDecryptor) pageDecryptor(dictionary bool, ordinal int) (*pageDecryptor, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	if dictionary {
+		return &pageDecryptor{
+			chunk:      d,
+			pageType:   moduleDictionaryPage,
+			headerType: moduleDictionaryPageHeader,
+			ordinals:   []int16{d.rowGroup, d.column},
+		}, nil
+	}
+
+	if ordinal > encryptionModuleLimit {
+		return nil, fmt.Errorf("invalid page ordinal %d", ordinal)
+	}
+
+	return &pageDecryptor{
+		chunk:      d,
+		pageType:   moduleDataPage,
+		headerType: moduleDataPageHeader,
+		ordinals:   []int16{d.rowGroup, d.column, int16(ordinal)},
+	}, nil
+}
+
+// pageDecryptor decrypts a single page and its header. All methods can be called on a nil
+// pageDecryptor, in which case the page is read unencrypted.
+type pageDecryptor struct {
+	chunk      *columnChunkDecryptor
+	pageType   byte
+	headerType byte
+	ordinals   []int16
+}
+
+func (d *pageDecryptor) readHeader(ctx context.Context, ph *parquet.PageHeader, r io.Reader, alloc *allocTracker) error {
+	if d == nil {
+		return readThrift(ctx, ph, r)
+	}
+
+	module, err := readModule(r, alloc)
+	if err != nil {
+		return err
+	}
+
+	data, err := d.chunk.cipher.decryptGCM(module, moduleAAD(d.chunk.file.fileAAD, d.headerType, d.ordinals...))
+	if err != nil {
+		return fmt.Errorf("decrypting page header failed: %w", err)
+	}
+
+	return readThrift(ctx, ph, bytes.NewReader(data))
+}
+
+// decryptPage reads and decrypts the page. It returns a copy of the page header where the compressed
+// page size is the size of the decrypted page, and a reader for the decrypted page. As the checksum
+// of an encrypted page is calculated from the encrypted page, it is validated here if requested.
+func (d *pageDecryptor) decryptPage(r io.Reader, ph *parquet.PageHeader, validateCRC bool, alloc *allocTracker) (*parquet.PageHeader, io.Reader, error) {
+	if d == nil {
+		return ph, r, nil
+	}
+
+	module, err := readPageBlock(r, parquet.CompressionCodec_UNCOMPRESSED, ph.CompressedPageSize, ph.UncompressedPageSize, validateCRC, ph.Crc, alloc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data []byte
+	if d.chunk.file.algorithm == EncryptionAlgorithmAESGCMCTR {
+		data, err = d.chunk.cipher.decryptCTR(module)
+	} else {
+		data, err = d.chunk.cipher.decryptGCM(module, moduleAAD(d.chunk.file.fileAAD, d.pageType, d.ordinals...))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("decrypting page failed: %w", err)
+	}
+
+	decrypted := *ph
+	decrypted.CompressedPageSize = int32(len(data))
+	decrypted.Crc = nil
+
+	return &decrypted, bytes.NewReader(data), nil
+}
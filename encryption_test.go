@@ -0,0 +1,582 @@
+package goparquet
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFooterKey = []byte("0123456789012345")
+	testColumnKey = []byte("1234567890123450")
+)
+
+func testDecryptGCM(t *testing.T, key, aad, module []byte) []byte {
+	require.True(t, len(module) >= encryptionLengthSize+encryptionNonceSize+encryptionTagSize)
+	require.Equal(t, uint32(len(module)-encryptionLengthSize), binary.LittleEndian.Uint32(module))
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	nonce := module[encryptionLengthSize : encryptionLengthSize+encryptionNonceSize]
+	data, err := gcm.Open(nil, nonce, module[encryptionLengthSize+encryptionNonceSize:], aad)
+	require.NoError(t, err)
+	return data
+}
+
+func testDecryptCTR(t *testing.T, key, module []byte) []byte {
+	require.Equal(t, uint32(len(module)-encryptionLengthSize), binary.LittleEndian.Uint32(module))
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, module[encryptionLengthSize:encryptionLengthSize+encryptionNonceSize])
+	iv[aes.BlockSize-1] = 1
+
+	data := make([]byte, len(module)-encryptionLengthSize-encryptionNonceSize)
+	cipher.NewCTR(block, iv).XORKeyStream(data, module[encryptionLengthSize+encryptionNonceSize:])
+	return data
+}
+
+// testFooter returns the footer of a parquet file, without the footer length and the magic bytes.
+func testFooter(t *testing.T, data []byte, expectedMagic []byte) []byte {
+	require.Equal(t, expectedMagic, data[:4])
+	require.Equal(t, expectedMagic, data[len(data)-4:])
+	ln := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	return data[len(data)-8-ln : len(data)-8]
+}
+
+func TestModuleAAD(t *testing.T) {
+	fileAAD := []byte("prefixunique")
+	require.Equal(t, append([]byte("prefixunique"), moduleFooter), moduleAAD(fileAAD, moduleFooter))
+	require.Equal(t, append([]byte("prefixunique"), moduleDataPage, 1, 0, 2, 1, 0xff, 0x7f), moduleAAD(fileAAD, moduleDataPage, 1, 258, 32767))
+}
+
+func TestModuleCipher(t *testing.T) {
+	_, err := newModuleCipher([]byte("too short"))
+	require.Error(t, err)
+
+	c, err := newModuleCipher(testFooterKey)
+	require.NoError(t, err)
+
+	data := []byte("hello world, this is a test of the module encryption")
+	aad := []byte("aad")
+
+	module, err := c.encryptGCM(data, aad)
+	require.NoError(t, err)
+	require.Len(t, module, encryptionLengthSize+encryptionNonceSize+len(data)+encryptionTagSize)
+	require.Equal(t, data, testDecryptGCM(t, testFooterKey, aad, module))
+
+	module, err = c.encryptCTR(data)
+	require.NoError(t, err)
+	require.Len(t, module, encryptionLengthSize+encryptionNonceSize+len(data))
+	require.Equal(t, data, testDecryptCTR(t, testFooterKey, module))
+}
+
+func writeEncryptedTestFile(t *testing.T, opts ...FileWriterOption) []byte {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		required binary ssn (STRING);
+		optional binary name (STRING);
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, append([]FileWriterOption{WithSchemaDefinition(sd)}, opts...)...)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, fw.AddData(map[string]interface{}{
+			"id":   int64(i),
+			"ssn":  []byte("123-45-6789"),
+			"name": []byte("john doe"),
+		}))
+	}
+	require.NoError(t, fw.Close())
+
+	return buf.Bytes()
+}
+
+func TestWriteEncryptedFooter(t *testing.T) {
+	data := writeEncryptedTestFile(t,
+		WithFooterKey(testFooterKey, []byte("footer key")),
+		WithColumnKey(ColumnPath{"ssn"}, testColumnKey, []byte("column key")),
+		WithColumnKey(ColumnPath{"name"}, testFooterKey, nil),
+		WithAADPrefix([]byte("prefix"), true),
+		WithPageIndex(true),
+		WithBloomFilter(ColumnPath{"ssn"}, 0, 0.01),
+	)
+
+	footer := bytes.NewReader(testFooter(t, data, magicEncrypted))
+
+	cryptoMeta := &parquet.FileCryptoMetaData{}
+	require.NoError(t, readThrift(context.Background(), cryptoMeta, footer))
+	require.Equal(t, []byte("footer key"), cryptoMeta.KeyMetadata)
+	require.NotNil(t, cryptoMeta.EncryptionAlgorithm.AES_GCM_V1)
+	require.Equal(t, []byte("prefix"), cryptoMeta.EncryptionAlgorithm.AES_GCM_V1.AadPrefix)
+	require.Nil(t, cryptoMeta.EncryptionAlgorithm.AES_GCM_V1.SupplyAadPrefix)
+
+	fileAAD := append([]byte("prefix"), cryptoMeta.EncryptionAlgorithm.AES_GCM_V1.AadFileUnique...)
+	require.Len(t, fileAAD, len("prefix")+aadFileUniqueSize)
+
+	module := make([]byte, footer.Len())
+	_, err := footer.Read(module)
+	require.NoError(t, err)
+
+	meta := &parquet.FileMetaData{}
+	require.NoError(t, readThrift(context.Background(), meta, bytes.NewReader(testDecryptGCM(t, testFooterKey, moduleAAD(fileAAD, moduleFooter), module))))
+	require.Equal(t, int64(100), meta.NumRows)
+	require.Nil(t, meta.EncryptionAlgorithm)
+	require.Equal(t, int16(0), *meta.RowGroups[0].Ordinal)
+
+	chunks := meta.RowGroups[0].Columns
+	require.Len(t, chunks, 3)
+
+	// id is not encrypted.
+	require.Nil(t, chunks[0].CryptoMetadata)
+	require.NotNil(t, chunks[0].MetaData)
+	require.Nil(t, chunks[0].EncryptedColumnMetadata)
+
+	// ssn is encrypted with its own key, so the column meta data is encrypted with it as well.
+	require.NotNil(t, chunks[1].CryptoMetadata.ENCRYPTION_WITH_COLUMN_KEY)
+	require.Equal(t, []string{"ssn"}, chunks[1].CryptoMetadata.ENCRYPTION_WITH_COLUMN_KEY.PathInSchema)
+	require.Equal(t, []byte("column key"), chunks[1].CryptoMetadata.ENCRYPTION_WITH_COLUMN_KEY.KeyMetadata)
+	require.Nil(t, chunks[1].MetaData)
+
+	ssnMeta := &parquet.ColumnMetaData{}
+	require.NoError(t, readThrift(context.Background(), ssnMeta, bytes.NewReader(testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleColumnMetaData, 0, 1), chunks[1].EncryptedColumnMetadata))))
+	require.Equal(t, []string{"ssn"}, ssnMeta.PathInSchema)
+	require.NotNil(t, ssnMeta.Statistics)
+	require.NotNil(t, ssnMeta.BloomFilterOffset)
+
+	// the first page of ssn is the dictionary page.
+	r := bytes.NewReader(data[*ssnMeta.DictionaryPageOffset:])
+	var ln uint32
+	require.NoError(t, binary.Read(r, binary.LittleEndian, &ln))
+	headerModule := data[*ssnMeta.DictionaryPageOffset : *ssnMeta.DictionaryPageOffset+int64(ln)+encryptionLengthSize]
+
+	ph := &parquet.PageHeader{}
+	require.NoError(t, readThrift(context.Background(), ph, bytes.NewReader(testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleDictionaryPageHeader, 0, 1), headerModule))))
+	require.Equal(t, parquet.PageType_DICTIONARY_PAGE, ph.Type)
+
+	pageStart := *ssnMeta.DictionaryPageOffset + int64(len(headerModule))
+	pageModule := data[pageStart : pageStart+int64(ph.CompressedPageSize)]
+	page := testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleDictionaryPage, 0, 1), pageModule)
+	require.Equal(t, int(ph.UncompressedPageSize), len(page))
+	require.True(t, bytes.Contains(page, []byte("123-45-6789")))
+	require.False(t, bytes.Contains(data, []byte("123-45-6789")))
+
+	// the first data page follows the dictionary page.
+	require.Equal(t, pageStart+int64(len(pageModule)), ssnMeta.DataPageOffset)
+	require.NoError(t, binary.Read(bytes.NewReader(data[ssnMeta.DataPageOffset:]), binary.LittleEndian, &ln))
+	headerModule = data[ssnMeta.DataPageOffset : ssnMeta.DataPageOffset+int64(ln)+encryptionLengthSize]
+	ph = &parquet.PageHeader{}
+	require.NoError(t, readThrift(context.Background(), ph, bytes.NewReader(testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleDataPageHeader, 0, 1, 0), headerModule))))
+	require.Equal(t, parquet.PageType_DATA_PAGE, ph.Type)
+
+	// the page index of ssn is encrypted.
+	require.NotNil(t, chunks[1].ColumnIndexOffset)
+	columnIndex := &parquet.ColumnIndex{}
+	indexModule := data[*chunks[1].ColumnIndexOffset : *chunks[1].ColumnIndexOffset+int64(*chunks[1].ColumnIndexLength)]
+	require.NoError(t, readThrift(context.Background(), columnIndex, bytes.NewReader(testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleColumnIndex, 0, 1), indexModule))))
+	require.Equal(t, []byte("123-45-6789"), columnIndex.MinValues[0])
+
+	offsetIndex := &parquet.OffsetIndex{}
+	indexModule = data[*chunks[1].OffsetIndexOffset : *chunks[1].OffsetIndexOffset+int64(*chunks[1].OffsetIndexLength)]
+	require.NoError(t, readThrift(context.Background(), offsetIndex, bytes.NewReader(testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleOffsetIndex, 0, 1), indexModule))))
+	require.Equal(t, ssnMeta.DataPageOffset, offsetIndex.PageLocations[0].Offset)
+
+	// name is encrypted with a column key, even though it is the same as the footer key.
+	require.NotNil(t, chunks[2].CryptoMetadata.ENCRYPTION_WITH_COLUMN_KEY)
+	require.Nil(t, chunks[2].MetaData)
+	require.NotNil(t, chunks[2].EncryptedColumnMetadata)
+}
+
+func TestWriteEncryptedFooterUniform(t *testing.T) {
+	data := writeEncryptedTestFile(t,
+		WithFooterKey(testFooterKey, nil),
+		WithEncryptionAlgorithm(EncryptionAlgorithmAESGCMCTR),
+	)
+
+	footer := bytes.NewReader(testFooter(t, data, magicEncrypted))
+
+	cryptoMeta := &parquet.FileCryptoMetaData{}
+	require.NoError(t, readThrift(context.Background(), cryptoMeta, footer))
+	require.NotNil(t, cryptoMeta.EncryptionAlgorithm.AES_GCM_CTR_V1)
+	require.Nil(t, cryptoMeta.KeyMetadata)
+	fileAAD := cryptoMeta.EncryptionAlgorithm.AES_GCM_CTR_V1.AadFileUnique
+
+	module := make([]byte, footer.Len())
+	_, err := footer.Read(module)
+	require.NoError(t, err)
+
+	meta := &parquet.FileMetaData{}
+	require.NoError(t, readThrift(context.Background(), meta, bytes.NewReader(testDecryptGCM(t, testFooterKey, moduleAAD(fileAAD, moduleFooter), module))))
+
+	for i, chunk := range meta.RowGroups[0].Columns {
+		require.NotNil(t, chunk.CryptoMetadata.ENCRYPTION_WITH_FOOTER_KEY)
+		require.NotNil(t, chunk.MetaData)
+		require.Nil(t, chunk.EncryptedColumnMetadata)
+
+		offset := chunk.MetaData.DataPageOffset
+		if chunk.MetaData.DictionaryPageOffset != nil {
+			offset = *chunk.MetaData.DictionaryPageOffset
+		}
+
+		var ln uint32
+		require.NoError(t, binary.Read(bytes.NewReader(data[offset:]), binary.LittleEndian, &ln))
+		headerModule := data[offset : offset+int64(ln)+encryptionLengthSize]
+
+		headerType := moduleDataPageHeader
+		ordinals := []int16{0, int16(i), 0}
+		if chunk.MetaData.DictionaryPageOffset != nil {
+			headerType = moduleDictionaryPageHeader
+			ordinals = ordinals[:2]
+		}
+
+		ph := &parquet.PageHeader{}
+		require.NoError(t, readThrift(context.Background(), ph, bytes.NewReader(testDecryptGCM(t, testFooterKey, moduleAAD(fileAAD, headerType, ordinals...), headerModule))))
+
+		pageStart := offset + int64(len(headerModule))
+		page := testDecryptCTR(t, testFooterKey, data[pageStart:pageStart+int64(ph.CompressedPageSize)])
+		require.Equal(t, int(ph.UncompressedPageSize), len(page))
+	}
+}
+
+func TestWritePlaintextFooter(t *testing.T) {
+	data := writeEncryptedTestFile(t,
+		WithFooterKey(testFooterKey, []byte("footer key")),
+		WithColumnKey(ColumnPath{"ssn"}, testColumnKey, []byte("column key")),
+		WithPlaintextFooter(true),
+		WithAADPrefix([]byte("prefix"), false),
+	)
+
+	footer := testFooter(t, data, magic)
+
+	meta := &parquet.FileMetaData{}
+	require.NoError(t, readThrift(context.Background(), meta, bytes.NewReader(footer)))
+	require.Equal(t, []byte("footer key"), meta.FooterSigningKeyMetadata)
+	require.NotNil(t, meta.EncryptionAlgorithm.AES_GCM_V1)
+	require.Nil(t, meta.EncryptionAlgorithm.AES_GCM_V1.AadPrefix)
+	require.True(t, *meta.EncryptionAlgorithm.AES_GCM_V1.SupplyAadPrefix)
+
+	fileAAD := append([]byte("prefix"), meta.EncryptionAlgorithm.AES_GCM_V1.AadFileUnique...)
+
+	// the footer is followed by the nonce and the tag of the encrypted footer.
+	signature := footer[len(footer)-encryptionNonceSize-encryptionTagSize:]
+	plainFooter := footer[:len(footer)-len(signature)]
+
+	block, err := aes.NewCipher(testFooterKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	sealed := gcm.Seal(nil, signature[:encryptionNonceSize], plainFooter, moduleAAD(fileAAD, moduleFooter))
+	require.Equal(t, signature[encryptionNonceSize:], sealed[len(plainFooter):])
+
+	chunks := meta.RowGroups[0].Columns
+	require.Nil(t, chunks[0].CryptoMetadata)
+	require.NotNil(t, chunks[0].MetaData.Statistics)
+
+	require.NotNil(t, chunks[1].CryptoMetadata.ENCRYPTION_WITH_COLUMN_KEY)
+	require.NotNil(t, chunks[1].MetaData)
+	require.Nil(t, chunks[1].MetaData.Statistics)
+
+	ssnMeta := &parquet.ColumnMetaData{}
+	require.NoError(t, readThrift(context.Background(), ssnMeta, bytes.NewReader(testDecryptGCM(t, testColumnKey, moduleAAD(fileAAD, moduleColumnMetaData, 0, 1), chunks[1].EncryptedColumnMetadata))))
+	require.NotNil(t, ssnMeta.Statistics)
+
+	require.Nil(t, chunks[2].CryptoMetadata)
+
+	// readers that are unaware of encryption can still read the meta data.
+	_, err = ReadFileMetaData(bytes.NewReader(data), true)
+	require.NoError(t, err)
+}
+
+func TestWriteEncryptionErrors(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+	}`)
+	require.NoError(t, err)
+
+	for _, opts := range [][]FileWriterOption{
+		{WithFooterKey([]byte("invalid"), nil)},
+		{WithColumnKey(ColumnPath{"id"}, testColumnKey, nil)},
+		{WithFooterKey(testFooterKey, nil), WithColumnKey(ColumnPath{"foo"}, testColumnKey, nil)},
+		{WithFooterKey(testFooterKey, nil), WithColumnKey(ColumnPath{"id"}, []byte("invalid"), nil)},
+	} {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, append([]FileWriterOption{WithSchemaDefinition(sd)}, opts...)...)
+		require.NoError(t, fw.AddData(map[string]interface{}{"id": int64(1)}))
+		require.Error(t, fw.Close())
+	}
+}
+
+var testKeyRetriever = KeyRetrieverFunc(func(keyMetadata []byte) ([]byte, error) {
+	switch string(keyMetadata) {
+	case "footer key":
+		return testFooterKey, nil
+	case "column key":
+		return testColumnKey, nil
+	default:
+		return nil, errors.New("unknown key")
+	}
+})
+
+func writeEncryptedRoundTripFile(t *testing.T, opts ...FileWriterOption) []byte {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		required binary ssn (STRING);
+		optional binary name (STRING);
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, append([]FileWriterOption{
+		WithSchemaDefinition(sd),
+		WithMaxPageSize(512),
+		WithPageIndex(true),
+		WithBloomFilter(ColumnPath{"ssn"}, 0, 0.01),
+		WithColumnKey(ColumnPath{"ssn"}, testColumnKey, []byte("column key")),
+	}, opts...)...)
+	for i := 0; i < 1000; i++ {
+		data := map[string]interface{}{
+			"id":  int64(i),
+			"ssn": []byte(fmt.Sprintf("ssn %d", i)),
+		}
+		if i%3 == 0 {
+			data["name"] = []byte(fmt.Sprintf("name %d", i%10))
+		}
+		require.NoError(t, fw.AddData(data))
+		if i == 499 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	return buf.Bytes()
+}
+
+func TestReadEncryptedFile(t *testing.T) {
+	tests := map[string][]FileWriterOption{
+		"encrypted footer": {
+			WithFooterKey(testFooterKey, []byte("footer key")),
+		},
+		"encrypted footer with AES-GCM-CTR": {
+			WithFooterKey(testFooterKey, []byte("footer key")),
+			WithEncryptionAlgorithm(EncryptionAlgorithmAESGCMCTR),
+		},
+		"plaintext footer": {
+			WithFooterKey(testFooterKey, []byte("footer key")),
+			WithPlaintextFooter(true),
+		},
+		"data page v2 with CRC": {
+			WithFooterKey(testFooterKey, []byte("footer key")),
+			WithDataPageV2(),
+			WithCRC(true),
+		},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := writeEncryptedRoundTripFile(t, opts...)
+
+			r, err := NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(testKeyRetriever), WithCRC32Validation(true))
+			require.NoError(t, err)
+			require.Empty(t, r.InaccessibleColumns())
+			require.True(t, r.FooterVerified())
+			require.Equal(t, int64(1000), r.NumRows())
+
+			for i := 0; i < 1000; i++ {
+				row, err := r.NextRow()
+				require.NoError(t, err)
+				require.Equal(t, int64(i), row["id"])
+				require.Equal(t, []byte(fmt.Sprintf("ssn %d", i)), row["ssn"])
+				if i%3 == 0 {
+					require.Equal(t, []byte(fmt.Sprintf("name %d", i%10)), row["name"])
+				} else {
+					require.NotContains(t, row, "name")
+				}
+			}
+			_, err = r.NextRow()
+			require.Equal(t, io.EOF, err)
+
+			offsetIndex, err := r.OffsetIndex(1, ColumnPath{"ssn"})
+			require.NoError(t, err)
+			require.True(t, len(offsetIndex.PageLocations) > 1)
+
+			columnIndex, err := r.ColumnIndex(1, ColumnPath{"id"})
+			require.NoError(t, err)
+			require.NotNil(t, columnIndex)
+
+			bloomFilter, err := r.BloomFilter(1, ColumnPath{"ssn"})
+			require.NoError(t, err)
+			require.True(t, bloomFilter.MightContain("ssn 750"))
+
+			require.NoError(t, r.SeekToRow(750))
+			row, err := r.NextRow()
+			require.NoError(t, err)
+			require.Equal(t, int64(750), row["id"])
+			require.Equal(t, []byte("ssn 750"), row["ssn"])
+		})
+	}
+}
+
+func TestReadEncryptedFileInaccessibleColumns(t *testing.T) {
+	footerKeyOnly := KeyRetrieverFunc(func(keyMetadata []byte) ([]byte, error) {
+		if string(keyMetadata) == "footer key" {
+			return testFooterKey, nil
+		}
+		return nil, fmt.Errorf("access denied: %w", ErrKeyNotAvailable)
+	})
+
+	data := writeEncryptedRoundTripFile(t, WithFooterKey(testFooterKey, []byte("footer key")))
+
+	// only columns whose keys aren't available are inaccessible, all other errors retrieving
+	// column keys are reported.
+	_, err := NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(KeyRetrieverFunc(func(keyMetadata []byte) ([]byte, error) {
+		if string(keyMetadata) == "footer key" {
+			return testFooterKey, nil
+		}
+		return nil, errors.New("temporary failure")
+	})))
+	require.Error(t, err)
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(footerKeyOnly))
+	require.NoError(t, err)
+	require.Equal(t, []ColumnPath{{"ssn"}}, r.InaccessibleColumns())
+
+	_, err = r.NextRow()
+	require.Error(t, err)
+
+	_, err = r.BloomFilter(0, ColumnPath{"ssn"})
+	require.Error(t, err)
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(footerKeyOnly), WithColumnPaths(ColumnPath{"id"}, ColumnPath{"name"}))
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		row, err := r.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, int64(i), row["id"])
+		require.NotContains(t, row, "ssn")
+	}
+
+	// with a plaintext footer, the file can be read without the footer key. Only the columns that
+	// aren't encrypted are accessible then.
+	data = writeEncryptedRoundTripFile(t,
+		WithFooterKey(testFooterKey, []byte("footer key")),
+		WithColumnKey(ColumnPath{"name"}, testFooterKey, []byte("footer key")),
+		WithPlaintextFooter(true),
+	)
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data), WithColumnPaths(ColumnPath{"id"}))
+	require.NoError(t, err)
+	require.Equal(t, []ColumnPath{{"ssn"}, {"name"}}, r.InaccessibleColumns())
+
+	require.False(t, r.FooterVerified())
+
+	row, err := r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": int64(0)}, row)
+
+	notAvailable := KeyRetrieverFunc(func([]byte) ([]byte, error) {
+		return nil, fmt.Errorf("no access: %w", ErrKeyNotAvailable)
+	})
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(notAvailable), WithColumnPaths(ColumnPath{"id"}))
+	require.NoError(t, err)
+	require.False(t, r.FooterVerified())
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(testKeyRetriever))
+	require.NoError(t, err)
+	require.True(t, r.FooterVerified())
+
+	// all other errors retrieving the footer key are reported, as the footer can't be verified.
+	for _, kr := range []KeyRetriever{
+		KeyRetrieverFunc(func([]byte) ([]byte, error) {
+			return nil, errors.New("temporary failure")
+		}),
+		KeyRetrieverFunc(func([]byte) ([]byte, error) {
+			return []byte("wrong size"), nil
+		}),
+	} {
+		_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(kr), WithColumnPaths(ColumnPath{"id"}))
+		require.Error(t, err)
+	}
+
+	// column meta data that can't be decrypted is reported, even if the column isn't read.
+	meta, err := ReadFileMetaData(bytes.NewReader(data), true)
+	require.NoError(t, err)
+	encrypted := meta.RowGroups[0].Columns[1].EncryptedColumnMetadata
+	require.NotEmpty(t, encrypted)
+
+	pos := bytes.Index(data, encrypted)
+	require.True(t, pos >= 0)
+	tampered := append([]byte(nil), data...)
+	tampered[pos+len(encrypted)-1] ^= 1
+
+	columnKeyOnly := KeyRetrieverFunc(func(keyMetadata []byte) ([]byte, error) {
+		if string(keyMetadata) == "column key" {
+			return testColumnKey, nil
+		}
+		return nil, ErrKeyNotAvailable
+	})
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(columnKeyOnly), WithColumnPaths(ColumnPath{"id"}))
+	require.NoError(t, err)
+	require.Equal(t, []ColumnPath{{"name"}}, r.InaccessibleColumns())
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(tampered), WithKeyRetriever(columnKeyOnly), WithColumnPaths(ColumnPath{"id"}))
+	require.Error(t, err)
+}
+
+func TestReadEncryptedFileErrors(t *testing.T) {
+	data := writeEncryptedRoundTripFile(t, WithFooterKey(testFooterKey, []byte("footer key")))
+
+	_, err := NewFileReader(bytes.NewReader(data))
+	require.Error(t, err)
+
+	_, err = ReadFileMetaData(bytes.NewReader(data), true)
+	require.Error(t, err)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(KeyRetrieverFunc(func([]byte) ([]byte, error) {
+		return testColumnKey, nil
+	})))
+	require.Error(t, err)
+
+	data = writeEncryptedRoundTripFile(t,
+		WithFooterKey(testFooterKey, []byte("footer key")),
+		WithAADPrefix([]byte("prefix"), false),
+	)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(testKeyRetriever))
+	require.Error(t, err)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(testKeyRetriever), WithDecryptionAADPrefix([]byte("wrong")))
+	require.Error(t, err)
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(testKeyRetriever), WithDecryptionAADPrefix([]byte("prefix")))
+	require.NoError(t, err)
+	row, err := r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), row["id"])
+
+	// a plaintext footer that doesn't match its signature must be detected.
+	data = writeEncryptedRoundTripFile(t,
+		WithFooterKey(testFooterKey, []byte("footer key")),
+		WithPlaintextFooter(true),
+	)
+	data[len(data)-9] ^= 0xff
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithKeyRetriever(testKeyRetriever))
+	require.EqualError(t, err, "reading file meta data failed: footer signature verification failed")
+}
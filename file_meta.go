@@ -20,55 +20,158 @@ func ReadFileMetaData(r io.ReadSeeker, extraValidation bool) (*parquet.FileMetaD
 }
 
 // ReadFileMetaDataWithContext reads and returns the meta data of a parquet file. You can use this function
-// to read and inspect the meta data before starting to read the whole parquet file.
+// to read and inspect the meta data before starting to read the whole parquet file. Files with an encrypted
+// footer can't be read with this function, use NewFileReaderWithOptions with a KeyRetriever instead.
 func ReadFileMetaDataWithContext(ctx context.Context, r io.ReadSeeker, extraValidation bool) (*parquet.FileMetaData, error) {
+	meta, _, err := readFileMetaData(ctx, r, extraValidation, nil)
+	return meta, err
+}
+
+// readFileMetaData reads the meta data of a parquet file. If decryption options are provided and the
+// file is encrypted, the meta data is decrypted and the returned fileDecryptor can be used to decrypt
+// the rest of the file. Without decryption options, files with an encrypted footer can't be read.
+func readFileMetaData(ctx context.Context, r io.ReadSeeker, extraValidation bool, opts *decryptionOptions) (*parquet.FileMetaData, *fileDecryptor, error) {
+	buf := make([]byte, 4)
+
+	var headerMagic []byte
 	if extraValidation {
 		if _, err := r.Seek(0, io.SeekStart); err != nil {
-			return nil, fmt.Errorf("seek for the file magic header failed: %w", err)
+			return nil, nil, fmt.Errorf("seek for the file magic header failed: %w", err)
 		}
 
-		buf := make([]byte, 4)
 		// read and validate header
 		if _, err := io.ReadFull(r, buf); err != nil {
-			return nil, fmt.Errorf("read the file magic header failed: %w", err)
+			return nil, nil, fmt.Errorf("read the file magic header failed: %w", err)
 		}
-		if !bytes.Equal(buf, magic) {
-			return nil, errors.New("invalid parquet file header")
-		}
-
-		// read and validate footer
-		if _, err := r.Seek(-4, io.SeekEnd); err != nil {
-			return nil, fmt.Errorf("seek for the file magic footer failed: %w", err)
+		if !bytes.Equal(buf, magic) && !bytes.Equal(buf, magicEncrypted) {
+			return nil, nil, errors.New("invalid parquet file header")
 		}
+		headerMagic = append([]byte{}, buf...)
+	}
 
-		if _, err := io.ReadFull(r, buf); err != nil {
-			return nil, fmt.Errorf("read the file magic header failed: %w", err)
-		}
-		if !bytes.Equal(buf, magic) {
-			return nil, errors.New("invalid parquet file footer")
-		}
+	// read footer magic
+	if _, err := r.Seek(-4, io.SeekEnd); err != nil {
+		return nil, nil, fmt.Errorf("seek for the file magic footer failed: %w", err)
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, nil, fmt.Errorf("read the file magic footer failed: %w", err)
+	}
+	encryptedFooter := bytes.Equal(buf, magicEncrypted)
+	if extraValidation && !bytes.Equal(buf, headerMagic) {
+		return nil, nil, errors.New("invalid parquet file footer")
 	}
 
 	// read footer length
 	if _, err := r.Seek(-8, io.SeekEnd); err != nil {
-		return nil, fmt.Errorf("seek for the footer len failed: %w", err)
+		return nil, nil, fmt.Errorf("seek for the footer len failed: %w", err)
 	}
 	var fl int32
 	if err := binary.Read(r, binary.LittleEndian, &fl); err != nil {
-		return nil, fmt.Errorf("read the footer len failed: %w", err)
+		return nil, nil, fmt.Errorf("read the footer len failed: %w", err)
 	}
 	if fl <= 0 {
-		return nil, fmt.Errorf("invalid footer len %d", fl)
+		return nil, nil, fmt.Errorf("invalid footer len %d", fl)
 	}
 
 	// read file metadata
 	if _, err := r.Seek(-8-int64(fl), io.SeekEnd); err != nil {
-		return nil, fmt.Errorf("seek file meta data failed: %w", err)
+		return nil, nil, fmt.Errorf("seek file meta data failed: %w", err)
 	}
+
+	if encryptedFooter {
+		if opts == nil {
+			return nil, nil, errors.New("file has an encrypted footer")
+		}
+		return readEncryptedFileMetaData(ctx, io.LimitReader(r, int64(fl)), opts)
+	}
+
+	if opts == nil {
+		meta := &parquet.FileMetaData{}
+		if err := readThrift(ctx, meta, io.LimitReader(r, int64(fl))); err != nil {
+			return nil, nil, fmt.Errorf("read file meta failed: %w", err)
+		}
+		return meta, nil, nil
+	}
+
+	footer := make([]byte, fl)
+	if _, err := io.ReadFull(r, footer); err != nil {
+		return nil, nil, fmt.Errorf("read file meta failed: %w", err)
+	}
+
+	footerReader := bytes.NewReader(footer)
 	meta := &parquet.FileMetaData{}
-	if err := readThrift(ctx, meta, io.LimitReader(r, int64(fl))); err != nil {
-		return nil, fmt.Errorf("read file meta failed: %w", err)
+	if err := readThrift(ctx, meta, footerReader); err != nil {
+		return nil, nil, fmt.Errorf("read file meta failed: %w", err)
+	}
+
+	if meta.EncryptionAlgorithm == nil {
+		return meta, nil, nil
+	}
+
+	// the plaintext footer of an encrypted file is followed by its signature.
+	fd, err := newPlaintextFooterDecryptor(ctx, meta, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature := footer[len(footer)-footerReader.Len():]
+	if err := fd.verifyFooter(footer[:len(footer)-len(signature)], signature); err != nil {
+		return nil, nil, err
+	}
+
+	return meta, fd, nil
+}
+
+// readEncryptedFileMetaData reads and decrypts an encrypted footer, which consists of the file crypto
+// meta data followed by the encrypted file meta data.
+func readEncryptedFileMetaData(ctx context.Context, r io.Reader, opts *decryptionOptions) (*parquet.FileMetaData, *fileDecryptor, error) {
+	cryptoMeta := &parquet.FileCryptoMetaData{}
+	if err := readThrift(ctx, cryptoMeta, r); err != nil {
+		return nil, nil, fmt.Errorf("read file crypto meta data failed: %w", err)
+	}
+
+	fd, err := newFileDecryptor(cryptoMeta.EncryptionAlgorithm, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := fd.setFooterKey(cryptoMeta.KeyMetadata); err != nil {
+		return nil, nil, err
+	}
+
+	module, err := readModule(r, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read file meta failed: %w", err)
+	}
+
+	meta, err := fd.decryptFooter(ctx, module)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := fd.decryptColumnMetaData(ctx, meta); err != nil {
+		return nil, nil, err
+	}
+
+	return meta, fd, nil
+}
+
+// newPlaintextFooterDecryptor creates the decryptor for a file with a plaintext footer. The footer key
+// is only required to verify the footer and to decrypt columns that are encrypted with the footer key,
+// so the file can still be read if it's not available. All other errors retrieving the key are returned.
+func newPlaintextFooterDecryptor(ctx context.Context, meta *parquet.FileMetaData, opts *decryptionOptions) (*fileDecryptor, error) {
+	fd, err := newFileDecryptor(meta.EncryptionAlgorithm, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fd.setFooterKey(meta.FooterSigningKeyMetadata); err != nil && !errors.Is(err, ErrKeyNotAvailable) {
+		return nil, err
+	}
+
+	if err := fd.decryptColumnMetaData(ctx, meta); err != nil {
+		return nil, err
 	}
 
-	return meta, nil
+	return fd, nil
 }
@@ -2,6 +2,7 @@ package goparquet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"runtime"
@@ -12,10 +13,15 @@ import (
 
 // FileReader is used to read data from a parquet file. Always use NewFileReader or a related
 // function  to create such an object.
+//
+// All data is read from the file using positional reads, so multiple FileReaders can read from the same
+// io.ReaderAt concurrently, see NewFileReaderAt. Methods that don't depend on the current position of the
+// FileReader, such as ColumnIndex, OffsetIndex and BloomFilter, are safe for concurrent use, but reading
+// rows using NextRow is not.
 type FileReader struct {
 	meta         *parquet.FileMetaData
 	schemaReader *schema
-	reader       io.ReadSeeker
+	readerAt     io.ReaderAt
 
 	rowGroupPosition int
 	currentRecord    int64
@@ -24,20 +30,57 @@ type FileReader struct {
 	ctx context.Context
 
 	allocTracker *allocTracker
+
+	decryptor *fileDecryptor
+
+	projection *schemaProjection
+
+	predicate        Predicate
+	predicateColumns map[string]*predicateColumn
+
+	concurrency int
+
+	// size is the size of the file, planner is nil if no read planner is configured.
+	size    int64
+	planner *readPlanner
 }
 
 // NewFileReaderWithOptions creates a new FileReader. You can provide a list of FileReaderOptions to configure
 // aspects of its behaviour, such as limiting the columns to read, the file metadata to use, or the
 // context to use. For a full list of options, please see the type FileReaderOption.
+//
+// If r implements io.ReaderAt, like *os.File and *bytes.Reader do, the data is read using positional reads.
+// Otherwise, all reads are serialized, and r must not be used by anything else while the FileReader is in use.
 func NewFileReaderWithOptions(r io.ReadSeeker, readerOptions ...FileReaderOption) (*FileReader, error) {
+	return newFileReader(r, newReaderAt(r), readerOptions)
+}
+
+// NewFileReaderAt creates a new FileReader that reads a file of the provided size using positional reads.
+// Other than when using NewFileReaderWithOptions, the same io.ReaderAt, e.g. an *os.File, can be shared by
+// multiple FileReaders that are used concurrently, for example to read different row groups or columns of
+// a file in parallel. You can provide a list of FileReaderOptions to configure aspects of its behaviour.
+func NewFileReaderAt(r io.ReaderAt, size int64, readerOptions ...FileReaderOption) (*FileReader, error) {
+	return newFileReader(io.NewSectionReader(r, 0, size), r, readerOptions)
+}
+
+// newFileReader creates a new FileReader that reads the file meta data from r, and everything else from ra.
+func newFileReader(r io.ReadSeeker, ra io.ReaderAt, readerOptions []FileReaderOption) (*FileReader, error) {
 	opts := newFileReaderOptions()
 	if err := opts.apply(readerOptions); err != nil {
 		return nil, err
 	}
 
-	var err error
+	var (
+		decryptor *fileDecryptor
+		err       error
+	)
 	if opts.metaData == nil {
-		opts.metaData, err = ReadFileMetaData(r, true)
+		opts.metaData, decryptor, err = readFileMetaData(opts.ctx, r, true, &opts.decryption)
+		if err != nil {
+			return nil, fmt.Errorf("reading file meta data failed: %w", err)
+		}
+	} else if opts.metaData.EncryptionAlgorithm != nil {
+		decryptor, err = newPlaintextFooterDecryptor(opts.ctx, opts.metaData, &opts.decryption)
 		if err != nil {
 			return nil, fmt.Errorf("reading file meta data failed: %w", err)
 		}
@@ -49,19 +92,75 @@ func NewFileReaderWithOptions(r io.ReadSeeker, readerOptions ...FileReaderOption
 	}
 
 	schema.SetSelectedColumns(opts.columns...)
-	// Reset the reader to the beginning of the file
-	if _, err := r.Seek(4, io.SeekStart); err != nil {
-		return nil, err
+
+	var projection *schemaProjection
+	if opts.targetSchemaDef != nil {
+		var cols []ColumnPath
+		projection, cols, err = newSchemaProjection(schema.GetSchemaDefinition(), opts.targetSchemaDef, opts.defaultValues)
+		if err != nil {
+			return nil, fmt.Errorf("resolving target schema failed: %w", err)
+		}
+
+		// only the columns of the target schema are read.
+		selected := make([]ColumnPath, 0, len(cols))
+		for _, c := range cols {
+			if schema.isSelectedByPath(c) {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) > 0 {
+			schema.SetSelectedColumns(selected...)
+		} else {
+			// none of the columns of the target schema are read from the file, which is different from
+			// selecting no columns, which selects all columns.
+			schema.selectNoColumns()
+		}
+	} else if len(opts.defaultValues) > 0 {
+		return nil, errors.New("default values require a target schema definition")
+	}
+
+	var predicateColumns map[string]*predicateColumn
+	if opts.predicate != nil {
+		predicateColumns, err = newPredicateColumns(schema, projection, opts.predicate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("determining the file size failed: %w", err)
+	}
+
+	var planner *readPlanner
+	if opts.coalesceReads || opts.prefetch {
+		planner = &readPlanner{
+			maxGap:   opts.maxGap,
+			prefetch: opts.prefetch,
+		}
 	}
+
 	return &FileReader{
-		meta:         opts.metaData,
-		schemaReader: schema,
-		reader:       r,
-		ctx:          opts.ctx,
-		allocTracker: opts.allocTracker,
+		meta:             opts.metaData,
+		schemaReader:     schema,
+		readerAt:         ra,
+		ctx:              opts.ctx,
+		allocTracker:     opts.allocTracker,
+		decryptor:        decryptor,
+		projection:       projection,
+		predicate:        opts.predicate,
+		predicateColumns: predicateColumns,
+		concurrency:      opts.concurrency,
+		size:             size,
+		planner:          planner,
 	}, nil
 }
 
+// newReader returns a reader with its own position to read from the file.
+func (f *FileReader) newReader() io.ReadSeeker {
+	return &readerAtSeeker{r: f.readerAt}
+}
+
 // FileReaderOption is an option that can be passed on to NewFileReaderWithOptions when
 // creating a new parquet file reader.
 type FileReaderOption func(*fileReaderOptions) error
@@ -71,6 +170,18 @@ type fileReaderOptions struct {
 	columns      []ColumnPath
 	validateCRC  bool
 	allocTracker *allocTracker
+	decryption   decryptionOptions
+
+	targetSchemaDef *parquetschema.SchemaDefinition
+	defaultValues   map[string]interface{}
+
+	predicate Predicate
+
+	concurrency int
+
+	coalesceReads bool
+	maxGap        int64
+	prefetch      bool
 }
 
 func newFileReaderOptions() *fileReaderOptions {
@@ -148,6 +259,125 @@ func WithMaximumMemorySize(maxSizeBytes uint64) FileReaderOption {
 	}
 }
 
+// WithKeyRetriever configures the KeyRetriever that is used to retrieve the keys to decrypt an
+// encrypted parquet file. Columns whose keys aren't available are inaccessible, but all other
+// columns can still be read if they are the only selected columns. All other errors retrieving
+// keys or decrypting column meta data fail to open the file. The footer key is required to read
+// files with an encrypted footer.
+func WithKeyRetriever(kr KeyRetriever) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		opts.decryption.keyRetriever = kr
+		return nil
+	}
+}
+
+// WithDecryptionAADPrefix configures the AAD prefix that was used to encrypt the parquet file.
+// It is required to decrypt files where the AAD prefix wasn't stored in the file.
+func WithDecryptionAADPrefix(prefix []byte) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		opts.decryption.aadPrefix = prefix
+		return nil
+	}
+}
+
+// WithTargetSchemaDefinition projects the rows of the file onto the provided schema definition, so
+// that files written with an older or newer version of a schema can be read using the same schema.
+// Columns that exist in the file but not in the target schema are not read. Columns that exist in
+// the target schema but not in the file are null, or set to their default value if one was configured
+// using WithDefaultValue. If such a column is required, a default value must be configured. The type
+// of a column can be promoted from INT32 to INT64 and from FLOAT to DOUBLE, and required columns
+// can be read as optional columns. All other differences between the file's schema and the target
+// schema are reported as error when creating the file reader. The schema definition returned by
+// GetSchemaDefinition is the target schema definition.
+func WithTargetSchemaDefinition(sd *parquetschema.SchemaDefinition) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		opts.targetSchemaDef = sd
+		return nil
+	}
+}
+
+// WithDefaultValue sets the default value of a data column that exists in the target schema set using
+// WithTargetSchemaDefinition but not in the file. The value needs to be of the Go type that is used for
+// the column's physical type, i.e. bool, int32, int64, [12]byte, float32, float64 or []byte. The same
+// value is returned for all rows, so it must not be modified.
+func WithDefaultValue(path ColumnPath, value interface{}) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		if value == nil {
+			return fmt.Errorf("default value of column %s is nil", path.flatName())
+		}
+		if opts.defaultValues == nil {
+			opts.defaultValues = make(map[string]interface{})
+		}
+		opts.defaultValues[path.flatName()] = value
+		return nil
+	}
+}
+
+// WithPredicate configures a predicate to only return the rows that match it. The columns the predicate
+// refers to are evaluated first. The pages of all other columns are only decompressed and decoded if they
+// contain matching rows, which requires an offset index (see WithPageIndex) to skip pages without decoding
+// them. Otherwise, only their levels are decoded, but not their values. The pages are still read though.
+// Row groups that can't contain any matching rows based on the statistics of their column chunks are
+// not read at all: NextRow continues with the next row group that may contain matching rows, and
+// SeekToRowGroup and SeekToRow advance to it if the requested row group is skipped. The predicate can
+// refer to all data columns of the file, or of the target schema if one is configured, independent of
+// the selected columns. An error is returned when creating the file reader if a value of the predicate
+// can't be compared with the values of its column.
+func WithPredicate(pred Predicate) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		if pred == nil {
+			return errors.New("predicate is nil")
+		}
+		opts.predicate = pred
+		return nil
+	}
+}
+
+// WithReaderConcurrency configures the number of column chunks of a row group that are read and decoded
+// concurrently. By default, column chunks are read one after another. If the reader passed to
+// NewFileReaderWithOptions doesn't implement io.ReaderAt, the reads are serialized, and only decoding
+// happens concurrently.
+func WithReaderConcurrency(n int) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		if n < 1 {
+			return fmt.Errorf("invalid concurrency %d", n)
+		}
+		opts.concurrency = n
+		return nil
+	}
+}
+
+// WithCoalescedReads configures the file reader to fetch the column chunks of a row group that are read
+// using as few reads as possible, instead of reading every page header and page separately. The byte
+// ranges of the column chunks are merged if the gap between them is at most maxGap bytes, and every
+// merged range is fetched using a single ReadAt call, concurrently if WithReaderConcurrency is used.
+// The fetched data is kept in memory while the row group is read. This greatly reduces the number
+// of requests when reading from remote storage such as object stores.
+func WithCoalescedReads(maxGap int64) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		if maxGap < 0 {
+			return fmt.Errorf("invalid maximum gap %d", maxGap)
+		}
+		opts.coalesceReads = true
+		opts.maxGap = maxGap
+		return nil
+	}
+}
+
+// WithPrefetch configures the file reader to fetch the column chunks of the next row group in the
+// background while the current row group is read. Prefetching implies coalesced reads, see
+// WithCoalescedReads; if that option isn't used, only adjacent byte ranges are merged. Please note
+// that the fetched column chunks of the next row group are kept in memory in addition to the ones
+// of the current row group, so up to twice as much memory is used for them. The prefetch uses the
+// context of the file reader (see WithReaderContext), and stops fetching further byte ranges when
+// it is done, or when another row group than the next one is read.
+func WithPrefetch(enable bool) FileReaderOption {
+	return func(opts *fileReaderOptions) error {
+		opts.prefetch = enable
+		return nil
+	}
+}
+
 // NewFileReader creates a new FileReader. You can limit the columns that are read by providing
 // the names of the specific columns to read using dotted notation. If no columns are provided,
 // then all columns are read.
@@ -183,27 +413,93 @@ func (*FileReader) recover(errp *error) {
 	}
 }
 
-// SeekToRowGroup seeks to a particular row group, identified by its index.
+// SeekToRowGroup seeks to a particular row group, identified by its index. If the row group is
+// skipped because of the predicate configured using WithPredicate, it seeks to the next row group
+// that isn't skipped.
 func (f *FileReader) SeekToRowGroup(rowGroupPosition int) (err error) {
 	defer f.recover(&err)
 	return f.SeekToRowGroupWithContext(f.ctx, rowGroupPosition)
 }
 
-// SeekToRowGroupWithContext seeks to a particular row group, identified by its index.
+// SeekToRowGroupWithContext seeks to a particular row group, identified by its index. If the row
+// group is skipped because of the predicate configured using WithPredicate, it seeks to the next row
+// group that isn't skipped.
 func (f *FileReader) SeekToRowGroupWithContext(ctx context.Context, rowGroupPosition int) (err error) {
 	defer f.recover(&err)
-	f.rowGroupPosition = rowGroupPosition - 1
+
+	if rowGroupPosition < 0 || rowGroupPosition >= len(f.meta.RowGroups) {
+		return fmt.Errorf("row group %d is out of range", rowGroupPosition)
+	}
+
+	f.rowGroupPosition = rowGroupPosition
 	f.currentRecord = 0
-	return f.readRowGroup(ctx)
+	f.skipRowGroup = false
+	if err := f.readRowGroup(ctx); err != nil {
+		f.skipRowGroup = true
+		return err
+	}
+	return nil
 }
 
-// readRowGroup read the next row group into memory
+// readRowGroup read the next row group into memory, skipping all row groups that can't match the predicate.
 func (f *FileReader) readRowGroup(ctx context.Context) error {
-	if len(f.meta.RowGroups) <= f.rowGroupPosition {
-		return io.EOF
+	for {
+		if len(f.meta.RowGroups) <= f.rowGroupPosition {
+			return io.EOF
+		}
+		f.rowGroupPosition++
+		if f.rowGroupMightMatch(f.rowGroupPosition - 1) {
+			break
+		}
+	}
+	return f.readRowGroupData(ctx, 0)
+}
+
+// SeekToRow seeks to the row identified by its index within the whole file, so that the next
+// call to NextRow returns this row. If the file contains an offset index for a column chunk,
+// only the data pages starting with the page that contains the row are read, otherwise the
+// rows before it are read and skipped. If a predicate is configured using WithPredicate, NextRow
+// returns the first matching row starting with this row. If the row is part of a row group that is
+// skipped because of the predicate, it seeks to the first row of the next row group that isn't skipped.
+func (f *FileReader) SeekToRow(row int64) (err error) {
+	defer f.recover(&err)
+	return f.SeekToRowWithContext(f.ctx, row)
+}
+
+// SeekToRowWithContext seeks to the row identified by its index within the whole file, so that
+// the next call to NextRow returns this row. If the file contains an offset index for a column chunk,
+// only the data pages starting with the page that contains the row are read, otherwise the
+// rows before it are read and skipped. If a predicate is configured using WithPredicate, NextRow
+// returns the first matching row starting with this row. If the row is part of a row group that is
+// skipped because of the predicate, it seeks to the first row of the next row group that isn't skipped.
+func (f *FileReader) SeekToRowWithContext(ctx context.Context, row int64) (err error) {
+	defer f.recover(&err)
+
+	if row < 0 {
+		return fmt.Errorf("invalid row index %d", row)
+	}
+
+	var firstRow int64
+	for idx, rg := range f.meta.RowGroups {
+		if row < firstRow+rg.NumRows {
+			f.skipRowGroup = false
+			if !f.rowGroupMightMatch(idx) {
+				f.rowGroupPosition = idx + 1
+				f.currentRecord = 0
+				if err := f.readRowGroup(ctx); err != nil {
+					f.skipRowGroup = true
+					return err
+				}
+				return nil
+			}
+			f.rowGroupPosition = idx + 1
+			f.currentRecord = row - firstRow
+			return f.readRowGroupData(ctx, f.currentRecord)
+		}
+		firstRow += rg.NumRows
 	}
-	f.rowGroupPosition++
-	return f.readRowGroupData(ctx) //, f.reader, f.schemaReader, f.meta.RowGroups[f.rowGroupPosition-1])
+
+	return io.EOF
 }
 
 // CurrentRowGroup returns information about the current row group.
@@ -214,6 +510,25 @@ func (f *FileReader) CurrentRowGroup() *parquet.RowGroup {
 	return f.meta.RowGroups[f.rowGroupPosition-1]
 }
 
+// CurrentRowGroupSortingColumns returns the columns that the rows of the current row group are sorted by,
+// in the order of their precedence. If the row group is not declared as sorted, or no row group has been
+// read yet, nil is returned.
+func (f *FileReader) CurrentRowGroupSortingColumns() ([]SortingColumn, error) {
+	if f.rowGroupPosition < 1 {
+		return nil, nil
+	}
+	return f.RowGroupSortingColumns(f.rowGroupPosition - 1)
+}
+
+// RowGroupSortingColumns returns the columns that the rows of the row group with the provided index are
+// sorted by, in the order of their precedence. If the row group is not declared as sorted, nil is returned.
+func (f *FileReader) RowGroupSortingColumns(rowGroup int) ([]SortingColumn, error) {
+	if rowGroup < 0 || rowGroup >= len(f.meta.RowGroups) {
+		return nil, fmt.Errorf("row group %d is out of range", rowGroup)
+	}
+	return fromParquetSortingColumns(f.schemaReader, f.meta.RowGroups[rowGroup].SortingColumns)
+}
+
 // RowGroupCount returns the number of row groups in the parquet file.
 func (f *FileReader) RowGroupCount() int {
 	return len(f.meta.RowGroups)
@@ -255,20 +570,41 @@ func (f *FileReader) RowGroupNumRowsWithContext(ctx context.Context) (numRecords
 }
 
 // NextRow reads the next row from the parquet file. If required, it will load the next row group.
+// If a predicate is configured using WithPredicate, rows that don't match it are skipped.
 func (f *FileReader) NextRow() (map[string]interface{}, error) {
 	return f.NextRowWithContext(f.ctx)
 }
 
 // NextRowWithContext reads the next row from the parquet file. If required, it will load the next row group.
+// If a predicate is configured using WithPredicate, rows that don't match it are skipped.
 func (f *FileReader) NextRowWithContext(ctx context.Context) (row map[string]interface{}, err error) {
 	defer f.recover(&err)
 
-	if err := f.advanceIfNeeded(ctx); err != nil {
-		return nil, err
+	for {
+		if err := f.advanceIfNeeded(ctx); err != nil {
+			return nil, err
+		}
+
+		match, err := f.currentRowMatches()
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			break
+		}
+
+		f.skipCurrentRow()
+		f.currentRecord++
 	}
 
+	f.skipHiddenColumns()
+
 	f.currentRecord++
-	return f.schemaReader.getData()
+	row, err = f.schemaReader.getData()
+	if err != nil || f.projection == nil {
+		return row, err
+	}
+	return f.projection.project(row), nil
 }
 
 // SkipRowGroup skips the currently loaded row group and advances to the next row group.
@@ -305,13 +641,101 @@ func (f *FileReader) ColumnMetaData(colName string) (map[string]string, error) {
 func (f *FileReader) ColumnMetaDataByPath(path ColumnPath) (metaData map[string]string, err error) {
 	defer f.recover(&err)
 	for _, col := range f.CurrentRowGroup().Columns {
-		if path.Equal(ColumnPath(col.MetaData.PathInSchema)) {
+		if col.MetaData != nil && path.Equal(ColumnPath(col.MetaData.PathInSchema)) {
 			return keyValueMetaDataToMap(col.MetaData.KeyValueMetadata), nil
 		}
 	}
 	return nil, fmt.Errorf("column %q not found", path.flatName())
 }
 
+// ColumnIndex returns the column index of a column in a row group, identified by the row group's
+// index and the column's path. The column index contains the min and max values as well as the
+// null count of every data page. If the file contains no column index for the column chunk, nil
+// is returned.
+func (f *FileReader) ColumnIndex(rowGroup int, path ColumnPath) (columnIndex *parquet.ColumnIndex, err error) {
+	defer f.recover(&err)
+
+	chunk, decryptor, err := f.columnChunk(rowGroup, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.readColumnIndex(f.ctx, f.newReader(), chunk, decryptor)
+}
+
+// OffsetIndex returns the offset index of a column in a row group, identified by the row group's
+// index and the column's path. The offset index contains the location and the first row index of
+// every data page. If the file contains no offset index for the column chunk, nil is returned.
+func (f *FileReader) OffsetIndex(rowGroup int, path ColumnPath) (offsetIndex *parquet.OffsetIndex, err error) {
+	defer f.recover(&err)
+
+	chunk, decryptor, err := f.columnChunk(rowGroup, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.readOffsetIndex(f.ctx, f.newReader(), chunk, decryptor)
+}
+
+// BloomFilter returns the bloom filter of a column in a row group, identified by the row group's
+// index and the column's path. The bloom filter can be used to check whether a row group definitely
+// doesn't contain a value in that column, without reading the row group. If the file contains no
+// bloom filter for the column chunk, nil is returned.
+func (f *FileReader) BloomFilter(rowGroup int, path ColumnPath) (bloomFilter *BloomFilter, err error) {
+	defer f.recover(&err)
+
+	chunk, decryptor, err := f.columnChunk(rowGroup, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.readBloomFilter(f.ctx, chunk, decryptor)
+}
+
+func (f *FileReader) columnChunk(rowGroup int, path ColumnPath) (*parquet.ColumnChunk, *columnChunkDecryptor, error) {
+	if rowGroup < 0 || rowGroup >= len(f.meta.RowGroups) {
+		return nil, nil, fmt.Errorf("row group %d is out of range", rowGroup)
+	}
+
+	col := f.schemaReader.GetColumnByPath(path)
+	if col == nil || !col.DataColumn() {
+		return nil, nil, fmt.Errorf("column %q not found", path.flatName())
+	}
+
+	columns := f.meta.RowGroups[rowGroup].Columns
+	if col.Index() >= len(columns) {
+		return nil, nil, fmt.Errorf("column index %d is out of bounds", col.Index())
+	}
+
+	chunk := columns[col.Index()]
+	decryptor, err := f.decryptor.columnChunkDecryptor(chunk, rowGroup, col.Index())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return chunk, decryptor, nil
+}
+
+// InaccessibleColumns returns the paths of all encrypted columns that can't be read because their
+// keys are not available. These columns need to be excluded from the selected columns to read the file.
+func (f *FileReader) InaccessibleColumns() []ColumnPath {
+	var paths []ColumnPath
+	for _, col := range f.schemaReader.Columns() {
+		if f.decryptor.inaccessibleColumn(col.path) {
+			paths = append(paths, col.path)
+		}
+	}
+	return paths
+}
+
+// FooterVerified returns true if the file meta data of an encrypted file has been authenticated, i.e. if
+// the footer is encrypted, or if the footer is plaintext and its signature has been verified using the
+// footer key. It returns false for files that aren't encrypted, if the footer key isn't available, and
+// if the file meta data was provided using WithFileMetaData.
+func (f *FileReader) FooterVerified() bool {
+	return f.decryptor != nil && f.decryptor.footerVerified
+}
+
 // SetSelectedColumns sets the columns which are read. By default, all columns
 // will be read.
 //
@@ -347,6 +771,9 @@ func (f *FileReader) GetColumnByPath(path ColumnPath) *Column {
 
 // GetSchemaDefinition returns the current schema definition.
 func (f *FileReader) GetSchemaDefinition() *parquetschema.SchemaDefinition {
+	if f.projection != nil {
+		return f.projection.target
+	}
 	return f.schemaReader.GetSchemaDefinition()
 }
 
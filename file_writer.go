@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/binary"
+	"fmt"
 	"io"
 
 	"github.com/fraugster/parquet-go/parquet"
@@ -29,10 +30,24 @@ type FileWriter struct {
 
 	rowGroups []*parquet.RowGroup
 
-	codec parquet.CompressionCodec
+	writePageIndex bool
+	pageIndexes    [][]*columnChunkIndex
+
+	bloomFilters []*bloomFilterOptions
+
+	sortingColumns []SortingColumn
+
+	encryption *encryptionOptions
+	encryptor  *fileEncryptor
+
+	codec                   parquet.CompressionCodec
+	compressionLevels       map[parquet.CompressionCodec]int
+	columnCompressionLevels []*columnCompressionLevel
 
 	newPageFunc newDataPageFunc
 
+	concurrency int
+
 	ctx context.Context
 
 	schemaDef *parquetschema.SchemaDefinition
@@ -123,6 +138,15 @@ func WithMaxPageSize(size int64) FileWriterOption {
 	}
 }
 
+// WithStatisticsTruncateLength sets the maximum length of the min and max values of byte array columns in
+// the statistics and the column index. Longer values are truncated, and the max value is rounded up so that
+// it's still an upper bound. The default length is 64 bytes. A negative length disables truncation.
+func WithStatisticsTruncateLength(length int) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.schemaWriter.statsTruncateLength = length
+	}
+}
+
 // WithSchemaDefinition sets the schema definition to use for this parquet file.
 func WithSchemaDefinition(sd *parquetschema.SchemaDefinition) FileWriterOption {
 	return func(fw *FileWriter) {
@@ -145,6 +169,155 @@ func WithCRC(enableCRC bool) FileWriterOption {
 	}
 }
 
+// WithCompressionLevel sets the compression level that is used when compressing data with the
+// provided compression codec. The block compressor that is registered for the compression codec
+// needs to implement LeveledBlockCompressor, which the built-in GZIP, ZSTD and BROTLI block
+// compressors do. If no compression level is set, the block compressor's default level is used.
+func WithCompressionLevel(codec parquet.CompressionCodec, level int) FileWriterOption {
+	return func(fw *FileWriter) {
+		if fw.compressionLevels == nil {
+			fw.compressionLevels = make(map[parquet.CompressionCodec]int)
+		}
+		fw.compressionLevels[codec] = level
+	}
+}
+
+// WithColumnCompressionLevel sets the compression level that is used when compressing the data
+// of the column with the provided path. It overrides the compression level that is set for the
+// compression codec using WithCompressionLevel.
+func WithColumnCompressionLevel(path ColumnPath, level int) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.columnCompressionLevels = append(fw.columnCompressionLevels, &columnCompressionLevel{
+			path:  path,
+			level: level,
+		})
+	}
+}
+
+// WithSortingColumns declares the columns that the rows of all row groups are sorted by. This
+// only records the sort order in the row group meta data, the rows need to be added in this
+// order. The sorting columns can be overridden for individual row groups by passing the
+// WithRowGroupSortingColumns option to FlushRowGroup.
+func WithSortingColumns(cols ...SortingColumn) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.sortingColumns = cols
+	}
+}
+
+// WithPageIndex enables the writing of the page index, i.e. a column index containing
+// the min and max values, null pages and null counts for each data page, and an offset
+// index containing the location of each data page. Readers can use the page index to
+// skip pages that don't contain relevant data. By default, no page index is written.
+func WithPageIndex(enable bool) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.writePageIndex = enable
+	}
+}
+
+// WithSortedDictionaries enables the sorting of dictionary values. The values of all
+// dictionary pages are written in the sort order of their column, and the dictionary
+// pages are flagged as sorted, which allows readers to binary-search the dictionary.
+// Sorting usually improves the compression of the dictionary and of the indices in
+// the data pages. INT96 dictionaries are never sorted, as their sort order is undefined.
+// By default, dictionary values are written in the order they were added.
+func WithSortedDictionaries(enable bool) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.schemaWriter.sortDictionaries = enable
+	}
+}
+
+// WithBloomFilter enables the writing of a split block bloom filter for the column
+// with the provided path. numDistinctValues is the expected number of distinct values
+// per row group and is used to size the bloom filter so that it doesn't exceed the
+// provided false positive rate. If numDistinctValues is 0 or less, the actual number of
+// distinct values of the column chunk is used. Bloom filters are not supported for
+// boolean columns.
+func WithBloomFilter(path ColumnPath, numDistinctValues int64, falsePositiveRate float64) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.bloomFilters = append(fw.bloomFilters, &bloomFilterOptions{
+			path:              path,
+			numDistinctValues: numDistinctValues,
+			falsePositiveRate: falsePositiveRate,
+		})
+	}
+}
+
+// WithWriterConcurrency sets the number of column chunks of a row group that are encoded and
+// compressed concurrently when the row group is flushed. Concurrently encoded column chunks are
+// buffered in memory and written in schema order, so the resulting file is the same as without
+// concurrency. Values less than or equal to 1 disable concurrency, which is the default. Custom
+// block compressors need to be safe for concurrent use if concurrency is enabled.
+func WithWriterConcurrency(n int) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.concurrency = n
+	}
+}
+
+// WithFooterKey enables the encryption of the file using the parquet modular encryption.
+// The footer key is used to encrypt the footer, and all columns unless keys for specific
+// columns are provided using WithColumnKey. The key needs to be 16, 24 or 32 bytes long.
+// The key meta data is stored in the file and allows readers to retrieve the key. It can
+// be nil if the readers know the key by other means.
+func WithFooterKey(key, keyMetadata []byte) FileWriterOption {
+	return func(fw *FileWriter) {
+		opts := fw.encryptionOptions()
+		opts.footerKey = key
+		opts.footerKeyMetadata = keyMetadata
+	}
+}
+
+// WithColumnKey sets the key to encrypt the column with the provided path. If keys for
+// specific columns are provided, only these columns are encrypted, and all other columns
+// are stored in plaintext. The key needs to be 16, 24 or 32 bytes long. The key meta data
+// is stored in the file and allows readers to retrieve the key. Encrypting columns requires
+// a footer key to be provided using WithFooterKey.
+func WithColumnKey(path ColumnPath, key, keyMetadata []byte) FileWriterOption {
+	return func(fw *FileWriter) {
+		opts := fw.encryptionOptions()
+		opts.columnKeys = append(opts.columnKeys, &columnKeyOptions{
+			path:        path,
+			key:         key,
+			keyMetadata: keyMetadata,
+		})
+	}
+}
+
+// WithPlaintextFooter writes the footer of an encrypted file in plaintext so that readers
+// without access to the footer key can still read the schema and the plaintext columns.
+// The footer is signed with the footer key so that readers with access to the key can verify
+// its integrity. By default, the footer of an encrypted file is encrypted.
+func WithPlaintextFooter(enable bool) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.encryptionOptions().plaintextFooter = enable
+	}
+}
+
+// WithEncryptionAlgorithm sets the algorithm used to encrypt the file. By default,
+// EncryptionAlgorithmAESGCM is used.
+func WithEncryptionAlgorithm(algorithm EncryptionAlgorithm) FileWriterOption {
+	return func(fw *FileWriter) {
+		fw.encryptionOptions().algorithm = algorithm
+	}
+}
+
+// WithAADPrefix sets the prefix of the additional authenticated data (AAD) of an encrypted
+// file, e.g. to bind the file to its name or to a table. If storeInFile is false, the prefix
+// is not stored in the file, and readers need to supply it themselves.
+func WithAADPrefix(prefix []byte, storeInFile bool) FileWriterOption {
+	return func(fw *FileWriter) {
+		opts := fw.encryptionOptions()
+		opts.aadPrefix = prefix
+		opts.storeAADPrefix = storeInFile
+	}
+}
+
+func (fw *FileWriter) encryptionOptions() *encryptionOptions {
+	if fw.encryption == nil {
+		fw.encryption = &encryptionOptions{}
+	}
+	return fw.encryption
+}
+
 // WithWriterContext overrides the default context (which is a context.Background())
 // in the FileWriter with the provided context.Context object.
 func WithWriterContext(ctx context.Context) FileWriterOption {
@@ -161,6 +334,9 @@ type columnKeyValues struct {
 type flushRowGroupOptionHandle struct {
 	cols   []columnKeyValues
 	global map[string]string
+
+	sortingColumns    []SortingColumn
+	hasSortingColumns bool
 }
 
 func newFlushRowGroupOptionHandle() *flushRowGroupOptionHandle {
@@ -225,6 +401,16 @@ func WithRowGroupMetaData(kv map[string]string) FlushRowGroupOption {
 	}
 }
 
+// WithRowGroupSortingColumns declares the columns that the rows of the row group are sorted by. It
+// overrides the sorting columns declared using WithSortingColumns. If no sorting columns are
+// provided, the row group is declared as unsorted.
+func WithRowGroupSortingColumns(cols ...SortingColumn) FlushRowGroupOption {
+	return func(h *flushRowGroupOptionHandle) {
+		h.sortingColumns = cols
+		h.hasSortingColumns = true
+	}
+}
+
 // FlushRowGroup writes the current row group to the parquet file.
 func (fw *FileWriter) FlushRowGroup(opts ...FlushRowGroupOption) error {
 	return fw.FlushRowGroupWithContext(fw.ctx, opts...)
@@ -237,10 +423,12 @@ func (fw *FileWriter) FlushRowGroupWithContext(ctx context.Context, opts ...Flus
 		return nil
 	}
 
-	if fw.w.Pos() == 0 {
-		if err := writeFull(fw.w, magic); err != nil {
-			return err
-		}
+	if err := fw.schemaWriter.checkNumRecords(); err != nil {
+		return err
+	}
+
+	if err := fw.start(); err != nil {
+		return err
 	}
 
 	h := newFlushRowGroupOptionHandle()
@@ -249,11 +437,24 @@ func (fw *FileWriter) FlushRowGroupWithContext(ctx context.Context, opts ...Flus
 		o(h)
 	}
 
-	cc, err := writeRowGroup(ctx, fw.w, fw.schemaWriter, fw.codec, fw.newPageFunc, h)
+	sortingColumns := fw.sortingColumns
+	if h.hasSortingColumns {
+		sortingColumns = h.sortingColumns
+	}
+	parquetSortingColumns, err := toParquetSortingColumns(fw.schemaWriter, sortingColumns)
 	if err != nil {
 		return err
 	}
 
+	cc, indexes, err := fw.writeRowGroup(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	if fw.writePageIndex {
+		fw.pageIndexes = append(fw.pageIndexes, indexes)
+	}
+
 	var totalCompressedSize, totalUncompressedSize int64
 
 	for _, c := range cc {
@@ -261,13 +462,19 @@ func (fw *FileWriter) FlushRowGroupWithContext(ctx context.Context, opts ...Flus
 		totalUncompressedSize += c.MetaData.TotalUncompressedSize
 	}
 
-	fw.rowGroups = append(fw.rowGroups, &parquet.RowGroup{
+	rg := &parquet.RowGroup{
 		Columns:             cc,
 		TotalByteSize:       totalUncompressedSize,
 		TotalCompressedSize: &totalCompressedSize,
 		NumRows:             fw.schemaWriter.rowGroupNumRecords(),
-		SortingColumns:      nil,
-	})
+		SortingColumns:      parquetSortingColumns,
+	}
+	if fw.encryptor != nil {
+		// the ordinal is part of the AAD of all modules of the row group.
+		ordinal := int16(len(fw.rowGroups))
+		rg.Ordinal = &ordinal
+	}
+	fw.rowGroups = append(fw.rowGroups, rg)
 	fw.totalNumRecords += fw.schemaWriter.rowGroupNumRecords()
 	// flush the schema
 	fw.schemaWriter.resetData()
@@ -275,6 +482,24 @@ func (fw *FileWriter) FlushRowGroupWithContext(ctx context.Context, opts ...Flus
 	return nil
 }
 
+// start sets up the encryption if required and writes the magic header
+// if nothing has been written yet.
+func (fw *FileWriter) start() error {
+	if fw.w.Pos() > 0 {
+		return nil
+	}
+
+	if fw.encryption != nil {
+		encryptor, err := newFileEncryptor(fw.schemaWriter, fw.encryption)
+		if err != nil {
+			return fmt.Errorf("setting up encryption failed: %w", err)
+		}
+		fw.encryptor = encryptor
+	}
+
+	return writeFull(fw.w, fw.encryptor.magic())
+}
+
 // AddData adds a new record to the current row group and flushes it if auto-flush is enabled and the size
 // is equal to or greater than the configured maximum row group size.
 func (fw *FileWriter) AddData(m map[string]interface{}) error {
@@ -310,6 +535,16 @@ func (fw *FileWriter) CloseWithContext(ctx context.Context, opts ...FlushRowGrou
 		}
 	}
 
+	if err := fw.start(); err != nil {
+		return err
+	}
+
+	if fw.writePageIndex {
+		if err := writePageIndexes(ctx, fw.w, fw.rowGroups, fw.pageIndexes); err != nil {
+			return err
+		}
+	}
+
 	kv := make([]*parquet.KeyValue, 0, len(fw.kvStore))
 	for i := range fw.kvStore {
 		v := fw.kvStore[i]
@@ -329,11 +564,18 @@ func (fw *FileWriter) CloseWithContext(ctx context.Context, opts ...FlushRowGrou
 		RowGroups:        fw.rowGroups,
 		KeyValueMetadata: kv,
 		CreatedBy:        &fw.createdBy,
-		ColumnOrders:     nil,
+		ColumnOrders:     typeDefinedColumnOrders(fw.schemaWriter),
 	}
 
 	pos := fw.w.Pos()
-	if err := writeThrift(ctx, meta, fw.w); err != nil {
+	if fw.encryptor != nil {
+		if err := fw.encryptor.encryptColumnMetaData(ctx, fw.rowGroups); err != nil {
+			return err
+		}
+		if err := fw.encryptor.writeFooter(ctx, fw.w, meta); err != nil {
+			return err
+		}
+	} else if err := writeThrift(ctx, meta, fw.w); err != nil {
 		return err
 	}
 
@@ -342,7 +584,7 @@ func (fw *FileWriter) CloseWithContext(ctx context.Context, opts ...FlushRowGrou
 		return err
 	}
 
-	if err := writeFull(fw.w, magic); err != nil {
+	if err := writeFull(fw.w, fw.encryptor.magic()); err != nil {
 		return err
 	}
 
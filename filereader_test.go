@@ -2,10 +2,15 @@ package goparquet
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"sync"
 	"testing"
 
+	"github.com/fraugster/parquet-go/parquet"
 	"github.com/fraugster/parquet-go/parquetschema"
 	"github.com/stretchr/testify/require"
 )
@@ -245,3 +250,202 @@ func TestIssue60(t *testing.T) {
 
 	t.Logf("row = %#v", row)
 }
+
+// readSeekerOnly hides the io.ReaderAt implementation of the wrapped reader.
+type readSeekerOnly struct {
+	io.ReadSeeker
+}
+
+func readAllRows(t *testing.T, r *FileReader) []map[string]interface{} {
+	var rows []map[string]interface{}
+	for {
+		row, err := r.NextRow()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestFileReaderConcurrency(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		required double score;
+		optional group tags (LIST) {
+			repeated group list {
+				required int32 element;
+			}
+		}
+		required group nested {
+			optional int32 a;
+			required boolean b;
+		}
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithCompressionCodec(parquet.CompressionCodec_SNAPPY), WithMaxPageSize(256))
+	for i := 0; i < 1000; i++ {
+		row := map[string]interface{}{
+			"id":    int64(i),
+			"score": float64(i) / 3,
+			"nested": map[string]interface{}{
+				"b": i%3 == 0,
+			},
+		}
+		if i%5 != 0 {
+			row["name"] = []byte(fmt.Sprintf("name-%d", i))
+		}
+		if i%7 != 0 {
+			row["tags"] = map[string]interface{}{
+				"list": []map[string]interface{}{{"element": int32(i)}, {"element": int32(i * 2)}},
+			}
+			row["nested"].(map[string]interface{})["a"] = int32(i)
+		}
+		require.NoError(t, fw.AddData(row))
+		if i%150 == 149 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	expected := readAllRows(t, r)
+	require.Len(t, expected, 1000)
+
+	sources := map[string]func() io.ReadSeeker{
+		"reader_at":   func() io.ReadSeeker { return bytes.NewReader(buf.Bytes()) },
+		"read_seeker": func() io.ReadSeeker { return readSeekerOnly{bytes.NewReader(buf.Bytes())} },
+	}
+
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewFileReaderWithOptions(source(), WithReaderConcurrency(4))
+			require.NoError(t, err)
+			require.Equal(t, expected, readAllRows(t, r))
+
+			r, err = NewFileReaderWithOptions(source(), WithReaderConcurrency(3), WithColumnPaths(ColumnPath{"id"}, ColumnPath{"tags"}))
+			require.NoError(t, err)
+			rows := readAllRows(t, r)
+			require.Len(t, rows, 1000)
+			for i, row := range rows {
+				require.Equal(t, expected[i]["id"], row["id"])
+				require.Equal(t, expected[i]["tags"], row["tags"])
+				require.NotContains(t, row, "name")
+			}
+
+			r, err = NewFileReaderWithOptions(source(), WithReaderConcurrency(4))
+			require.NoError(t, err)
+			require.NoError(t, r.SeekToRow(512))
+			require.Equal(t, expected[512:], readAllRows(t, r))
+
+			r, err = NewFileReaderWithOptions(source(), WithReaderConcurrency(4), WithPredicate(Gt(ColumnPath{"id"}, 900)))
+			require.NoError(t, err)
+			require.Equal(t, expected[901:], readAllRows(t, r))
+		})
+	}
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()), WithReaderConcurrency(0))
+	require.Error(t, err)
+}
+
+func TestNewFileReaderAt(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithPageIndex(true), WithBloomFilter(ColumnPath{"name"}, 100, 0.01))
+	for i := 0; i < 1000; i++ {
+		row := map[string]interface{}{"id": int64(i)}
+		if i%2 != 0 {
+			row["name"] = []byte(fmt.Sprintf("name-%d", i))
+		}
+		require.NoError(t, fw.AddData(row))
+		if i%100 == 99 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	f, err := ioutil.TempFile("", "parquet-go-test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.Write(buf.Bytes())
+	require.NoError(t, err)
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	expected := readAllRows(t, r)
+
+	meta, err := ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+
+	// every row group is read by its own reader, all of them sharing the same file.
+	var (
+		wg     sync.WaitGroup
+		rows   = make([][]map[string]interface{}, len(meta.RowGroups))
+		errs   = make([]error, len(meta.RowGroups))
+		shared *FileReader
+	)
+	shared, err = NewFileReaderAt(f, int64(buf.Len()))
+	require.NoError(t, err)
+
+	for rg := range meta.RowGroups {
+		wg.Add(1)
+		go func(rg int) {
+			defer wg.Done()
+
+			errs[rg] = func() error {
+				r, err := NewFileReaderAt(f, int64(buf.Len()), WithFileMetaData(meta))
+				if err != nil {
+					return err
+				}
+				if err := r.SeekToRowGroup(rg); err != nil {
+					return err
+				}
+				for i := int64(0); i < meta.RowGroups[rg].NumRows; i++ {
+					row, err := r.NextRow()
+					if err != nil {
+						return err
+					}
+					rows[rg] = append(rows[rg], row)
+				}
+
+				// position independent methods can be used concurrently on the same reader.
+				if _, err := shared.OffsetIndex(rg, ColumnPath{"id"}); err != nil {
+					return err
+				}
+				bf, err := shared.BloomFilter(rg, ColumnPath{"name"})
+				if err != nil {
+					return err
+				}
+				if !bf.MightContain([]byte(fmt.Sprintf("name-%d", rg*100+1))) {
+					return fmt.Errorf("bloom filter of row group %d doesn't contain its values", rg)
+				}
+				return nil
+			}()
+		}(rg)
+	}
+	wg.Wait()
+
+	var actual []map[string]interface{}
+	for rg := range meta.RowGroups {
+		require.NoError(t, errs[rg])
+		actual = append(actual, rows[rg]...)
+	}
+	require.Equal(t, expected, actual)
+
+	require.Equal(t, expected, readAllRows(t, shared))
+
+	_, err = NewFileReaderAt(f, 4)
+	require.Error(t, err)
+}
@@ -3,11 +3,14 @@ module github.com/fraugster/parquet-go
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/apache/thrift v0.16.0
 	github.com/araddon/dateparse v0.0.0-20210429162001-6b43995a97de
 	github.com/davecgh/go-spew v1.1.1
 	github.com/golang/snappy v0.0.4
+	github.com/klauspost/compress v1.15.9
 	github.com/kr/pretty v0.1.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/stretchr/testify v1.7.0
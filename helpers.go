@@ -9,6 +9,7 @@ import (
 	"io"
 	"math"
 	"math/bits"
+	"sync"
 
 	"github.com/apache/thrift/lib/go/thrift"
 )
@@ -63,6 +64,118 @@ func (o *offsetReader) Count() int64 {
 	return o.count
 }
 
+// readerAtSeeker reads from an io.ReaderAt using its own offset, so that multiple readerAtSeekers
+// can read from the same io.ReaderAt concurrently. Seeking relative to the end is not supported.
+type readerAtSeeker struct {
+	r      io.ReaderAt
+	offset int64
+}
+
+func (r *readerAtSeeker) Read(p []byte) (int, error) {
+	n, err := r.r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *readerAtSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	default:
+		return 0, fmt.Errorf("unsupported whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+// lockedReaderAt implements io.ReaderAt on top of an io.ReadSeeker by seeking before every read.
+// The reads are serialized, so it is safe for concurrent use as long as the io.ReadSeeker isn't
+// used otherwise at the same time.
+type lockedReaderAt struct {
+	mtx sync.Mutex
+	r   io.ReadSeeker
+}
+
+func (l *lockedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if _, err := l.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(l.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// forEachConcurrently calls fn for all indexes from 0 to n-1 using at most concurrency goroutines, and
+// returns the error of the lowest index that failed. If concurrency is less than 2, fn is called in the
+// current goroutine, and the first error is returned immediately.
+func forEachConcurrently(concurrency, n int, fn func(i int) error) error {
+	if concurrency < 2 || n < 2 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if concurrency > n {
+		concurrency = n
+	}
+
+	var (
+		wg   sync.WaitGroup
+		work = make(chan int)
+		errs = make([]error, n)
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				errs[idx] = fn(idx)
+			}
+		}()
+	}
+
+	for idx := 0; idx < n; idx++ {
+		work <- idx
+	}
+	close(work)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newReaderAt returns the io.ReadSeeker as io.ReaderAt, or wraps it if it doesn't implement it.
+func newReaderAt(r io.ReadSeeker) io.ReaderAt {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra
+	}
+	return &lockedReaderAt{r: r}
+}
+
 func decodeRLEValue(bytes []byte) int32 {
 	switch len(bytes) {
 	case 0:
@@ -0,0 +1,22 @@
+// Package httpreader provides a reader for files served by HTTP servers that support range requests.
+// It allows the goparquet package to read parquet files without downloading them whole, for example
+// to only inspect their meta data, or to only read a few columns.
+//
+// The Reader implements io.ReaderAt and io.ReadSeeker, and provides the size of the file:
+//
+//	r, err := httpreader.New("https://example.com/data.parquet")
+//	if err != nil {
+//		// ...
+//	}
+//	defer r.Close()
+//
+//	fr, err := goparquet.NewFileReaderAt(r, r.Size(), goparquet.WithCoalescedReads(64*1024))
+//	if err != nil {
+//		// ...
+//	}
+//
+// Reads are cached in blocks, so that the many small reads that are required to read the file meta
+// data and the page headers don't result in a request each. Failed requests are retried. Both can be
+// configured using options, as can the http.Client that is used to send the requests, which allows
+// adding authentication, for example.
+package httpreader
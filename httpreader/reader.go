@@ -0,0 +1,450 @@
+package httpreader
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBlockSize is the size of the blocks that are cached by default.
+	DefaultBlockSize = 256 * 1024
+
+	// DefaultCacheBlocks is the number of blocks that are cached by default.
+	DefaultCacheBlocks = 16
+
+	// DefaultRetries is the number of times a failed request is retried by default.
+	DefaultRetries = 3
+
+	// DefaultRetryWait is the time to wait before retrying a failed request for the first
+	// time by default. The time is doubled for every further retry.
+	DefaultRetryWait = 100 * time.Millisecond
+)
+
+// Reader reads a file served by an HTTP server using range requests. It implements io.ReaderAt, which
+// is safe for concurrent use, and io.ReadSeeker. Reads are served from a cache of fixed-size blocks if
+// possible. Reads that span more blocks than the cache can hold are not cached.
+type Reader struct {
+	url    string
+	client *http.Client
+	header http.Header
+	ctx    context.Context
+
+	retries   int
+	retryWait time.Duration
+
+	blockSize   int64
+	cacheBlocks int
+
+	size int64
+
+	// mtx protects the cache and the offset used by Read and Seek.
+	mtx    sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+	offset int64
+}
+
+// cachedBlock is a block of the file, identified by its index.
+type cachedBlock struct {
+	index int64
+	data  []byte
+}
+
+// Option is an option that can be passed to New to configure the Reader.
+type Option func(*Reader) error
+
+// WithClient sets the http.Client that is used to send requests. By default, http.DefaultClient
+// is used. A custom client can be used to configure timeouts or a transport that adds authentication.
+func WithClient(client *http.Client) Option {
+	return func(r *Reader) error {
+		if client == nil {
+			return errors.New("client is nil")
+		}
+		r.client = client
+		return nil
+	}
+}
+
+// WithHeader adds a header that is sent with every request.
+func WithHeader(key, value string) Option {
+	return func(r *Reader) error {
+		r.header.Add(key, value)
+		return nil
+	}
+}
+
+// WithContext sets the context that is used for all requests. If none is set,
+// context.Background() is used.
+func WithContext(ctx context.Context) Option {
+	return func(r *Reader) error {
+		r.ctx = ctx
+		return nil
+	}
+}
+
+// WithRetries sets the number of times a request is retried if it fails because of a network
+// error or a server error, and the time to wait before the first retry, which is doubled for
+// every further retry. Requests that fail because of a client error, e.g. because the file
+// doesn't exist, are not retried.
+func WithRetries(retries int, wait time.Duration) Option {
+	return func(r *Reader) error {
+		if retries < 0 {
+			return fmt.Errorf("invalid number of retries %d", retries)
+		}
+		r.retries = retries
+		r.retryWait = wait
+		return nil
+	}
+}
+
+// WithBlockCache configures the block cache. Reads are aligned to blocks of blockSize bytes, and
+// up to numBlocks blocks are cached, discarding the least recently used blocks first. If numBlocks
+// is 0, the cache is disabled, and every read results in a request.
+func WithBlockCache(blockSize int64, numBlocks int) Option {
+	return func(r *Reader) error {
+		if blockSize <= 0 {
+			return fmt.Errorf("invalid block size %d", blockSize)
+		}
+		if numBlocks < 0 {
+			return fmt.Errorf("invalid number of blocks %d", numBlocks)
+		}
+		r.blockSize = blockSize
+		r.cacheBlocks = numBlocks
+		return nil
+	}
+}
+
+// New creates a new Reader for the file at the provided URL. The size of the file is determined
+// using a range request, which fails if the server doesn't support range requests.
+func New(url string, opts ...Option) (*Reader, error) {
+	r := &Reader{
+		url:         url,
+		client:      http.DefaultClient,
+		header:      make(http.Header),
+		ctx:         context.Background(),
+		retries:     DefaultRetries,
+		retryWait:   DefaultRetryWait,
+		blockSize:   DefaultBlockSize,
+		cacheBlocks: DefaultCacheBlocks,
+		blocks:      make(map[int64]*list.Element),
+		lru:         list.New(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	r.size, err = r.fetchSize()
+	if err != nil {
+		return nil, fmt.Errorf("determining the size of %s failed: %w", url, err)
+	}
+
+	return r, nil
+}
+
+// Size returns the size of the file.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Close releases the cached blocks. The Reader must not be used afterwards.
+func (r *Reader) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.blocks = make(map[int64]*list.Element)
+	r.lru.Init()
+	return nil
+}
+
+// Read reads from the current offset, see io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mtx.Lock()
+	offset := r.offset
+	r.mtx.Unlock()
+
+	n, err := r.ReadAt(p, offset)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+
+	r.mtx.Lock()
+	r.offset = offset + int64(n)
+	r.mtx.Unlock()
+
+	return n, err
+}
+
+// Seek sets the offset for the next Read, see io.Seeker.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+// ReadAt reads len(p) bytes starting at offset off, see io.ReaderAt.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	var (
+		n   int
+		err error
+	)
+	firstBlock, lastBlock := off/r.blockSize, (end-1)/r.blockSize
+	if lastBlock-firstBlock+1 > int64(r.cacheBlocks) {
+		n, err = r.readUncached(p[:end-off], off)
+	} else {
+		n, err = r.readCached(p[:end-off], off, firstBlock, lastBlock)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (r *Reader) readUncached(p []byte, off int64) (int, error) {
+	data, err := r.fetch(off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	return copy(p, data), nil
+}
+
+// readCached reads the blocks from the cache, and fetches each run of consecutive missing blocks using
+// a single request.
+func (r *Reader) readCached(p []byte, off int64, firstBlock, lastBlock int64) (int, error) {
+	blocks := make([][]byte, lastBlock-firstBlock+1)
+
+	r.mtx.Lock()
+	for i := range blocks {
+		if elem, ok := r.blocks[firstBlock+int64(i)]; ok {
+			r.lru.MoveToFront(elem)
+			blocks[i] = elem.Value.(*cachedBlock).data
+		}
+	}
+	r.mtx.Unlock()
+
+	for i := 0; i < len(blocks); {
+		if blocks[i] != nil {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(blocks) && blocks[j] == nil {
+			j++
+		}
+
+		start := (firstBlock + int64(i)) * r.blockSize
+		end := (firstBlock + int64(j)) * r.blockSize
+		if end > r.size {
+			end = r.size
+		}
+
+		data, err := r.fetch(start, end-start)
+		if err != nil {
+			return 0, err
+		}
+
+		for k := i; k < j; k++ {
+			blockStart := int64(k-i) * r.blockSize
+			blockEnd := blockStart + r.blockSize
+			if blockEnd > int64(len(data)) {
+				blockEnd = int64(len(data))
+			}
+			blocks[k] = data[blockStart:blockEnd:blockEnd]
+			r.addBlock(firstBlock+int64(k), blocks[k])
+		}
+
+		i = j
+	}
+
+	n := 0
+	for i, block := range blocks {
+		if i == 0 {
+			block = block[off-firstBlock*r.blockSize:]
+		}
+		n += copy(p[n:], block)
+	}
+
+	return n, nil
+}
+
+func (r *Reader) addBlock(index int64, data []byte) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.blocks[index]; ok {
+		return
+	}
+
+	r.blocks[index] = r.lru.PushFront(&cachedBlock{index: index, data: data})
+
+	for r.lru.Len() > r.cacheBlocks {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.blocks, oldest.Value.(*cachedBlock).index)
+	}
+}
+
+// errRangeNotSupported is returned if the server responds with the whole file to a range request.
+var errRangeNotSupported = errors.New("server doesn't support range requests")
+
+// statusError is returned if the server responded with an unexpected status code.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "unexpected HTTP status " + e.status
+}
+
+// retryable returns true if the request failed because of a network error or a server error.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, errRangeNotSupported) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't retryable, or the number of
+// retries is exhausted.
+func (r *Reader) retry(fn func() error) error {
+	wait := r.retryWait
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.retries || !retryable(err) {
+			return err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-r.ctx.Done():
+			t.Stop()
+			return r.ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// fetch reads length bytes starting at offset off using a range request.
+func (r *Reader) fetch(off, length int64) ([]byte, error) {
+	var data []byte
+	err := r.retry(func() error {
+		resp, err := r.do(off, length)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data = make([]byte, length)
+		if _, err := io.ReadFull(resp.Body, data); err != nil {
+			return fmt.Errorf("reading %d bytes at offset %d failed: %w", length, off, err)
+		}
+		return nil
+	})
+	return data, err
+}
+
+// fetchSize determines the size of the file from the Content-Range header of a range request
+// for the first byte.
+func (r *Reader) fetchSize() (int64, error) {
+	var size int64
+	err := r.retry(func() error {
+		resp, err := r.do(0, 1)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+		contentRange := resp.Header.Get("Content-Range")
+		idx := strings.LastIndex(contentRange, "/")
+		if idx < 0 {
+			return fmt.Errorf("invalid Content-Range header %q", contentRange)
+		}
+		size, err = strconv.ParseInt(contentRange[idx+1:], 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("unknown size in Content-Range header %q", contentRange)
+		}
+		return nil
+	})
+	return size, err
+}
+
+// do sends a range request for length bytes starting at offset off. The response body must be
+// closed by the caller if no error is returned.
+func (r *Reader) do(off, length int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+length-1))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil, errRangeNotSupported
+		}
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,257 @@
+package httpreader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+type testServer struct {
+	*httptest.Server
+	requests int64
+	failures int64
+}
+
+// newTestServer serves the data using range requests. The first failures requests fail with a server error.
+func newTestServer(data []byte, failures int64) *testServer {
+	ts := &testServer{failures: failures}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&ts.requests, 1) <= ts.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "test.parquet", time.Time{}, bytes.NewReader(data))
+	}))
+	return ts
+}
+
+func (ts *testServer) resetRequests() {
+	atomic.StoreInt64(&ts.requests, 0)
+}
+
+func (ts *testServer) numRequests() int64 {
+	return atomic.LoadInt64(&ts.requests)
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(42)).Read(data)
+	return data
+}
+
+func TestReaderReadAt(t *testing.T) {
+	data := testData(1000)
+	ts := newTestServer(data, 0)
+	defer ts.Close()
+
+	r, err := New(ts.URL, WithBlockCache(100, 4))
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), r.Size())
+
+	tests := []struct {
+		off, length int64
+		requests    int64
+	}{
+		{off: 10, length: 20, requests: 1},   // block 0
+		{off: 50, length: 10, requests: 0},   // cached
+		{off: 90, length: 120, requests: 1},  // blocks 1 and 2 are fetched using one request
+		{off: 0, length: 300, requests: 0},   // blocks 0 to 2 are cached
+		{off: 950, length: 50, requests: 1},  // last block
+		{off: 250, length: 600, requests: 1}, // more blocks than the cache can hold are not cached
+		{off: 120, length: 10, requests: 0},
+		{off: 400, length: 50, requests: 1},
+		{off: 500, length: 50, requests: 1},  // block 0 is evicted
+		{off: 0, length: 50, requests: 1},    // block 0 is fetched again, block 2 is evicted
+		{off: 100, length: 150, requests: 1}, // block 1 is cached, block 2 is fetched
+	}
+
+	for _, tt := range tests {
+		ts.resetRequests()
+		buf := make([]byte, tt.length)
+		n, err := r.ReadAt(buf, tt.off)
+		require.NoError(t, err)
+		require.Equal(t, int(tt.length), n)
+		require.Equal(t, data[tt.off:tt.off+tt.length], buf)
+		require.Equal(t, tt.requests, ts.numRequests(), "requests for %d bytes at offset %d", tt.length, tt.off)
+	}
+
+	buf := make([]byte, 100)
+	n, err := r.ReadAt(buf, 980)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 20, n)
+	require.Equal(t, data[980:], buf[:n])
+
+	_, err = r.ReadAt(buf, 1000)
+	require.Equal(t, io.EOF, err)
+
+	_, err = r.Seek(-100, io.SeekEnd)
+	require.NoError(t, err)
+	rest, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data[900:], rest)
+}
+
+func TestReaderConcurrentReads(t *testing.T) {
+	data := testData(10000)
+	ts := newTestServer(data, 0)
+	defer ts.Close()
+
+	r, err := New(ts.URL, WithBlockCache(512, 8))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			off := int64(i * 400)
+			buf := make([]byte, 1500)
+			n, err := r.ReadAt(buf, off)
+			if err != nil && err != io.EOF {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(data[off:off+int64(n)], buf[:n]) {
+				errs <- fmt.Errorf("wrong data at offset %d", off)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestReaderRetries(t *testing.T) {
+	data := testData(100)
+
+	ts := newTestServer(data, 2)
+	defer ts.Close()
+	r, err := New(ts.URL, WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), r.Size())
+	require.Equal(t, int64(3), ts.numRequests())
+
+	ts = newTestServer(data, 3)
+	defer ts.Close()
+	_, err = New(ts.URL, WithRetries(2, time.Millisecond))
+	require.Error(t, err)
+	require.Equal(t, int64(3), ts.numRequests())
+
+	// client errors are not retried.
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	_, err = New(notFound.URL, WithRetries(2, time.Millisecond))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ts = newTestServer(data, 1)
+	defer ts.Close()
+	_, err = New(ts.URL, WithContext(ctx))
+	require.Error(t, err)
+}
+
+func TestReaderWithoutRangeSupport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(testData(100))
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "range requests")
+}
+
+type headerTransport struct {
+	header http.Header
+}
+
+func (h *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.header = req.Header.Clone()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestReaderClientAndHeader(t *testing.T) {
+	ts := newTestServer(testData(100), 0)
+	defer ts.Close()
+
+	transport := &headerTransport{}
+	_, err := New(ts.URL, WithClient(&http.Client{Transport: transport}), WithHeader("Authorization", "Bearer token"))
+	require.NoError(t, err)
+	require.Equal(t, "Bearer token", transport.header.Get("Authorization"))
+	require.Equal(t, "bytes=0-0", transport.header.Get("Range"))
+
+	_, err = New(ts.URL, WithClient(nil))
+	require.Error(t, err)
+	_, err = New(ts.URL, WithBlockCache(0, 1))
+	require.Error(t, err)
+	_, err = New(ts.URL, WithRetries(-1, 0))
+	require.Error(t, err)
+}
+
+func TestReadParquetFile(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := goparquet.NewFileWriter(&buf, goparquet.WithSchemaDefinition(sd))
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, fw.AddData(map[string]interface{}{
+			"id":   int64(i),
+			"name": []byte(fmt.Sprintf("name-%d", i)),
+		}))
+		if i%250 == 249 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	ts := newTestServer(buf.Bytes(), 0)
+	defer ts.Close()
+
+	r, err := New(ts.URL, WithBlockCache(1024, 4))
+	require.NoError(t, err)
+	defer r.Close()
+
+	// reading the meta data only requires the end of the file.
+	fr, err := goparquet.NewFileReaderAt(r, r.Size(), goparquet.WithCoalescedReads(0), goparquet.WithPrefetch(true))
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), fr.NumRows())
+	require.Equal(t, sd.String(), fr.GetSchemaDefinition().String())
+	require.LessOrEqual(t, ts.numRequests(), int64(3))
+
+	for i := 0; i < 1000; i++ {
+		row, err := fr.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, int64(i), row["id"])
+	}
+	_, err = fr.NextRow()
+	require.Equal(t, io.EOF, err)
+
+	fr, err = goparquet.NewFileReaderWithOptions(r, goparquet.WithColumnPaths(goparquet.ColumnPath{"name"}))
+	require.NoError(t, err)
+	row, err := fr.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": []byte("name-0")}, row)
+}
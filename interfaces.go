@@ -12,16 +12,30 @@ type pageReader interface {
 	init(dDecoder, rDecoder getLevelDecoder, values getValueDecoderFn) error
 	read(r io.Reader, ph *parquet.PageHeader, codec parquet.CompressionCodec, validateCRC bool) error
 
-	readValues(size int) (values []interface{}, dLevel *packedArray, rLevel *packedArray, err error)
+	// readLevels decodes the repetition and definition levels of all values of the page, and returns
+	// the number of non-null values.
+	readLevels() (dLevel *packedArray, rLevel *packedArray, notNull int, err error)
+
+	// readValues decodes the next n non-null values of the page.
+	readValues(n int) ([]interface{}, error)
+
+	// readTypedValues decodes the next len(dst) non-null values of the page into dst, which is a slice
+	// of the Go type of the values, e.g. []int64. It returns false if the values decoder of the page
+	// doesn't support this, in which case no values are decoded.
+	readTypedValues(dst interface{}) (bool, error)
+
+	// decompress decompresses the page data if it isn't decompressed yet. This is done when the page
+	// data is needed at the latest.
+	decompress() error
 
 	numValues() int32
 }
 
 // pageReader is an internal interface used only internally to read the pages
 type pageWriter interface {
-	init(col *Column, codec parquet.CompressionCodec) error
+	init(col *Column, codec parquet.CompressionCodec, level *int) error
 
-	write(ctx context.Context, w io.Writer) (int, int, error)
+	write(ctx context.Context, w io.Writer, encryptor *pageEncryptor) (int, int, error)
 }
 
 type newDataPageFunc func(useDict bool, dictValues []interface{}, page *dataPage, enableCRC bool) pageWriter
@@ -32,6 +46,12 @@ type valuesDecoder interface {
 	decodeValues([]interface{}) (int, error)
 }
 
+// typedValuesDecoder is implemented by values decoders that can decode values into a slice of their
+// Go type, e.g. []int64, which avoids boxing every value.
+type typedValuesDecoder interface {
+	decodeTypedValues(dst interface{}) (int, error)
+}
+
 type dictValuesDecoder interface {
 	valuesDecoder
 
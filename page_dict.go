@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"math"
+	"sort"
 
 	"github.com/fraugster/parquet-go/parquet"
 )
@@ -75,18 +77,25 @@ type dictPageWriter struct {
 	sch        *schema
 	col        *Column
 	codec      parquet.CompressionCodec
+	level      *int
 	dictValues []interface{}
+	sorted     bool
 }
 
-func (dp *dictPageWriter) init(sch *schema, col *Column, codec parquet.CompressionCodec, dictValues []interface{}) error {
+func (dp *dictPageWriter) init(sch *schema, col *Column, codec parquet.CompressionCodec, level *int, dictValues []interface{}) error {
 	dp.sch = sch
 	dp.col = col
 	dp.codec = codec
+	dp.level = level
 	dp.dictValues = dictValues
 	return nil
 }
 
 func (dp *dictPageWriter) getHeader(comp, unComp int, crc32Checksum *int32) *parquet.PageHeader {
+	var isSorted *bool
+	if dp.sorted {
+		isSorted = boolPtr(true)
+	}
 	ph := &parquet.PageHeader{
 		Type:                 parquet.PageType_DICTIONARY_PAGE,
 		UncompressedPageSize: int32(unComp),
@@ -95,13 +104,13 @@ func (dp *dictPageWriter) getHeader(comp, unComp int, crc32Checksum *int32) *par
 		DictionaryPageHeader: &parquet.DictionaryPageHeader{
 			NumValues: int32(len(dp.dictValues)),
 			Encoding:  parquet.Encoding_PLAIN, // PLAIN_DICTIONARY is deprecated in the Parquet 2.0 specification
-			IsSorted:  nil,
+			IsSorted:  isSorted,
 		},
 	}
 	return ph
 }
 
-func (dp *dictPageWriter) write(ctx context.Context, w io.Writer) (int, int, error) {
+func (dp *dictPageWriter) write(ctx context.Context, w io.Writer, encryptor *pageEncryptor) (int, int, error) {
 	// In V1 data page is compressed separately
 	dataBuf := &bytes.Buffer{}
 
@@ -115,10 +124,15 @@ func (dp *dictPageWriter) write(ctx context.Context, w io.Writer) (int, int, err
 		return 0, 0, err
 	}
 
-	comp, err := compressBlock(dataBuf.Bytes(), dp.codec)
+	comp, err := compressBlock(dataBuf.Bytes(), dp.codec, dp.level)
 	if err != nil {
 		return 0, 0, fmt.Errorf("compressing data failed with %s method: %w", dp.codec, err)
 	}
+
+	comp, err = encryptor.encryptPage(comp)
+	if err != nil {
+		return 0, 0, fmt.Errorf("encrypting dictionary page failed: %w", err)
+	}
 	compSize, unCompSize := len(comp), len(dataBuf.Bytes())
 
 	var crc32Checksum *int32
@@ -128,9 +142,64 @@ func (dp *dictPageWriter) write(ctx context.Context, w io.Writer) (int, int, err
 	}
 
 	header := dp.getHeader(compSize, unCompSize, crc32Checksum)
-	if err := writeThrift(ctx, header, w); err != nil {
+	if err := encryptor.writeHeader(ctx, header, w); err != nil {
 		return 0, 0, err
 	}
 
 	return compSize, unCompSize, writeFull(w, comp)
 }
+
+// sortDictValues sorts the dictionary values in place, using the sort order of the column
+// with the provided physical type. It returns the new index of each value, indexed by its
+// old index. INT96 and boolean values can't be sorted, in which case nil is returned.
+func sortDictValues(typ parquet.Type, order statsOrder, values []interface{}) []int32 {
+	switch typ {
+	case parquet.Type_INT96, parquet.Type_BOOLEAN:
+		return nil
+	}
+
+	perm := make([]int, len(values))
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(i, j int) bool {
+		return lessDictValue(order, values[perm[i]], values[perm[j]])
+	})
+
+	sorted := make([]interface{}, len(values))
+	remap := make([]int32, len(values))
+	for newIdx, oldIdx := range perm {
+		sorted[newIdx] = values[oldIdx]
+		remap[oldIdx] = int32(newIdx)
+	}
+	copy(values, sorted)
+
+	return remap
+}
+
+// lessDictValue reports whether a sorts before b. NaN values are sorted after all other values.
+func lessDictValue(order statsOrder, a, b interface{}) bool {
+	switch x := a.(type) {
+	case int32:
+		y := b.(int32)
+		if order == statsOrderUnsigned {
+			return uint32(x) < uint32(y)
+		}
+		return x < y
+	case int64:
+		y := b.(int64)
+		if order == statsOrderUnsigned {
+			return uint64(x) < uint64(y)
+		}
+		return x < y
+	case float32:
+		y := b.(float32)
+		return x < y || (math.IsNaN(float64(y)) && !math.IsNaN(float64(x)))
+	case float64:
+		y := b.(float64)
+		return x < y || (math.IsNaN(y) && !math.IsNaN(x))
+	case []byte:
+		return compareByteArrays(order, x, b.([]byte)) < 0
+	}
+	return false
+}
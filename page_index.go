@@ -0,0 +1,199 @@
+package goparquet
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// columnChunkIndex contains the page index of a single column chunk, i.e. the
+// column index with the per-page statistics and the offset index with the
+// location of every data page.
+type columnChunkIndex struct {
+	typ   parquet.Type
+	order statsOrder
+
+	// columnIndex is nil if not all non-null pages of the chunk came with
+	// min and max values, as the column index is useless in that case.
+	columnIndex *parquet.ColumnIndex
+	offsetIndex *parquet.OffsetIndex
+
+	// encryptor is nil if the column is not encrypted.
+	encryptor *columnChunkEncryptor
+}
+
+func newColumnChunkIndex(typ parquet.Type, order statsOrder) *columnChunkIndex {
+	return &columnChunkIndex{
+		typ:   typ,
+		order: order,
+		columnIndex: &parquet.ColumnIndex{
+			NullPages:  []bool{},
+			MinValues:  [][]byte{},
+			MaxValues:  [][]byte{},
+			NullCounts: []int64{},
+		},
+		offsetIndex: &parquet.OffsetIndex{
+			PageLocations: []*parquet.PageLocation{},
+		},
+	}
+}
+
+// addPage adds a data page to the index. offset is the position of the page
+// header in the file, size the size of the page including its header.
+func (idx *columnChunkIndex) addPage(offset int64, size int32, firstRowIndex int64, page *dataPage) {
+	idx.offsetIndex.PageLocations = append(idx.offsetIndex.PageLocations, &parquet.PageLocation{
+		Offset:             offset,
+		CompressedPageSize: size,
+		FirstRowIndex:      firstRowIndex,
+	})
+
+	if idx.columnIndex == nil {
+		return
+	}
+
+	nullPage := page.numValues == 0
+	minValue, maxValue := []byte{}, []byte{}
+	if !nullPage {
+		if page.stats == nil || page.stats.MinValue == nil || page.stats.MaxValue == nil {
+			idx.columnIndex = nil
+			return
+		}
+		minValue, maxValue = page.stats.MinValue, page.stats.MaxValue
+	}
+
+	idx.columnIndex.NullPages = append(idx.columnIndex.NullPages, nullPage)
+	idx.columnIndex.MinValues = append(idx.columnIndex.MinValues, minValue)
+	idx.columnIndex.MaxValues = append(idx.columnIndex.MaxValues, maxValue)
+	idx.columnIndex.NullCounts = append(idx.columnIndex.NullCounts, page.nullValues)
+}
+
+// finish determines the boundary order of the column index once all pages have been added.
+func (idx *columnChunkIndex) finish() {
+	if idx.columnIndex == nil {
+		return
+	}
+
+	ascending, descending := true, true
+	prev := -1
+	for i, nullPage := range idx.columnIndex.NullPages {
+		if nullPage {
+			continue
+		}
+		if prev >= 0 {
+			minCmp := compareStatsValues(idx.typ, idx.order, idx.columnIndex.MinValues[prev], idx.columnIndex.MinValues[i])
+			maxCmp := compareStatsValues(idx.typ, idx.order, idx.columnIndex.MaxValues[prev], idx.columnIndex.MaxValues[i])
+			if minCmp > 0 || maxCmp > 0 {
+				ascending = false
+			}
+			if minCmp < 0 || maxCmp < 0 {
+				descending = false
+			}
+		}
+		prev = i
+	}
+
+	switch {
+	case ascending:
+		idx.columnIndex.BoundaryOrder = parquet.BoundaryOrder_ASCENDING
+	case descending:
+		idx.columnIndex.BoundaryOrder = parquet.BoundaryOrder_DESCENDING
+	default:
+		idx.columnIndex.BoundaryOrder = parquet.BoundaryOrder_UNORDERED
+	}
+}
+
+// writePageIndexes writes the column indexes and the offset indexes of all row groups
+// and sets their offsets and lengths in the column chunk meta data. As recommended
+// by the parquet specification, all column indexes are written first, followed by
+// all offset indexes.
+func writePageIndexes(ctx context.Context, w writePos, rowGroups []*parquet.RowGroup, indexes [][]*columnChunkIndex) error {
+	for i, rg := range rowGroups {
+		for j, chunk := range rg.Columns {
+			if indexes[i][j].columnIndex == nil {
+				continue
+			}
+
+			pos := w.Pos()
+			if err := indexes[i][j].encryptor.writeThrift(ctx, moduleColumnIndex, indexes[i][j].columnIndex, w); err != nil {
+				return err
+			}
+			chunk.ColumnIndexOffset = int64Ptr(pos)
+			chunk.ColumnIndexLength = int32Ptr(int32(w.Pos() - pos))
+		}
+	}
+
+	for i, rg := range rowGroups {
+		for j, chunk := range rg.Columns {
+			pos := w.Pos()
+			if err := indexes[i][j].encryptor.writeThrift(ctx, moduleOffsetIndex, indexes[i][j].offsetIndex, w); err != nil {
+				return err
+			}
+			chunk.OffsetIndexOffset = int64Ptr(pos)
+			chunk.OffsetIndexLength = int32Ptr(int32(w.Pos() - pos))
+		}
+	}
+
+	return nil
+}
+
+// readOffsetIndex reads the offset index of a column chunk. If the column chunk has no offset index,
+// nil is returned.
+func (f *FileReader) readOffsetIndex(ctx context.Context, r io.ReadSeeker, chunk *parquet.ColumnChunk, decryptor *columnChunkDecryptor) (*parquet.OffsetIndex, error) {
+	if chunk.OffsetIndexOffset == nil || chunk.OffsetIndexLength == nil {
+		return nil, nil
+	}
+
+	offsetIndex := &parquet.OffsetIndex{}
+	if err := f.readIndex(ctx, r, offsetIndex, *chunk.OffsetIndexOffset, *chunk.OffsetIndexLength, decryptor, moduleOffsetIndex); err != nil {
+		return nil, fmt.Errorf("reading offset index failed: %w", err)
+	}
+
+	return offsetIndex, nil
+}
+
+// readColumnIndex reads the column index of a column chunk. If the column chunk has no column index,
+// nil is returned.
+func (f *FileReader) readColumnIndex(ctx context.Context, r io.ReadSeeker, chunk *parquet.ColumnChunk, decryptor *columnChunkDecryptor) (*parquet.ColumnIndex, error) {
+	if chunk.ColumnIndexOffset == nil || chunk.ColumnIndexLength == nil {
+		return nil, nil
+	}
+
+	columnIndex := &parquet.ColumnIndex{}
+	if err := f.readIndex(ctx, r, columnIndex, *chunk.ColumnIndexOffset, *chunk.ColumnIndexLength, decryptor, moduleColumnIndex); err != nil {
+		return nil, fmt.Errorf("reading column index failed: %w", err)
+	}
+
+	return columnIndex, nil
+}
+
+func (f *FileReader) readIndex(ctx context.Context, r io.ReadSeeker, tr thriftReader, offset int64, length int32, decryptor *columnChunkDecryptor, moduleType byte) error {
+	if offset < 0 || length <= 0 {
+		return fmt.Errorf("invalid index offset %d or length %d", offset, length)
+	}
+
+	f.allocTracker.test(uint64(length))
+
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	return decryptor.readThrift(ctx, moduleType, tr, io.LimitReader(r, int64(length)), f.allocTracker)
+}
+
+// findPageLocation returns the location of the page that contains the row with the provided index,
+// as well as the page's ordinal within the column chunk.
+func findPageLocation(offsetIndex *parquet.OffsetIndex, row int64) (*parquet.PageLocation, int) {
+	var (
+		loc     *parquet.PageLocation
+		ordinal int
+	)
+	for i, l := range offsetIndex.PageLocations {
+		if l.FirstRowIndex > row {
+			break
+		}
+		loc, ordinal = l, i
+	}
+	return loc, ordinal
+}
@@ -0,0 +1,248 @@
+package goparquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func readThriftAt(t *testing.T, data []byte, offset int64, length int32, tr thriftReader) {
+	require.NoError(t, readThrift(context.Background(), tr, io.NewSectionReader(bytes.NewReader(data), offset, int64(length))))
+}
+
+func TestWritePageIndex(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional int32 opt;
+		required boolean flag;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithMaxPageSize(32), WithPageIndex(true))
+
+	const numRows = 1000
+	for i := 0; i < numRows; i++ {
+		data := map[string]interface{}{"id": int64(i), "flag": i%2 == 0}
+		if i >= 500 {
+			data["opt"] = int32(numRows - i)
+		}
+		require.NoError(t, fw.AddData(data))
+	}
+	require.NoError(t, fw.Close())
+
+	data := buf.Bytes()
+
+	meta, err := ReadFileMetaData(bytes.NewReader(data), true)
+	require.NoError(t, err)
+	require.Len(t, meta.RowGroups, 1)
+
+	chunks := meta.RowGroups[0].Columns
+	require.Len(t, chunks, 3)
+
+	for i, chunk := range chunks {
+		require.NotNil(t, chunk.OffsetIndexOffset)
+		require.NotNil(t, chunk.OffsetIndexLength)
+
+		offsetIndex := &parquet.OffsetIndex{}
+		readThriftAt(t, data, *chunk.OffsetIndexOffset, *chunk.OffsetIndexLength, offsetIndex)
+		if i < 2 {
+			require.True(t, len(offsetIndex.PageLocations) > 1, "expected more than one page in column %d", i)
+		}
+		require.Equal(t, int64(0), offsetIndex.PageLocations[0].FirstRowIndex)
+		require.Equal(t, chunk.MetaData.DataPageOffset, offsetIndex.PageLocations[0].Offset)
+
+		for j, loc := range offsetIndex.PageLocations {
+			ph := &parquet.PageHeader{}
+			readThriftAt(t, data, loc.Offset, loc.CompressedPageSize, ph)
+			require.True(t, ph.Type == parquet.PageType_DATA_PAGE, "page %d is not a data page", j)
+			if j > 0 {
+				require.True(t, loc.FirstRowIndex > offsetIndex.PageLocations[j-1].FirstRowIndex)
+			}
+		}
+	}
+
+	// boolean columns come without statistics, so no column index can be written.
+	require.Nil(t, chunks[2].ColumnIndexOffset)
+	require.Nil(t, chunks[2].ColumnIndexLength)
+
+	idIndex := &parquet.ColumnIndex{}
+	readThriftAt(t, data, *chunks[0].ColumnIndexOffset, *chunks[0].ColumnIndexLength, idIndex)
+	require.Equal(t, parquet.BoundaryOrder_ASCENDING, idIndex.BoundaryOrder)
+	require.Equal(t, int64(0), int64(binary.LittleEndian.Uint64(idIndex.MinValues[0])))
+	require.Equal(t, int64(numRows-1), int64(binary.LittleEndian.Uint64(idIndex.MaxValues[len(idIndex.MaxValues)-1])))
+	for _, nullPage := range idIndex.NullPages {
+		require.False(t, nullPage)
+	}
+
+	optIndex := &parquet.ColumnIndex{}
+	readThriftAt(t, data, *chunks[1].ColumnIndexOffset, *chunks[1].ColumnIndexLength, optIndex)
+	require.Equal(t, parquet.BoundaryOrder_DESCENDING, optIndex.BoundaryOrder)
+	require.True(t, optIndex.NullPages[0])
+	require.Equal(t, []byte{}, optIndex.MinValues[0])
+	require.False(t, optIndex.NullPages[len(optIndex.NullPages)-1])
+
+	var nullCount int64
+	for _, n := range optIndex.NullCounts {
+		nullCount += n
+	}
+	require.Equal(t, int64(500), nullCount)
+}
+
+func TestWriteWithoutPageIndex(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+	require.NoError(t, fw.AddData(map[string]interface{}{"id": int64(23)}))
+	require.NoError(t, fw.Close())
+
+	meta, err := ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+
+	chunk := meta.RowGroups[0].Columns[0]
+	require.Nil(t, chunk.ColumnIndexOffset)
+	require.Nil(t, chunk.OffsetIndexOffset)
+}
+
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int64
+}
+
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := c.ReadSeeker.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestSeekToRow(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		repeated int32 numbers;
+	}`)
+	require.NoError(t, err)
+
+	const numRows = 3000
+
+	records := make([]map[string]interface{}, 0, numRows)
+	for i := 0; i < numRows; i++ {
+		rec := map[string]interface{}{"id": int64(i)}
+		if i%3 != 0 {
+			rec["name"] = []byte(fmt.Sprintf("name %d", i))
+		}
+		if i%5 != 0 {
+			rec["numbers"] = []int32{int32(i), int32(i % 7), int32(i % 11)}
+		}
+		records = append(records, rec)
+	}
+
+	writeFile := func(opts ...FileWriterOption) []byte {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, append([]FileWriterOption{WithSchemaDefinition(sd), WithMaxPageSize(256)}, opts...)...)
+		for i, rec := range records {
+			require.NoError(t, fw.AddData(rec))
+			if i%1000 == 999 {
+				require.NoError(t, fw.FlushRowGroup())
+			}
+		}
+		require.NoError(t, fw.Close())
+		return buf.Bytes()
+	}
+
+	withIndex := writeFile(WithPageIndex(true))
+	withoutIndex := writeFile()
+
+	readRows := func(data []byte, row int64, count int) int64 {
+		rs := &countingReadSeeker{ReadSeeker: bytes.NewReader(data)}
+		r, err := NewFileReader(rs)
+		require.NoError(t, err)
+		rs.n = 0
+
+		require.NoError(t, r.SeekToRow(row))
+		for i := int64(0); i < int64(count); i++ {
+			rec, err := r.NextRow()
+			require.NoError(t, err)
+			require.Equal(t, records[row+i], rec, "row %d doesn't match", row+i)
+		}
+		return rs.n
+	}
+
+	for _, row := range []int64{0, 1, 499, 999, 1000, 1750, 2999} {
+		count := 10
+		if rem := int(numRows - row); rem < count {
+			count = rem
+		}
+		bytesWithIndex := readRows(withIndex, row, count)
+		bytesWithoutIndex := readRows(withoutIndex, row, count)
+		if row%1000 > 500 {
+			require.True(t, bytesWithIndex < bytesWithoutIndex, "row %d: expected to read less data with page index, got %d vs %d", row, bytesWithIndex, bytesWithoutIndex)
+		}
+	}
+
+	r, err := NewFileReader(bytes.NewReader(withIndex))
+	require.NoError(t, err)
+	require.True(t, errors.Is(r.SeekToRow(numRows), io.EOF))
+	require.Error(t, r.SeekToRow(-1))
+}
+
+func TestFileReaderPageIndex(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+	}`)
+	require.NoError(t, err)
+
+	writeFile := func(opts ...FileWriterOption) []byte {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, append([]FileWriterOption{WithSchemaDefinition(sd), WithMaxPageSize(64)}, opts...)...)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, fw.AddData(map[string]interface{}{"id": int64(i)}))
+		}
+		require.NoError(t, fw.Close())
+		return buf.Bytes()
+	}
+
+	r, err := NewFileReader(bytes.NewReader(writeFile(WithPageIndex(true))))
+	require.NoError(t, err)
+
+	columnIndex, err := r.ColumnIndex(0, ColumnPath{"id"})
+	require.NoError(t, err)
+	require.NotNil(t, columnIndex)
+	require.Equal(t, parquet.BoundaryOrder_ASCENDING, columnIndex.BoundaryOrder)
+
+	offsetIndex, err := r.OffsetIndex(0, ColumnPath{"id"})
+	require.NoError(t, err)
+	require.NotNil(t, offsetIndex)
+	require.Len(t, offsetIndex.PageLocations, len(columnIndex.NullPages))
+
+	_, err = r.ColumnIndex(1, ColumnPath{"id"})
+	require.Error(t, err)
+
+	_, err = r.OffsetIndex(0, ColumnPath{"foo"})
+	require.Error(t, err)
+
+	r, err = NewFileReader(bytes.NewReader(writeFile()))
+	require.NoError(t, err)
+
+	columnIndex, err = r.ColumnIndex(0, ColumnPath{"id"})
+	require.NoError(t, err)
+	require.Nil(t, columnIndex)
+
+	offsetIndex, err = r.OffsetIndex(0, ColumnPath{"id"})
+	require.NoError(t, err)
+	require.Nil(t, offsetIndex)
+}
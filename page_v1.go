@@ -21,7 +21,9 @@ type dataPageReaderV1 struct {
 	valuesDecoder      valuesDecoder
 	fn                 getValueDecoderFn
 
-	position int
+	// block is the compressed page data until the page is decompressed.
+	block []byte
+	codec parquet.CompressionCodec
 
 	alloc *allocTracker
 }
@@ -30,36 +32,46 @@ func (dp *dataPageReaderV1) numValues() int32 {
 	return dp.valuesCount
 }
 
-func (dp *dataPageReaderV1) readValues(size int) (values []interface{}, dLevel *packedArray, rLevel *packedArray, err error) {
-	if rem := int(dp.valuesCount) - dp.position; rem < size {
-		size = rem
+func (dp *dataPageReaderV1) readLevels() (dLevel *packedArray, rLevel *packedArray, notNull int, err error) {
+	if err := dp.decompress(); err != nil {
+		return nil, nil, 0, err
 	}
 
-	if size == 0 {
-		return nil, nil, nil, nil
-	}
-
-	rLevel, _, err = decodePackedArray(dp.rDecoder, size)
+	rLevel, _, err = decodePackedArray(dp.rDecoder, int(dp.valuesCount))
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("read repetition levels failed: %w", err)
+		return nil, nil, 0, fmt.Errorf("read repetition levels failed: %w", err)
 	}
 
-	var notNull int
-	dLevel, notNull, err = decodePackedArray(dp.dDecoder, size)
+	dLevel, notNull, err = decodePackedArray(dp.dDecoder, int(dp.valuesCount))
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("read definition levels failed: %w", err)
+		return nil, nil, 0, fmt.Errorf("read definition levels failed: %w", err)
 	}
 
-	val := make([]interface{}, notNull)
+	return dLevel, rLevel, notNull, nil
+}
+
+func (dp *dataPageReaderV1) readValues(n int) ([]interface{}, error) {
+	val := make([]interface{}, n)
 
-	if notNull != 0 {
-		if n, err := dp.valuesDecoder.decodeValues(val); err != nil {
-			return nil, nil, nil, fmt.Errorf("read values from page failed, need %d value read %d: %w", notNull, n, err)
+	if n != 0 {
+		if read, err := dp.valuesDecoder.decodeValues(val); err != nil {
+			return nil, fmt.Errorf("read values from page failed, need %d value read %d: %w", n, read, err)
 		}
 	}
-	dp.position += size
 
-	return val, dLevel, rLevel, nil
+	return val, nil
+}
+
+func (dp *dataPageReaderV1) readTypedValues(dst interface{}) (bool, error) {
+	dec, ok := dp.valuesDecoder.(typedValuesDecoder)
+	if !ok || dst == nil {
+		return false, nil
+	}
+
+	if n, err := dec.decodeTypedValues(dst); err != nil {
+		return false, fmt.Errorf("read values from page failed, need %d values but read %d: %w", typedLen(dst), n, err)
+	}
+	return true, nil
 }
 
 func (dp *dataPageReaderV1) init(dDecoder, rDecoder getLevelDecoder, values getValueDecoderFn) error {
@@ -79,7 +91,6 @@ func (dp *dataPageReaderV1) init(dDecoder, rDecoder getLevelDecoder, values getV
 	}
 
 	dp.fn = values
-	dp.position = 0
 
 	return nil
 }
@@ -93,22 +104,30 @@ func (dp *dataPageReaderV1) read(r io.Reader, ph *parquet.PageHeader, codec parq
 		return fmt.Errorf("negative NumValues in DATA_PAGE: %d", dp.valuesCount)
 	}
 
-	dataPageBlock, err := readPageBlock(r, codec, ph.GetCompressedPageSize(), ph.GetUncompressedPageSize(), validateCRC, ph.Crc, dp.alloc)
-	if err != nil {
-		return err
-	}
-
-	reader, err := newBlockReader(dataPageBlock, codec, ph.GetCompressedPageSize(), ph.GetUncompressedPageSize(), dp.alloc)
-	if err != nil {
+	if dp.block, err = readPageBlock(r, codec, ph.GetCompressedPageSize(), ph.GetUncompressedPageSize(), validateCRC, ph.Crc, dp.alloc); err != nil {
 		return err
 	}
+	dp.codec = codec
 
 	dp.encoding = ph.DataPageHeader.Encoding
 	dp.ph = ph
 
-	if dp.valuesDecoder, err = dp.fn(dp.encoding); err != nil {
+	dp.valuesDecoder, err = dp.fn(dp.encoding)
+	return err
+}
+
+// decompress decompresses the page data, which contains both the levels and the values of a
+// DATA_PAGE, and initializes the decoders. It does nothing if the page is already decompressed.
+func (dp *dataPageReaderV1) decompress() error {
+	if dp.block == nil {
+		return nil
+	}
+
+	reader, err := newBlockReader(dp.block, dp.codec, dp.ph.GetCompressedPageSize(), dp.ph.GetUncompressedPageSize(), dp.alloc)
+	if err != nil {
 		return err
 	}
+	dp.block = nil
 
 	if err := dp.rDecoder.initSize(reader); err != nil {
 		return err
@@ -125,15 +144,17 @@ type dataPageWriterV1 struct {
 	dictValues []interface{}
 	col        *Column
 	codec      parquet.CompressionCodec
+	level      *int
 	page       *dataPage
 
 	dictionary bool
 	enableCRC  bool
 }
 
-func (dp *dataPageWriterV1) init(col *Column, codec parquet.CompressionCodec) error {
+func (dp *dataPageWriterV1) init(col *Column, codec parquet.CompressionCodec, level *int) error {
 	dp.col = col
 	dp.codec = codec
+	dp.level = level
 	return nil
 }
 
@@ -159,7 +180,7 @@ func (dp *dataPageWriterV1) getHeader(comp, unComp int, pageStats *parquet.Stati
 	return ph
 }
 
-func (dp *dataPageWriterV1) write(ctx context.Context, w io.Writer) (int, int, error) {
+func (dp *dataPageWriterV1) write(ctx context.Context, w io.Writer, encryptor *pageEncryptor) (int, int, error) {
 	dataBuf := &bytes.Buffer{}
 	// Only write repetition value higher than zero
 	if dp.col.MaxRepetitionLevel() > 0 {
@@ -201,10 +222,15 @@ func (dp *dataPageWriterV1) write(ctx context.Context, w io.Writer) (int, int, e
 		}
 	}
 
-	comp, err := compressBlock(dataBuf.Bytes(), dp.codec)
+	comp, err := compressBlock(dataBuf.Bytes(), dp.codec, dp.level)
 	if err != nil {
 		return 0, 0, fmt.Errorf("compressing data failed with %s method: %w", dp.codec, err)
 	}
+
+	comp, err = encryptor.encryptPage(comp)
+	if err != nil {
+		return 0, 0, fmt.Errorf("encrypting data page failed: %w", err)
+	}
 	compSize, unCompSize := len(comp), len(dataBuf.Bytes())
 
 	var crc32Checksum *int32
@@ -214,7 +240,7 @@ func (dp *dataPageWriterV1) write(ctx context.Context, w io.Writer) (int, int, e
 	}
 
 	header := dp.getHeader(compSize, unCompSize, dp.page.stats, crc32Checksum)
-	if err := writeThrift(ctx, header, w); err != nil {
+	if err := encryptor.writeHeader(ctx, header, w); err != nil {
 		return 0, 0, err
 	}
 
@@ -19,7 +19,10 @@ type dataPageReaderV2 struct {
 	valuesDecoder      valuesDecoder
 	dDecoder, rDecoder levelDecoder
 	fn                 getValueDecoderFn
-	position           int
+
+	// block is the compressed values section of the page until the page is decompressed.
+	block []byte
+	codec parquet.CompressionCodec
 
 	alloc *allocTracker
 }
@@ -28,35 +31,49 @@ func (dp *dataPageReaderV2) numValues() int32 {
 	return dp.valuesCount
 }
 
-func (dp *dataPageReaderV2) readValues(size int) (values []interface{}, dLevel *packedArray, rLevel *packedArray, err error) {
-	if rem := int(dp.valuesCount) - dp.position; rem < size {
-		size = rem
-	}
-
-	if size == 0 {
-		return nil, nil, nil, nil
+func (dp *dataPageReaderV2) readLevels() (dLevel *packedArray, rLevel *packedArray, notNull int, err error) {
+	rLevel, _, err = decodePackedArray(dp.rDecoder, int(dp.valuesCount))
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("read repetition levels failed: %w", err)
 	}
 
-	rLevel, _, err = decodePackedArray(dp.rDecoder, size)
+	dLevel, notNull, err = decodePackedArray(dp.dDecoder, int(dp.valuesCount))
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("read repetition levels failed: %w", err)
+		return nil, nil, 0, fmt.Errorf("read definition levels failed: %w", err)
 	}
 
-	var notNull int
-	dLevel, notNull, err = decodePackedArray(dp.dDecoder, size)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("read definition levels failed: %w", err)
+	return dLevel, rLevel, notNull, nil
+}
+
+func (dp *dataPageReaderV2) readValues(n int) ([]interface{}, error) {
+	if err := dp.decompress(); err != nil {
+		return nil, err
 	}
 
-	val := make([]interface{}, notNull)
+	val := make([]interface{}, n)
 
-	if notNull != 0 {
-		if n, err := dp.valuesDecoder.decodeValues(val); err != nil {
-			return nil, nil, nil, fmt.Errorf("read values from page failed, need %d values but read %d: %w", notNull, n, err)
+	if n != 0 {
+		if read, err := dp.valuesDecoder.decodeValues(val); err != nil {
+			return nil, fmt.Errorf("read values from page failed, need %d values but read %d: %w", n, read, err)
 		}
 	}
-	dp.position += size
-	return val, dLevel, rLevel, nil
+	return val, nil
+}
+
+func (dp *dataPageReaderV2) readTypedValues(dst interface{}) (bool, error) {
+	dec, ok := dp.valuesDecoder.(typedValuesDecoder)
+	if !ok || dst == nil {
+		return false, nil
+	}
+
+	if err := dp.decompress(); err != nil {
+		return false, err
+	}
+
+	if n, err := dec.decodeTypedValues(dst); err != nil {
+		return false, fmt.Errorf("read values from page failed, need %d values but read %d: %w", typedLen(dst), n, err)
+	}
+	return true, nil
 }
 
 func (dp *dataPageReaderV2) init(dDecoder, rDecoder getLevelDecoder, values getValueDecoderFn) error {
@@ -71,7 +88,6 @@ func (dp *dataPageReaderV2) init(dDecoder, rDecoder getLevelDecoder, values getV
 		return err
 	}
 	dp.fn = values
-	dp.position = 0
 
 	return nil
 }
@@ -122,10 +138,25 @@ func (dp *dataPageReaderV2) read(r io.Reader, ph *parquet.PageHeader, codec parq
 		}
 	}
 
-	reader, err := newBlockReader(dataPageBlock[levelsSize:], codec, ph.GetCompressedPageSize()-levelsSize, ph.GetUncompressedPageSize()-levelsSize, dp.alloc)
+	// the levels are not compressed, so only the values need to be decompressed before they are read.
+	dp.block, dp.codec = dataPageBlock[levelsSize:], codec
+
+	return nil
+}
+
+// decompress decompresses the values of the page and initializes their decoder. It does nothing
+// if the page is already decompressed.
+func (dp *dataPageReaderV2) decompress() error {
+	if dp.block == nil {
+		return nil
+	}
+
+	levelsSize := dp.ph.DataPageHeaderV2.RepetitionLevelsByteLength + dp.ph.DataPageHeaderV2.DefinitionLevelsByteLength
+	reader, err := newBlockReader(dp.block, dp.codec, dp.ph.GetCompressedPageSize()-levelsSize, dp.ph.GetUncompressedPageSize()-levelsSize, dp.alloc)
 	if err != nil {
 		return err
 	}
+	dp.block = nil
 
 	return dp.valuesDecoder.init(reader)
 }
@@ -134,15 +165,17 @@ type dataPageWriterV2 struct {
 	dictValues []interface{}
 	col        *Column
 	codec      parquet.CompressionCodec
+	level      *int
 	page       *dataPage
 
 	dictionary bool
 	enableCRC  bool
 }
 
-func (dp *dataPageWriterV2) init(col *Column, codec parquet.CompressionCodec) error {
+func (dp *dataPageWriterV2) init(col *Column, codec parquet.CompressionCodec, level *int) error {
 	dp.col = col
 	dp.codec = codec
+	dp.level = level
 	return nil
 }
 
@@ -170,7 +203,7 @@ func (dp *dataPageWriterV2) getHeader(comp, unComp, defSize, repSize int, isComp
 	return ph
 }
 
-func (dp *dataPageWriterV2) write(ctx context.Context, w io.Writer) (int, int, error) {
+func (dp *dataPageWriterV2) write(ctx context.Context, w io.Writer, encryptor *pageEncryptor) (int, int, error) {
 	rep := &bytes.Buffer{}
 
 	// Only write repetition value higher than zero
@@ -216,33 +249,32 @@ func (dp *dataPageWriterV2) write(ctx context.Context, w io.Writer) (int, int, e
 		}
 	}
 
-	comp, err := compressBlock(dataBuf.Bytes(), dp.codec)
+	comp, err := compressBlock(dataBuf.Bytes(), dp.codec, dp.level)
 	if err != nil {
 		return 0, 0, fmt.Errorf("compressing data failed with %s method: %w", dp.codec, err)
 	}
 
+	// the levels are not compressed, but they are encrypted together with the values.
+	body := append(append(rep.Bytes(), def.Bytes()...), comp...)
+	body, err = encryptor.encryptPage(body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("encrypting data page failed: %w", err)
+	}
+
 	var crc32Checksum *int32
 	if dp.enableCRC {
-		v := int32(crc32.ChecksumIEEE(append(append(rep.Bytes(), def.Bytes()...), comp...)))
+		v := int32(crc32.ChecksumIEEE(body))
 		crc32Checksum = &v
 	}
 
-	compSize, unCompSize := len(comp), len(dataBuf.Bytes())
 	defLen, repLen := def.Len(), rep.Len()
+	compSize, unCompSize := len(body)-defLen-repLen, len(dataBuf.Bytes())
 	header := dp.getHeader(compSize, unCompSize, defLen, repLen, dp.codec != parquet.CompressionCodec_UNCOMPRESSED, dp.page.stats, int32(dp.page.numRows), crc32Checksum)
-	if err := writeThrift(ctx, header, w); err != nil {
-		return 0, 0, err
-	}
-
-	if err := writeFull(w, rep.Bytes()); err != nil {
-		return 0, 0, err
-	}
-
-	if err := writeFull(w, def.Bytes()); err != nil {
+	if err := encryptor.writeHeader(ctx, header, w); err != nil {
 		return 0, 0, err
 	}
 
-	return compSize + defLen + repLen, unCompSize + defLen + repLen, writeFull(w, comp)
+	return len(body), unCompSize + defLen + repLen, writeFull(w, body)
 }
 
 func newDataPageV2Writer(useDict bool, dictValues []interface{}, page *dataPage, enableCRC bool) pageWriter {
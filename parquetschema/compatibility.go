@@ -0,0 +1,398 @@
+package parquetschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// CompatibilityMode defines which kind of compatibility CheckCompatibility checks for.
+type CompatibilityMode int
+
+const (
+	// BackwardCompatible checks whether data written using the old schema can be read using the new schema.
+	BackwardCompatible CompatibilityMode = iota + 1
+	// ForwardCompatible checks whether data written using the new schema can be read using the old schema.
+	ForwardCompatible
+	// FullyCompatible checks whether the schemas are both backward and forward compatible.
+	FullyCompatible
+)
+
+func (m CompatibilityMode) String() string {
+	switch m {
+	case BackwardCompatible:
+		return "BACKWARD"
+	case ForwardCompatible:
+		return "FORWARD"
+	case FullyCompatible:
+		return "FULL"
+	}
+	return fmt.Sprintf("CompatibilityMode(%d)", int(m))
+}
+
+func (m CompatibilityMode) backward() bool {
+	return m == BackwardCompatible || m == FullyCompatible
+}
+
+func (m CompatibilityMode) forward() bool {
+	return m == ForwardCompatible || m == FullyCompatible
+}
+
+// ViolationType describes the kind of an incompatible change between two schemas.
+type ViolationType int
+
+const (
+	// RequiredFieldAdded is reported if a required field was added. Data written using
+	// the old schema doesn't contain the field, so it can't be read using the new schema.
+	RequiredFieldAdded ViolationType = iota + 1
+	// RequiredFieldRemoved is reported if a required field was removed. Data written using
+	// the new schema doesn't contain the field, so it can't be read using the old schema.
+	RequiredFieldRemoved
+	// FieldKindChanged is reported if a group was changed to a primitive field or vice versa.
+	FieldKindChanged
+	// PhysicalTypeChanged is reported if the physical type of a field was changed, and the
+	// values can't be promoted to the type of the reading schema.
+	PhysicalTypeChanged
+	// LogicalTypeChanged is reported if the logical type or converted type of a field
+	// was changed in an incompatible way.
+	LogicalTypeChanged
+	// RepetitionTightened is reported if an optional field was changed to a required field.
+	RepetitionTightened
+	// RepetitionRelaxed is reported if a required field was changed to an optional field.
+	RepetitionRelaxed
+	// RepetitionChanged is reported if a field was changed from or to a repeated field.
+	RepetitionChanged
+)
+
+func (t ViolationType) String() string {
+	switch t {
+	case RequiredFieldAdded:
+		return "REQUIRED_FIELD_ADDED"
+	case RequiredFieldRemoved:
+		return "REQUIRED_FIELD_REMOVED"
+	case FieldKindChanged:
+		return "FIELD_KIND_CHANGED"
+	case PhysicalTypeChanged:
+		return "PHYSICAL_TYPE_CHANGED"
+	case LogicalTypeChanged:
+		return "LOGICAL_TYPE_CHANGED"
+	case RepetitionTightened:
+		return "REPETITION_TIGHTENED"
+	case RepetitionRelaxed:
+		return "REPETITION_RELAXED"
+	case RepetitionChanged:
+		return "REPETITION_CHANGED"
+	}
+	return fmt.Sprintf("ViolationType(%d)", int(t))
+}
+
+// Violation describes an incompatible change of a field between two schemas.
+type Violation struct {
+	// Path is the path of the field.
+	Path []string
+	// Type is the kind of the incompatible change.
+	Type ViolationType
+	// Mode is the compatibility that is violated by the change, either BackwardCompatible
+	// or ForwardCompatible. Changes that violate both are reported once per compatibility.
+	Mode CompatibilityMode
+	// Description is a human-readable description of the change.
+	Description string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", strings.Join(v.Path, "."), v.Description, v.Mode)
+}
+
+// CheckCompatibility checks whether the changes from the old schema to the new schema are compatible
+// in the provided mode, and returns all incompatible changes. If no violations are returned, the schemas
+// are compatible. Fields are matched by name. The rules are the same as the rules used when reading
+// files using a different schema, i.e. optional fields can be added and removed, required fields can
+// be changed to optional fields when reading, and INT32 can be promoted to INT64 and FLOAT to DOUBLE.
+func CheckCompatibility(oldSchema, newSchema *SchemaDefinition, mode CompatibilityMode) ([]Violation, error) {
+	if oldSchema == nil || oldSchema.RootColumn == nil {
+		return nil, errors.New("old schema definition is empty")
+	}
+	if newSchema == nil || newSchema.RootColumn == nil {
+		return nil, errors.New("new schema definition is empty")
+	}
+	if !mode.backward() && !mode.forward() {
+		return nil, fmt.Errorf("invalid compatibility mode %s", mode)
+	}
+
+	c := &compatibilityChecker{mode: mode}
+	c.checkChildren(nil, oldSchema.RootColumn.Children, newSchema.RootColumn.Children)
+	return c.violations, nil
+}
+
+type compatibilityChecker struct {
+	mode       CompatibilityMode
+	violations []Violation
+}
+
+func (c *compatibilityChecker) report(path []string, typ ViolationType, mode CompatibilityMode, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{
+		Path:        path,
+		Type:        typ,
+		Mode:        mode,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *compatibilityChecker) checkChildren(parent []string, oldCols, newCols []*ColumnDefinition) {
+	for _, oldCol := range oldCols {
+		path := append(parent[:len(parent):len(parent)], oldCol.SchemaElement.GetName())
+		newCol := findChild(newCols, oldCol.SchemaElement.GetName())
+		if newCol == nil {
+			if c.mode.forward() && oldCol.SchemaElement.GetRepetitionType() == parquet.FieldRepetitionType_REQUIRED {
+				c.report(path, RequiredFieldRemoved, ForwardCompatible, "required field was removed")
+			}
+			continue
+		}
+		c.checkColumn(path, oldCol, newCol)
+	}
+
+	for _, newCol := range newCols {
+		if findChild(oldCols, newCol.SchemaElement.GetName()) != nil {
+			continue
+		}
+		if c.mode.backward() && newCol.SchemaElement.GetRepetitionType() == parquet.FieldRepetitionType_REQUIRED {
+			c.report(append(parent[:len(parent):len(parent)], newCol.SchemaElement.GetName()), RequiredFieldAdded, BackwardCompatible, "required field was added")
+		}
+	}
+}
+
+func (c *compatibilityChecker) checkColumn(path []string, oldCol, newCol *ColumnDefinition) {
+	oldElem, newElem := oldCol.SchemaElement, newCol.SchemaElement
+
+	oldGroup, newGroup := len(oldCol.Children) > 0, len(newCol.Children) > 0
+	if oldGroup != newGroup {
+		for _, mode := range c.modes() {
+			c.report(path, FieldKindChanged, mode, "%s was changed to %s", fieldKind(oldGroup), fieldKind(newGroup))
+		}
+		return
+	}
+
+	c.checkRepetition(path, oldElem.GetRepetitionType(), newElem.GetRepetitionType())
+
+	if oldGroup {
+		if !sameAnnotation(oldElem, newElem) {
+			for _, mode := range c.modes() {
+				c.report(path, LogicalTypeChanged, mode, "group type %s was changed to %s", annotationString(oldElem), annotationString(newElem))
+			}
+		}
+		c.checkChildren(path, oldCol.Children, newCol.Children)
+		return
+	}
+
+	if sameType(oldElem, newElem) {
+		if !sameAnnotation(oldElem, newElem) {
+			for _, mode := range c.modes() {
+				c.report(path, LogicalTypeChanged, mode, "type %s was changed to %s", typeString(oldElem), typeString(newElem))
+			}
+		}
+		return
+	}
+
+	// data written using the old schema is read using the new schema and vice versa.
+	if c.mode.backward() && !CanPromote(oldElem, newElem) {
+		c.report(path, PhysicalTypeChanged, BackwardCompatible, "type %s can't be read as %s", typeString(oldElem), typeString(newElem))
+	}
+	if c.mode.forward() && !CanPromote(newElem, oldElem) {
+		c.report(path, PhysicalTypeChanged, ForwardCompatible, "type %s can't be read as %s", typeString(newElem), typeString(oldElem))
+	}
+}
+
+func (c *compatibilityChecker) checkRepetition(path []string, oldRep, newRep parquet.FieldRepetitionType) {
+	if oldRep == newRep {
+		return
+	}
+
+	if oldRep == parquet.FieldRepetitionType_REPEATED || newRep == parquet.FieldRepetitionType_REPEATED {
+		for _, mode := range c.modes() {
+			c.report(path, RepetitionChanged, mode, "repetition type %s was changed to %s", oldRep, newRep)
+		}
+		return
+	}
+
+	if oldRep == parquet.FieldRepetitionType_OPTIONAL && c.mode.backward() {
+		c.report(path, RepetitionTightened, BackwardCompatible, "optional field was changed to required")
+	}
+	if oldRep == parquet.FieldRepetitionType_REQUIRED && c.mode.forward() {
+		c.report(path, RepetitionRelaxed, ForwardCompatible, "required field was changed to optional")
+	}
+}
+
+// modes returns the individual compatibilities that are checked.
+func (c *compatibilityChecker) modes() []CompatibilityMode {
+	switch c.mode {
+	case BackwardCompatible:
+		return []CompatibilityMode{BackwardCompatible}
+	case ForwardCompatible:
+		return []CompatibilityMode{ForwardCompatible}
+	}
+	return []CompatibilityMode{BackwardCompatible, ForwardCompatible}
+}
+
+func findChild(cols []*ColumnDefinition, name string) *ColumnDefinition {
+	for _, c := range cols {
+		if c.SchemaElement.GetName() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func fieldKind(group bool) string {
+	if group {
+		return "group"
+	}
+	return "primitive field"
+}
+
+func sameType(a, b *parquet.SchemaElement) bool {
+	return a.GetType() == b.GetType() && (a.GetType() != parquet.Type_FIXED_LEN_BYTE_ARRAY || a.GetTypeLength() == b.GetTypeLength())
+}
+
+// CanPromote returns true if values written using the from element can be read as values of the
+// to element. This is the case if both elements have the same physical type and the same logical
+// or converted type, if signed INT32 values are read as signed INT64 values, or if FLOAT values are
+// read as DOUBLE values. FIXED_LEN_BYTE_ARRAY elements also need to have the same type length.
+func CanPromote(from, to *parquet.SchemaElement) bool {
+	if sameType(from, to) {
+		return sameAnnotation(from, to)
+	}
+	switch {
+	case from.GetType() == parquet.Type_INT32 && to.GetType() == parquet.Type_INT64:
+		return isSignedInteger(from) && isSignedInteger(to)
+	case from.GetType() == parquet.Type_FLOAT && to.GetType() == parquet.Type_DOUBLE:
+		return !isAnnotated(from) && !isAnnotated(to)
+	}
+	return false
+}
+
+func isAnnotated(elem *parquet.SchemaElement) bool {
+	return elem.LogicalType != nil || elem.ConvertedType != nil
+}
+
+// sameAnnotation compares the logical types of both elements, or their converted types if
+// not both elements have a logical type. If one element only has a logical type and the other
+// one only has a converted type, the logical type is converted to its converted type first.
+func sameAnnotation(a, b *parquet.SchemaElement) bool {
+	switch {
+	case a.LogicalType != nil && b.LogicalType != nil:
+		return a.LogicalType.Equals(b.LogicalType)
+	case a.ConvertedType != nil && b.ConvertedType != nil:
+		return *a.ConvertedType == *b.ConvertedType
+	case a.LogicalType != nil && b.ConvertedType != nil:
+		return sameConvertedType(a, b)
+	case a.ConvertedType != nil && b.LogicalType != nil:
+		return sameConvertedType(b, a)
+	}
+	return isAnnotated(a) == isAnnotated(b)
+}
+
+// sameConvertedType returns true if the logical type of elem corresponds to the converted type
+// of convElem. Logical types without a corresponding converted type are always different.
+func sameConvertedType(elem, convElem *parquet.SchemaElement) bool {
+	ct, ok := convertedType(elem.LogicalType)
+	if !ok || ct != *convElem.ConvertedType {
+		return false
+	}
+	if ct == parquet.ConvertedType_DECIMAL {
+		return elem.LogicalType.DECIMAL.Precision == convElem.GetPrecision() && elem.LogicalType.DECIMAL.Scale == convElem.GetScale()
+	}
+	return true
+}
+
+// convertedType returns the converted type that corresponds to the logical type, the same way
+// the schema parser sets both of them.
+func convertedType(lt *parquet.LogicalType) (parquet.ConvertedType, bool) {
+	switch {
+	case lt.IsSetSTRING():
+		return parquet.ConvertedType_UTF8, true
+	case lt.IsSetMAP():
+		return parquet.ConvertedType_MAP, true
+	case lt.IsSetLIST():
+		return parquet.ConvertedType_LIST, true
+	case lt.IsSetENUM():
+		return parquet.ConvertedType_ENUM, true
+	case lt.IsSetDECIMAL():
+		return parquet.ConvertedType_DECIMAL, true
+	case lt.IsSetDATE():
+		return parquet.ConvertedType_DATE, true
+	case lt.IsSetJSON():
+		return parquet.ConvertedType_JSON, true
+	case lt.IsSetBSON():
+		return parquet.ConvertedType_BSON, true
+	case lt.IsSetTIME() && lt.TIME.Unit.IsSetMILLIS():
+		return parquet.ConvertedType_TIME_MILLIS, true
+	case lt.IsSetTIME() && lt.TIME.Unit.IsSetMICROS():
+		return parquet.ConvertedType_TIME_MICROS, true
+	case lt.IsSetTIMESTAMP() && lt.TIMESTAMP.Unit.IsSetMILLIS():
+		return parquet.ConvertedType_TIMESTAMP_MILLIS, true
+	case lt.IsSetTIMESTAMP() && lt.TIMESTAMP.Unit.IsSetMICROS():
+		return parquet.ConvertedType_TIMESTAMP_MICROS, true
+	case lt.IsSetINTEGER():
+		switch lt.INTEGER.BitWidth {
+		case 8:
+			if lt.INTEGER.IsSigned {
+				return parquet.ConvertedType_INT_8, true
+			}
+			return parquet.ConvertedType_UINT_8, true
+		case 16:
+			if lt.INTEGER.IsSigned {
+				return parquet.ConvertedType_INT_16, true
+			}
+			return parquet.ConvertedType_UINT_16, true
+		case 32:
+			if lt.INTEGER.IsSigned {
+				return parquet.ConvertedType_INT_32, true
+			}
+			return parquet.ConvertedType_UINT_32, true
+		case 64:
+			if lt.INTEGER.IsSigned {
+				return parquet.ConvertedType_INT_64, true
+			}
+			return parquet.ConvertedType_UINT_64, true
+		}
+	}
+	return 0, false
+}
+
+// isSignedInteger returns true if the element is not annotated, or annotated as signed integer.
+func isSignedInteger(elem *parquet.SchemaElement) bool {
+	if elem.LogicalType != nil {
+		return elem.LogicalType.IsSetINTEGER() && elem.LogicalType.INTEGER.IsSigned
+	}
+	if elem.ConvertedType != nil {
+		switch *elem.ConvertedType {
+		case parquet.ConvertedType_INT_8, parquet.ConvertedType_INT_16, parquet.ConvertedType_INT_32, parquet.ConvertedType_INT_64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func typeString(elem *parquet.SchemaElement) string {
+	if !isAnnotated(elem) {
+		return getSchemaType(elem)
+	}
+	return getSchemaType(elem) + " (" + annotationString(elem) + ")"
+}
+
+func annotationString(elem *parquet.SchemaElement) string {
+	switch {
+	case elem.LogicalType != nil && elem.LogicalType.IsSetLIST():
+		return "LIST"
+	case elem.LogicalType != nil && elem.LogicalType.IsSetMAP():
+		return "MAP"
+	case elem.LogicalType != nil:
+		return getSchemaLogicalType(elem.LogicalType)
+	case elem.ConvertedType != nil:
+		return elem.ConvertedType.String()
+	}
+	return "none"
+}
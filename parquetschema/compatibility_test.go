@@ -0,0 +1,255 @@
+package parquetschema
+
+import (
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	oldSchema, err := ParseSchemaDefinition(`message test {
+		required int64 id;
+		required int32 count;
+		optional float score;
+		required binary name (STRING);
+		optional binary comment (STRING);
+		required int64 created (TIMESTAMP(MILLIS, true));
+		optional group tags (LIST) {
+			repeated group list {
+				required binary element (STRING);
+			}
+		}
+		required group info {
+			optional int32 age;
+			required binary email (STRING);
+		}
+	}`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		schema   string
+		backward []Violation
+		forward  []Violation
+	}{
+		{
+			name:   "identical",
+			schema: oldSchema.String(),
+		},
+		{
+			name: "compatible changes",
+			schema: `message test {
+				required int64 id;
+				required int64 count;
+				optional double score;
+				optional binary name (STRING);
+				optional group tags (LIST) {
+					repeated group list {
+						required binary element (STRING);
+					}
+				}
+				required group info {
+					optional int32 age;
+					required binary email (STRING);
+					optional binary phone (STRING);
+				}
+				required int64 created (TIMESTAMP(MILLIS, true));
+				optional int32 rating;
+			}`,
+			forward: []Violation{
+				{Path: []string{"count"}, Type: PhysicalTypeChanged, Mode: ForwardCompatible, Description: "type int64 can't be read as int32"},
+				{Path: []string{"score"}, Type: PhysicalTypeChanged, Mode: ForwardCompatible, Description: "type double can't be read as float"},
+				{Path: []string{"name"}, Type: RepetitionRelaxed, Mode: ForwardCompatible, Description: "required field was changed to optional"},
+			},
+		},
+		{
+			name: "incompatible changes",
+			schema: `message test {
+				required binary id;
+				required int32 count (INT(16, true));
+				optional float score;
+				required binary name (STRING);
+				required binary comment (STRING);
+				required int64 created (TIMESTAMP(MICROS, true));
+				optional group tags {
+					required binary element (STRING);
+				}
+				required binary info (STRING);
+				required int32 rating;
+			}`,
+			backward: []Violation{
+				{Path: []string{"id"}, Type: PhysicalTypeChanged, Mode: BackwardCompatible, Description: "type int64 can't be read as binary"},
+				{Path: []string{"count"}, Type: LogicalTypeChanged, Mode: BackwardCompatible, Description: "type int32 was changed to int32 (INT(16, true))"},
+				{Path: []string{"comment"}, Type: RepetitionTightened, Mode: BackwardCompatible, Description: "optional field was changed to required"},
+				{Path: []string{"created"}, Type: LogicalTypeChanged, Mode: BackwardCompatible, Description: "type int64 (TIMESTAMP(MILLIS, true)) was changed to int64 (TIMESTAMP(MICROS, true))"},
+				{Path: []string{"tags"}, Type: LogicalTypeChanged, Mode: BackwardCompatible, Description: "group type LIST was changed to none"},
+				{Path: []string{"tags", "element"}, Type: RequiredFieldAdded, Mode: BackwardCompatible, Description: "required field was added"},
+				{Path: []string{"info"}, Type: FieldKindChanged, Mode: BackwardCompatible, Description: "group was changed to primitive field"},
+				{Path: []string{"rating"}, Type: RequiredFieldAdded, Mode: BackwardCompatible, Description: "required field was added"},
+			},
+			forward: []Violation{
+				{Path: []string{"id"}, Type: PhysicalTypeChanged, Mode: ForwardCompatible, Description: "type binary can't be read as int64"},
+				{Path: []string{"count"}, Type: LogicalTypeChanged, Mode: ForwardCompatible, Description: "type int32 was changed to int32 (INT(16, true))"},
+				{Path: []string{"created"}, Type: LogicalTypeChanged, Mode: ForwardCompatible, Description: "type int64 (TIMESTAMP(MILLIS, true)) was changed to int64 (TIMESTAMP(MICROS, true))"},
+				{Path: []string{"tags"}, Type: LogicalTypeChanged, Mode: ForwardCompatible, Description: "group type LIST was changed to none"},
+				{Path: []string{"info"}, Type: FieldKindChanged, Mode: ForwardCompatible, Description: "group was changed to primitive field"},
+			},
+		},
+		{
+			name: "removed fields",
+			schema: `message test {
+				required int64 id;
+				optional group info {
+					optional int32 age;
+				}
+			}`,
+			forward: []Violation{
+				{Path: []string{"count"}, Type: RequiredFieldRemoved, Mode: ForwardCompatible, Description: "required field was removed"},
+				{Path: []string{"name"}, Type: RequiredFieldRemoved, Mode: ForwardCompatible, Description: "required field was removed"},
+				{Path: []string{"created"}, Type: RequiredFieldRemoved, Mode: ForwardCompatible, Description: "required field was removed"},
+				{Path: []string{"info"}, Type: RepetitionRelaxed, Mode: ForwardCompatible, Description: "required field was changed to optional"},
+				{Path: []string{"info", "email"}, Type: RequiredFieldRemoved, Mode: ForwardCompatible, Description: "required field was removed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSchema, err := ParseSchemaDefinition(tt.schema)
+			require.NoError(t, err)
+
+			violations, err := CheckCompatibility(oldSchema, newSchema, BackwardCompatible)
+			require.NoError(t, err)
+			require.Equal(t, tt.backward, violations)
+
+			violations, err = CheckCompatibility(oldSchema, newSchema, ForwardCompatible)
+			require.NoError(t, err)
+			require.Equal(t, tt.forward, violations)
+
+			violations, err = CheckCompatibility(oldSchema, newSchema, FullyCompatible)
+			require.NoError(t, err)
+			require.Len(t, violations, len(tt.backward)+len(tt.forward))
+		})
+	}
+}
+
+func TestCheckCompatibilityErrors(t *testing.T) {
+	sd, err := ParseSchemaDefinition(`message test { required int64 id; }`)
+	require.NoError(t, err)
+
+	_, err = CheckCompatibility(nil, sd, BackwardCompatible)
+	require.Error(t, err)
+
+	_, err = CheckCompatibility(sd, &SchemaDefinition{}, BackwardCompatible)
+	require.Error(t, err)
+
+	_, err = CheckCompatibility(sd, sd, CompatibilityMode(0))
+	require.Error(t, err)
+}
+
+func TestViolationString(t *testing.T) {
+	v := Violation{Path: []string{"info", "email"}, Type: RequiredFieldAdded, Mode: BackwardCompatible, Description: "required field was added"}
+	require.Equal(t, "info.email: required field was added (BACKWARD)", v.String())
+	require.Equal(t, "REQUIRED_FIELD_ADDED", v.Type.String())
+}
+
+func TestCanPromote(t *testing.T) {
+	sd, err := ParseSchemaDefinition(`message test {
+		required int32 i32;
+		required int64 i64;
+		required int32 u32 (INT(32, false));
+		required int64 u64 (INT(64, false));
+		required float f;
+		required double d;
+		required binary s (STRING);
+		required binary b;
+		required fixed_len_byte_array(4) fixed4;
+		required fixed_len_byte_array(8) fixed8;
+	}`)
+	require.NoError(t, err)
+
+	elem := func(name string) *parquet.SchemaElement {
+		return sd.SubSchema(name).SchemaElement()
+	}
+
+	tests := []struct {
+		from, to string
+		expected bool
+	}{
+		{"i32", "i32", true},
+		{"i32", "i64", true},
+		{"i64", "i32", false},
+		{"u32", "u64", false},
+		{"i32", "u64", false},
+		{"f", "d", true},
+		{"d", "f", false},
+		{"s", "s", true},
+		{"s", "b", false},
+		{"b", "s", false},
+		{"fixed4", "fixed4", true},
+		{"fixed4", "fixed8", false},
+		{"i64", "d", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, CanPromote(elem(tt.from), elem(tt.to)), "%s as %s", tt.from, tt.to)
+	}
+}
+
+func TestCanPromoteMixedAnnotations(t *testing.T) {
+	logical := func(typ parquet.Type, lt *parquet.LogicalType) *parquet.SchemaElement {
+		return &parquet.SchemaElement{Type: parquet.TypePtr(typ), LogicalType: lt}
+	}
+	converted := func(typ parquet.Type, ct parquet.ConvertedType) *parquet.SchemaElement {
+		return &parquet.SchemaElement{Type: parquet.TypePtr(typ), ConvertedType: parquet.ConvertedTypePtr(ct)}
+	}
+	timestamp := func(unit *parquet.TimeUnit) *parquet.LogicalType {
+		return &parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{IsAdjustedToUTC: true, Unit: unit}}
+	}
+	precision, scale := int32(10), int32(2)
+	decimal := converted(parquet.Type_INT64, parquet.ConvertedType_DECIMAL)
+	decimal.Precision, decimal.Scale = &precision, &scale
+
+	tests := []struct {
+		name      string
+		logical   *parquet.SchemaElement
+		converted *parquet.SchemaElement
+		expected  bool
+	}{
+		{"string_utf8", logical(parquet.Type_BYTE_ARRAY, &parquet.LogicalType{STRING: parquet.NewStringType()}), converted(parquet.Type_BYTE_ARRAY, parquet.ConvertedType_UTF8), true},
+		{"string_enum", logical(parquet.Type_BYTE_ARRAY, &parquet.LogicalType{STRING: parquet.NewStringType()}), converted(parquet.Type_BYTE_ARRAY, parquet.ConvertedType_ENUM), false},
+		{"uuid_utf8", logical(parquet.Type_BYTE_ARRAY, &parquet.LogicalType{UUID: parquet.NewUUIDType()}), converted(parquet.Type_BYTE_ARRAY, parquet.ConvertedType_UTF8), false},
+		{"timestamp_millis", logical(parquet.Type_INT64, timestamp(&parquet.TimeUnit{MILLIS: parquet.NewMilliSeconds()})), converted(parquet.Type_INT64, parquet.ConvertedType_TIMESTAMP_MILLIS), true},
+		{"timestamp_utf8", logical(parquet.Type_INT64, timestamp(&parquet.TimeUnit{MILLIS: parquet.NewMilliSeconds()})), converted(parquet.Type_INT64, parquet.ConvertedType_UTF8), false},
+		{"timestamp_micros", logical(parquet.Type_INT64, timestamp(&parquet.TimeUnit{MILLIS: parquet.NewMilliSeconds()})), converted(parquet.Type_INT64, parquet.ConvertedType_TIMESTAMP_MICROS), false},
+		{"timestamp_nanos", logical(parquet.Type_INT64, timestamp(&parquet.TimeUnit{NANOS: parquet.NewNanoSeconds()})), converted(parquet.Type_INT64, parquet.ConvertedType_TIMESTAMP_MICROS), false},
+		{"int_signed", logical(parquet.Type_INT32, &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 16, IsSigned: true}}), converted(parquet.Type_INT32, parquet.ConvertedType_INT_16), true},
+		{"int_unsigned", logical(parquet.Type_INT32, &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 16, IsSigned: false}}), converted(parquet.Type_INT32, parquet.ConvertedType_INT_16), false},
+		{"decimal", logical(parquet.Type_INT64, &parquet.LogicalType{DECIMAL: &parquet.DecimalType{Precision: 10, Scale: 2}}), decimal, true},
+		{"decimal_scale", logical(parquet.Type_INT64, &parquet.LogicalType{DECIMAL: &parquet.DecimalType{Precision: 10, Scale: 3}}), decimal, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, CanPromote(tt.logical, tt.converted))
+			require.Equal(t, tt.expected, CanPromote(tt.converted, tt.logical))
+		})
+	}
+
+	// files written by tools that only set one of both annotations are checked as well.
+	oldSchema, err := ParseSchemaDefinition(`message test { required binary name (STRING); }`)
+	require.NoError(t, err)
+	oldSchema.SubSchema("name").SchemaElement().ConvertedType = nil
+
+	newSchema, err := ParseSchemaDefinition(`message test { required binary name (ENUM); }`)
+	require.NoError(t, err)
+	newSchema.SubSchema("name").SchemaElement().LogicalType = nil
+
+	violations, err := CheckCompatibility(oldSchema, newSchema, BackwardCompatible)
+	require.NoError(t, err)
+	require.Equal(t, []Violation{
+		{Path: []string{"name"}, Type: LogicalTypeChanged, Mode: BackwardCompatible, Description: "type binary (STRING) was changed to binary (ENUM)"},
+	}, violations)
+}
@@ -0,0 +1,679 @@
+package goparquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// Predicate is a filter expression over the values of data columns. It is used by the file reader
+// to only return matching rows, and to skip row groups that can't contain any matching rows based
+// on the statistics of their column chunks. Please use the functions Eq, Lt, Gt, In, IsNull, And and
+// Or to create predicates.
+type Predicate interface {
+	// columnPaths returns the paths of all columns the predicate refers to.
+	columnPaths() []ColumnPath
+
+	// checkValues checks that the values of the predicate can be compared with the values of the columns.
+	checkValues(cols map[string]*predicateColumn) error
+
+	// mightMatch returns false if none of the rows described by the statistics can match
+	// the predicate.
+	mightMatch(stats func(ColumnPath) *columnStatistics) bool
+
+	// matches returns true if the row whose values are provided matches the predicate.
+	matches(row func(ColumnPath) *columnValues) bool
+}
+
+type compareOp int
+
+const (
+	opEq compareOp = iota
+	opLt
+	opGt
+)
+
+func (op compareOp) matches(cmp int) bool {
+	switch op {
+	case opLt:
+		return cmp < 0
+	case opGt:
+		return cmp > 0
+	}
+	return cmp == 0
+}
+
+type comparisonPredicate struct {
+	path  ColumnPath
+	op    compareOp
+	value interface{}
+}
+
+// Eq returns a predicate that matches rows where the column's value is equal to the provided
+// value. For repeated columns, it matches rows where any of the column's values is equal to it.
+// Null values never match. The value can be of any Go integer type for INT32 and INT64 columns,
+// float32 or float64 for FLOAT and DOUBLE columns, []byte or string for byte array columns, and
+// bool for BOOLEAN columns. Values of unsigned integer columns can also be provided as int32 or
+// int64, in which case they are interpreted as the unsigned value with the same bits, like it
+// is done when writing them. INT96 columns are not supported. Comparisons with NaN never match.
+func Eq(path ColumnPath, value interface{}) Predicate {
+	return &comparisonPredicate{path: path, op: opEq, value: value}
+}
+
+// Lt returns a predicate that matches rows where the column's value is less than the provided
+// value in the order defined by the column's type. Please see Eq for the supported values.
+func Lt(path ColumnPath, value interface{}) Predicate {
+	return &comparisonPredicate{path: path, op: opLt, value: value}
+}
+
+// Gt returns a predicate that matches rows where the column's value is greater than the provided
+// value in the order defined by the column's type. Please see Eq for the supported values.
+func Gt(path ColumnPath, value interface{}) Predicate {
+	return &comparisonPredicate{path: path, op: opGt, value: value}
+}
+
+func (p *comparisonPredicate) columnPaths() []ColumnPath {
+	return []ColumnPath{p.path}
+}
+
+func (p *comparisonPredicate) checkValues(cols map[string]*predicateColumn) error {
+	return cols[p.path.flatName()].checkValue(p.value)
+}
+
+func (p *comparisonPredicate) mightMatch(stats func(ColumnPath) *columnStatistics) bool {
+	s := stats(p.path)
+	if s.allNull() {
+		return false
+	}
+
+	v, ok := s.col.normalize(p.value)
+	if !ok {
+		return true
+	}
+	if isNaN(v) {
+		return false
+	}
+
+	if s.min == nil || s.max == nil {
+		return true
+	}
+
+	minCmp, ok := s.col.compare(s.min, v)
+	if !ok {
+		return true
+	}
+	maxCmp, ok := s.col.compare(s.max, v)
+	if !ok {
+		return true
+	}
+
+	switch p.op {
+	case opLt:
+		return minCmp < 0
+	case opGt:
+		return maxCmp > 0
+	}
+	return minCmp <= 0 && maxCmp >= 0
+}
+
+func (p *comparisonPredicate) matches(row func(ColumnPath) *columnValues) bool {
+	c := row(p.path)
+
+	v, ok := c.col.normalize(p.value)
+	if !ok {
+		return false
+	}
+
+	for _, x := range c.values {
+		if cmp, ok := c.col.compare(x, v); ok && p.op.matches(cmp) {
+			return true
+		}
+	}
+	return false
+}
+
+type inPredicate struct {
+	path   ColumnPath
+	values []interface{}
+}
+
+// In returns a predicate that matches rows where the column's value is equal to any of the
+// provided values. Please see Eq for the supported values.
+func In(path ColumnPath, values ...interface{}) Predicate {
+	return &inPredicate{path: path, values: values}
+}
+
+func (p *inPredicate) columnPaths() []ColumnPath {
+	return []ColumnPath{p.path}
+}
+
+func (p *inPredicate) checkValues(cols map[string]*predicateColumn) error {
+	for _, v := range p.values {
+		if err := cols[p.path.flatName()].checkValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *inPredicate) mightMatch(stats func(ColumnPath) *columnStatistics) bool {
+	for _, v := range p.values {
+		if Eq(p.path, v).mightMatch(stats) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *inPredicate) matches(row func(ColumnPath) *columnValues) bool {
+	for _, v := range p.values {
+		if Eq(p.path, v).matches(row) {
+			return true
+		}
+	}
+	return false
+}
+
+type isNullPredicate struct {
+	path ColumnPath
+}
+
+// IsNull returns a predicate that matches rows where the column's value is null. For repeated
+// columns, it matches rows where the column has no values other than null.
+func IsNull(path ColumnPath) Predicate {
+	return &isNullPredicate{path: path}
+}
+
+func (p *isNullPredicate) columnPaths() []ColumnPath {
+	return []ColumnPath{p.path}
+}
+
+func (p *isNullPredicate) checkValues(map[string]*predicateColumn) error {
+	return nil
+}
+
+func (p *isNullPredicate) mightMatch(stats func(ColumnPath) *columnStatistics) bool {
+	s := stats(p.path)
+	return s.nullCount == nil || *s.nullCount > 0
+}
+
+func (p *isNullPredicate) matches(row func(ColumnPath) *columnValues) bool {
+	return len(row(p.path).values) == 0
+}
+
+type andPredicate struct {
+	preds []Predicate
+}
+
+// And returns a predicate that matches rows that match all of the provided predicates.
+func And(preds ...Predicate) Predicate {
+	return &andPredicate{preds: nonNilPredicates(preds)}
+}
+
+func (p *andPredicate) columnPaths() []ColumnPath {
+	return predicatesColumnPaths(p.preds)
+}
+
+func (p *andPredicate) checkValues(cols map[string]*predicateColumn) error {
+	return checkPredicatesValues(p.preds, cols)
+}
+
+func (p *andPredicate) mightMatch(stats func(ColumnPath) *columnStatistics) bool {
+	for _, pred := range p.preds {
+		if !pred.mightMatch(stats) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *andPredicate) matches(row func(ColumnPath) *columnValues) bool {
+	for _, pred := range p.preds {
+		if !pred.matches(row) {
+			return false
+		}
+	}
+	return true
+}
+
+type orPredicate struct {
+	preds []Predicate
+}
+
+// Or returns a predicate that matches rows that match any of the provided predicates.
+func Or(preds ...Predicate) Predicate {
+	return &orPredicate{preds: nonNilPredicates(preds)}
+}
+
+func (p *orPredicate) columnPaths() []ColumnPath {
+	return predicatesColumnPaths(p.preds)
+}
+
+func (p *orPredicate) checkValues(cols map[string]*predicateColumn) error {
+	return checkPredicatesValues(p.preds, cols)
+}
+
+func (p *orPredicate) mightMatch(stats func(ColumnPath) *columnStatistics) bool {
+	for _, pred := range p.preds {
+		if pred.mightMatch(stats) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *orPredicate) matches(row func(ColumnPath) *columnValues) bool {
+	for _, pred := range p.preds {
+		if pred.matches(row) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonNilPredicates(preds []Predicate) []Predicate {
+	ret := make([]Predicate, 0, len(preds))
+	for _, pred := range preds {
+		if pred != nil {
+			ret = append(ret, pred)
+		}
+	}
+	return ret
+}
+
+func predicatesColumnPaths(preds []Predicate) []ColumnPath {
+	var paths []ColumnPath
+	for _, pred := range preds {
+		paths = append(paths, pred.columnPaths()...)
+	}
+	return paths
+}
+
+func checkPredicatesValues(preds []Predicate, cols map[string]*predicateColumn) error {
+	for _, pred := range preds {
+		if err := pred.checkValues(cols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// predicateColumn is a column a predicate refers to. Values of the column are normalized
+// before they are compared, so that values of different Go types can be compared with each
+// other: integers are converted to int64, or uint64 for unsigned integer columns, floating
+// point numbers to float64 and strings to []byte.
+type predicateColumn struct {
+	path  ColumnPath
+	typ   parquet.Type
+	order statsOrder
+
+	// col is the column of the file, or nil if the column only exists in the target schema.
+	col *Column
+
+	// defaultValue is the normalized default value of a column that only exists in the target schema.
+	defaultValue interface{}
+
+	// values holds the normalized values of the current row.
+	values columnValues
+}
+
+// newPredicateColumns resolves the columns the predicate refers to. They need to be data columns
+// of the file, or of the target schema if one is configured.
+func newPredicateColumns(sch *schema, projection *schemaProjection, pred Predicate) (map[string]*predicateColumn, error) {
+	cols := make(map[string]*predicateColumn)
+	for _, path := range pred.columnPaths() {
+		if _, ok := cols[path.flatName()]; ok {
+			continue
+		}
+
+		pc := &predicateColumn{path: path}
+		pc.values.col = pc
+
+		var elem *parquet.SchemaElement
+		if projection != nil {
+			def := findColumnDefinition(projection.target.RootColumn, path)
+			if def == nil || len(def.Children) > 0 {
+				return nil, fmt.Errorf("predicate refers to unknown column %s", path.flatName())
+			}
+			elem = def.SchemaElement
+		}
+
+		if col := sch.GetColumnByPath(path); col != nil && col.DataColumn() {
+			pc.col = col
+			elem = col.Element()
+		} else if projection == nil {
+			return nil, fmt.Errorf("predicate refers to unknown column %s", path.flatName())
+		}
+
+		pc.typ = elem.GetType()
+		pc.order = columnStatsOrder(pc.typ, &ColumnParameters{
+			LogicalType:   elem.LogicalType,
+			ConvertedType: elem.ConvertedType,
+		})
+
+		if pc.col == nil {
+			if def := projection.defaultValue(path); def != nil {
+				pc.defaultValue, _ = pc.normalize(def)
+				pc.values.values = []interface{}{pc.defaultValue}
+			}
+		}
+
+		cols[path.flatName()] = pc
+	}
+
+	if err := pred.checkValues(cols); err != nil {
+		return nil, err
+	}
+
+	return cols, nil
+}
+
+func (c *predicateColumn) checkValue(v interface{}) error {
+	if _, ok := c.normalize(v); !ok {
+		return fmt.Errorf("predicate on column %s: value %v of type %T can't be compared with values of type %s", c.path.flatName(), v, v, c.typ)
+	}
+	return nil
+}
+
+// normalize converts a value to the type it is compared as. It returns false if the value can't
+// be compared with the values of the column.
+func (c *predicateColumn) normalize(v interface{}) (interface{}, bool) {
+	switch c.typ {
+	case parquet.Type_BOOLEAN:
+		b, ok := v.(bool)
+		return b, ok
+	case parquet.Type_INT32, parquet.Type_INT64:
+		if c.order == statsOrderUnsigned {
+			return normalizeUnsigned(v)
+		}
+		return normalizeSigned(v)
+	case parquet.Type_FLOAT, parquet.Type_DOUBLE:
+		switch x := v.(type) {
+		case float32:
+			return float64(x), true
+		case float64:
+			return x, true
+		}
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		switch x := v.(type) {
+		case []byte:
+			return x, true
+		case string:
+			return []byte(x), true
+		}
+	}
+	return nil, false
+}
+
+func normalizeSigned(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint:
+		return int64(x), uint64(x) <= math.MaxInt64
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), x <= math.MaxInt64
+	}
+	return nil, false
+}
+
+func normalizeUnsigned(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case int:
+		return uint64(x), x >= 0
+	case int8:
+		return uint64(x), x >= 0
+	case int16:
+		return uint64(x), x >= 0
+	case int32:
+		// unsigned values of INT32 columns are stored as int32.
+		return uint64(uint32(x)), true
+	case int64:
+		// unsigned values of INT64 columns are stored as int64.
+		return uint64(x), true
+	case uint:
+		return uint64(x), true
+	case uint8:
+		return uint64(x), true
+	case uint16:
+		return uint64(x), true
+	case uint32:
+		return uint64(x), true
+	case uint64:
+		return x, true
+	}
+	return nil, false
+}
+
+// compare compares two normalized values of the column. It returns false if the values can't
+// be compared, i.e. if one of them is NaN.
+func (c *predicateColumn) compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case int64:
+		y, ok := b.(int64)
+		return compareInt64(x, y), ok
+	case uint64:
+		y, ok := b.(uint64)
+		return compareUint64(x, y), ok
+	case float64:
+		y, ok := b.(float64)
+		if !ok || math.IsNaN(x) || math.IsNaN(y) {
+			return 0, false
+		}
+		return compareFloat64(x, y), true
+	case []byte:
+		y, ok := b.([]byte)
+		return compareByteArrays(c.order, x, y), ok
+	case bool:
+		y, ok := b.(bool)
+		switch {
+		case x == y:
+			return 0, ok
+		case y:
+			return -1, ok
+		}
+		return 1, ok
+	}
+	return 0, false
+}
+
+func isNaN(v interface{}) bool {
+	f, ok := v.(float64)
+	return ok && math.IsNaN(f)
+}
+
+// decodeStatsValue decodes a min or max value of the statistics and normalizes it.
+func (c *predicateColumn) decodeStatsValue(b []byte) interface{} {
+	var v interface{}
+	switch c.typ {
+	case parquet.Type_BOOLEAN:
+		if len(b) < 1 {
+			return nil
+		}
+		v = b[0] != 0
+	case parquet.Type_INT32:
+		if len(b) < 4 {
+			return nil
+		}
+		v = int32(binary.LittleEndian.Uint32(b))
+	case parquet.Type_INT64:
+		if len(b) < 8 {
+			return nil
+		}
+		v = int64(binary.LittleEndian.Uint64(b))
+	case parquet.Type_FLOAT:
+		if len(b) < 4 {
+			return nil
+		}
+		v = math.Float32frombits(binary.LittleEndian.Uint32(b))
+	case parquet.Type_DOUBLE:
+		if len(b) < 8 {
+			return nil
+		}
+		v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+	default:
+		v = b
+	}
+
+	ret, _ := c.normalize(v)
+	return ret
+}
+
+// columnStatistics are the statistics of a column chunk as they are used to evaluate predicates.
+type columnStatistics struct {
+	col *predicateColumn
+
+	// min and max are the normalized min and max values. They are nil if they are unknown or can't be used.
+	min, max  interface{}
+	nullCount *int64
+	numValues int64
+}
+
+func (s *columnStatistics) allNull() bool {
+	return s.nullCount != nil && *s.nullCount >= s.numValues
+}
+
+// columnValues are the normalized values of a column in the current row. Null values are omitted.
+type columnValues struct {
+	col    *predicateColumn
+	values []interface{}
+}
+
+// rowGroupMightMatch returns false if the configured predicate rules out all rows of the
+// row group with the provided index.
+func (f *FileReader) rowGroupMightMatch(rowGroup int) bool {
+	if f.predicate == nil {
+		return true
+	}
+
+	return f.predicate.mightMatch(func(path ColumnPath) *columnStatistics {
+		return f.columnStatistics(rowGroup, f.predicateColumns[path.flatName()])
+	})
+}
+
+func (f *FileReader) columnStatistics(rowGroup int, pc *predicateColumn) *columnStatistics {
+	s := &columnStatistics{col: pc}
+
+	if pc.col == nil {
+		// all values of the column are either null or the default value.
+		s.numValues = f.meta.RowGroups[rowGroup].NumRows
+		s.nullCount = int64Ptr(s.numValues)
+		if pc.defaultValue != nil {
+			s.nullCount = int64Ptr(0)
+			s.min, s.max = pc.defaultValue, pc.defaultValue
+		}
+		return s
+	}
+
+	idx := pc.col.Index()
+	columns := f.meta.RowGroups[rowGroup].Columns
+	if idx >= len(columns) || columns[idx].MetaData == nil {
+		// the column chunk is unknown or its meta data is encrypted with an unavailable key.
+		return s
+	}
+
+	meta := columns[idx].MetaData
+	if meta.Statistics == nil {
+		return s
+	}
+
+	s.nullCount, s.numValues = meta.Statistics.NullCount, meta.NumValues
+
+	typeDefinedOrder := false
+	if idx < len(f.meta.ColumnOrders) {
+		if f.meta.ColumnOrders[idx].TYPE_ORDER == nil {
+			// the min and max values are in an unknown order.
+			return s
+		}
+		typeDefinedOrder = true
+	}
+
+	// files without column orders may have been written using the signed order for all types,
+	// so the min and max values are only used if the order is the same for all writers.
+	signedOnly := pc.order == statsOrderSigned && pc.typ != parquet.Type_BYTE_ARRAY && pc.typ != parquet.Type_FIXED_LEN_BYTE_ARRAY && pc.typ != parquet.Type_INT96
+
+	var min, max []byte
+	switch {
+	case meta.Statistics.MinValue != nil && meta.Statistics.MaxValue != nil && (typeDefinedOrder || signedOnly):
+		min, max = meta.Statistics.MinValue, meta.Statistics.MaxValue
+	case meta.Statistics.Min != nil && meta.Statistics.Max != nil && signedOnly:
+		min, max = meta.Statistics.Min, meta.Statistics.Max
+	default:
+		return s
+	}
+
+	s.min, s.max = pc.decodeStatsValue(min), pc.decodeStatsValue(max)
+	return s
+}
+
+// currentRowMatches returns true if the next row of the current row group matches the configured
+// predicate. Only the values of the columns the predicate refers to are read, and their read
+// position is not changed.
+func (f *FileReader) currentRowMatches() (bool, error) {
+	if f.predicate == nil {
+		return true, nil
+	}
+
+	for _, pc := range f.predicateColumns {
+		if pc.col == nil {
+			continue
+		}
+
+		values, err := pc.col.data.peekRow(pc.values.values[:0], int32(pc.col.maxD))
+		if err != nil {
+			return false, fmt.Errorf("reading column %s failed: %w", pc.path.flatName(), err)
+		}
+
+		pc.values.values = values[:0]
+		for _, v := range values {
+			if nv, ok := pc.normalize(v); ok {
+				pc.values.values = append(pc.values.values, nv)
+			}
+		}
+	}
+
+	return f.predicate.matches(func(path ColumnPath) *columnValues {
+		return &f.predicateColumns[path.flatName()].values
+	}), nil
+}
+
+// skipCurrentRow skips the next row of the current row group in all columns that are read. The
+// columns only skip the row when they are read the next time, so the values of columns that aren't
+// part of the predicate are never decoded for rows that don't match.
+func (f *FileReader) skipCurrentRow() {
+	for _, c := range f.schemaReader.Columns() {
+		c.data.skipRows(1)
+	}
+}
+
+// skipHiddenColumns skips the next row of all columns that are only read to evaluate the predicate.
+func (f *FileReader) skipHiddenColumns() {
+	for _, pc := range f.predicateColumns {
+		if pc.col == nil || !pc.col.data.hidden {
+			continue
+		}
+		pc.col.data.skipRows(1)
+	}
+}
+
+// isPredicateColumn returns true if the column of the file is read to evaluate the predicate.
+func (f *FileReader) isPredicateColumn(path ColumnPath) bool {
+	pc, ok := f.predicateColumns[path.flatName()]
+	return ok && pc.col != nil
+}
@@ -0,0 +1,504 @@
+package goparquet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+// writePredicateTestFile writes a file with 5 row groups of 10 rows each. The ids of row group i are
+// 10*i to 10*i+9, and the names of row group 2 are all null.
+func writePredicateTestFile(t *testing.T) []byte {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		optional double score;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+
+	for i := 0; i < 50; i++ {
+		data := map[string]interface{}{
+			"id":    int64(i),
+			"score": float64(i) / 2,
+		}
+		if i/10 != 2 {
+			data["name"] = []byte{byte('a' + i/10), byte('a' + i%10)}
+		}
+		require.NoError(t, fw.AddData(data))
+		if i%10 == 9 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	return buf.Bytes()
+}
+
+func readIDs(t *testing.T, r *FileReader) []int64 {
+	var ids []int64
+	for {
+		row, err := r.NextRow()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, row["id"].(int64))
+	}
+}
+
+func idRange(from, to int64) []int64 {
+	var ids []int64
+	for i := from; i < to; i++ {
+		ids = append(ids, i)
+	}
+	return ids
+}
+
+func TestPredicateFilter(t *testing.T) {
+	data := writePredicateTestFile(t)
+
+	tests := map[string]struct {
+		pred      Predicate
+		expected  []int64
+		rowGroups []int
+	}{
+		"eq": {
+			pred:      Eq(ColumnPath{"id"}, int64(25)),
+			expected:  []int64{25},
+			rowGroups: []int{2},
+		},
+		"eq-int": {
+			pred:      Eq(ColumnPath{"id"}, 25),
+			expected:  []int64{25},
+			rowGroups: []int{2},
+		},
+		"eq-no-match": {
+			pred: Eq(ColumnPath{"id"}, int64(50)),
+		},
+		"lt": {
+			pred:      Lt(ColumnPath{"id"}, int64(11)),
+			expected:  idRange(0, 11),
+			rowGroups: []int{0, 1},
+		},
+		"lt-boundary": {
+			pred:      Lt(ColumnPath{"id"}, int64(10)),
+			expected:  idRange(0, 10),
+			rowGroups: []int{0},
+		},
+		"gt": {
+			pred:      Gt(ColumnPath{"score"}, 19.5),
+			expected:  idRange(40, 50),
+			rowGroups: []int{4},
+		},
+		"in": {
+			pred:      In(ColumnPath{"id"}, int64(5), uint8(45), 100),
+			expected:  []int64{5, 45},
+			rowGroups: []int{0, 4},
+		},
+		"eq-string": {
+			pred:      Eq(ColumnPath{"name"}, "dc"),
+			expected:  []int64{32},
+			rowGroups: []int{3},
+		},
+		"lt-string": {
+			pred:      Lt(ColumnPath{"name"}, []byte("bc")),
+			expected:  idRange(0, 12),
+			rowGroups: []int{0, 1},
+		},
+		"is-null": {
+			pred:      IsNull(ColumnPath{"name"}),
+			expected:  idRange(20, 30),
+			rowGroups: []int{2},
+		},
+		"and": {
+			pred:      And(Gt(ColumnPath{"id"}, int64(15)), Lt(ColumnPath{"score"}, float32(10))),
+			expected:  idRange(16, 20),
+			rowGroups: []int{1},
+		},
+		"or": {
+			pred:      Or(Eq(ColumnPath{"id"}, int64(3)), IsNull(ColumnPath{"name"})),
+			expected:  append([]int64{3}, idRange(20, 30)...),
+			rowGroups: []int{0, 2},
+		},
+		"nan": {
+			pred: Eq(ColumnPath{"score"}, math.NaN()),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(tt.pred))
+			require.NoError(t, err)
+
+			var rowGroups []int
+			for i := 0; i < r.RowGroupCount(); i++ {
+				if r.rowGroupMightMatch(i) {
+					rowGroups = append(rowGroups, i)
+				}
+			}
+			require.Equal(t, tt.rowGroups, rowGroups)
+
+			require.Equal(t, tt.expected, readIDs(t, r))
+		})
+	}
+}
+
+func TestPredicateSeek(t *testing.T) {
+	data := writePredicateTestFile(t)
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(Or(
+		Eq(ColumnPath{"id"}, int64(25)),
+		Eq(ColumnPath{"id"}, int64(45)),
+	)))
+	require.NoError(t, err)
+
+	for _, seek := range []struct {
+		rowGroup int
+		row      int64
+		expected int64
+	}{
+		{rowGroup: 1, row: -1, expected: 25},
+		{rowGroup: 4, row: -1, expected: 45},
+		{row: 5, expected: 25},
+		{row: 25, expected: 25},
+		{row: 27, expected: 45},
+		{row: 35, expected: 45},
+	} {
+		if seek.row >= 0 {
+			require.NoError(t, r.SeekToRow(seek.row))
+		} else {
+			require.NoError(t, r.SeekToRowGroup(seek.rowGroup))
+		}
+		row, err := r.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, seek.expected, row["id"])
+	}
+
+	require.NoError(t, r.SeekToRowGroup(0))
+	require.Equal(t, []int64{25, 45}, readIDs(t, r))
+
+	require.Error(t, r.SeekToRowGroup(5))
+	require.Error(t, r.SeekToRowGroup(-1))
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(Eq(ColumnPath{"id"}, int64(25))))
+	require.NoError(t, err)
+
+	require.Equal(t, io.EOF, r.SeekToRow(35))
+	_, err = r.NextRow()
+	require.Equal(t, io.EOF, err)
+
+	require.Equal(t, io.EOF, r.SeekToRowGroup(3))
+	_, err = r.NextRow()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestPredicateColumns(t *testing.T) {
+	data := writePredicateTestFile(t)
+
+	_, err := NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(Eq(ColumnPath{"foo"}, int64(1))))
+	require.Error(t, err)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(nil))
+	require.Error(t, err)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(Eq(ColumnPath{"id"}, "25")))
+	require.Error(t, err)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(In(ColumnPath{"score"}, 1.0, 2)))
+	require.Error(t, err)
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data), WithPredicate(Eq(ColumnPath{"id"}, uint64(math.MaxUint64))))
+	require.Error(t, err)
+
+	// columns that are only read to evaluate the predicate are not returned.
+	r, err := NewFileReaderWithOptions(bytes.NewReader(data),
+		WithColumnPaths(ColumnPath{"id"}),
+		WithPredicate(Lt(ColumnPath{"name"}, "bc")),
+	)
+	require.NoError(t, err)
+	for i := int64(0); i < 12; i++ {
+		row, err := r.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"id": i}, row)
+	}
+	_, err = r.NextRow()
+	require.Equal(t, io.EOF, err)
+
+	target, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional double score;
+		optional int32 added;
+	}`)
+	require.NoError(t, err)
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data),
+		WithTargetSchemaDefinition(target),
+		WithPredicate(Eq(ColumnPath{"added"}, int32(1))),
+	)
+	require.NoError(t, err)
+	require.Empty(t, readIDs(t, r))
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data),
+		WithTargetSchemaDefinition(target),
+		WithPredicate(And(IsNull(ColumnPath{"added"}), Gt(ColumnPath{"id"}, 47))),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int64{48, 49}, readIDs(t, r))
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data),
+		WithTargetSchemaDefinition(target),
+		WithDefaultValue(ColumnPath{"added"}, int32(1)),
+		WithPredicate(Eq(ColumnPath{"added"}, 1)),
+	)
+	require.NoError(t, err)
+	require.Equal(t, idRange(0, 50), readIDs(t, r))
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(data),
+		WithTargetSchemaDefinition(target),
+		WithDefaultValue(ColumnPath{"added"}, int32(1)),
+		WithPredicate(Or(Gt(ColumnPath{"added"}, 1), IsNull(ColumnPath{"added"}))),
+	)
+	require.NoError(t, err)
+	require.Empty(t, readIDs(t, r))
+
+	_, err = NewFileReaderWithOptions(bytes.NewReader(data),
+		WithTargetSchemaDefinition(target),
+		WithPredicate(Eq(ColumnPath{"name"}, "bb")),
+	)
+	require.Error(t, err)
+}
+
+func TestPredicateLazyPages(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		required binary payload (STRING);
+	}`)
+	require.NoError(t, err)
+
+	for _, v2 := range []bool{false, true} {
+		opts := []FileWriterOption{WithSchemaDefinition(sd), WithMaxPageSize(1024), WithPageIndex(true), WithCompressionCodec(parquet.CompressionCodec_SNAPPY)}
+		if v2 {
+			opts = append(opts, WithDataPageV2())
+		}
+
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, opts...)
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, fw.AddData(map[string]interface{}{
+				"id":      int64(i),
+				"payload": []byte(fmt.Sprintf("payload %04d", i)),
+			}))
+		}
+		require.NoError(t, fw.Close())
+
+		r, err := NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()), WithPredicate(In(ColumnPath{"id"}, 3, 997)))
+		require.NoError(t, err)
+
+		row, err := r.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"id": int64(3), "payload": []byte("payload 0003")}, row)
+
+		row, err = r.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"id": int64(997), "payload": []byte("payload 0997")}, row)
+
+		_, err = r.NextRow()
+		require.Equal(t, io.EOF, err)
+
+		// only the first and the last page of the payload column contain matching rows, all other pages are
+		// never decompressed.
+		data := r.schemaReader.GetColumnByPath(ColumnPath{"payload"}).data
+		require.Greater(t, len(data.pages), 3)
+		require.Equal(t, 2, decompressedPages(data.pages), "v2: %t", v2)
+
+		data = r.schemaReader.GetColumnByPath(ColumnPath{"id"}).data
+		require.Equal(t, len(data.pages), decompressedPages(data.pages), "v2: %t", v2)
+	}
+}
+
+func decompressedPages(pages []pageReader) int {
+	n := 0
+	for _, p := range pages {
+		switch p := p.(type) {
+		case *dataPageReaderV1:
+			if p.block == nil {
+				n++
+			}
+		case *dataPageReaderV2:
+			if p.block == nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestPredicateNestedColumns(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		repeated int32 tags;
+		optional group info {
+			optional binary name (STRING);
+			required int32 age (INT(32, false));
+		}
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+	for i := 0; i < 20; i++ {
+		data := map[string]interface{}{
+			"id": int64(i),
+		}
+		if i%4 != 0 {
+			data["tags"] = []int32{int32(i), int32(i * 2)}
+		}
+		if i%5 != 0 {
+			data["info"] = map[string]interface{}{
+				"age": int32(-i),
+			}
+		}
+		require.NoError(t, fw.AddData(data))
+		if i%10 == 9 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	tests := map[string]struct {
+		pred     Predicate
+		expected []int64
+	}{
+		"repeated-eq": {
+			pred:     Eq(ColumnPath{"tags"}, 6),
+			expected: []int64{3, 6},
+		},
+		"repeated-is-null": {
+			pred:     IsNull(ColumnPath{"tags"}),
+			expected: []int64{0, 4, 8, 12, 16},
+		},
+		"nested-is-null": {
+			pred:     IsNull(ColumnPath{"info", "age"}),
+			expected: []int64{0, 5, 10, 15},
+		},
+		"nested-unsigned": {
+			pred:     Gt(ColumnPath{"info", "age"}, uint32(math.MaxUint32-2)),
+			expected: []int64{1, 2},
+		},
+		"nested-unsigned-int32": {
+			pred:     Lt(ColumnPath{"info", "age"}, int32(-18)),
+			expected: []int64{19},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()), WithPredicate(tt.pred))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, readIDs(t, r))
+		})
+	}
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()),
+		WithColumnPaths(ColumnPath{"id"}, ColumnPath{"info", "age"}),
+		WithPredicate(Eq(ColumnPath{"tags"}, 18)),
+	)
+	require.NoError(t, err)
+	row, err := r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"id":   int64(9),
+		"info": map[string]interface{}{"age": int32(-9)},
+	}, row)
+	row, err = r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"id":   int64(18),
+		"info": map[string]interface{}{"age": int32(-18)},
+	}, row)
+	_, err = r.NextRow()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestPredicateMightMatch(t *testing.T) {
+	int32Stats := func(order statsOrder, min, max int32) func(ColumnPath) *columnStatistics {
+		col := &predicateColumn{typ: parquet.Type_INT32, order: order}
+		s := &columnStatistics{col: col, numValues: 10, nullCount: int64Ptr(0)}
+		s.min, _ = col.normalize(min)
+		s.max, _ = col.normalize(max)
+		return func(ColumnPath) *columnStatistics { return s }
+	}
+
+	// unsigned order: 1 < 100 < 0xffffffff (-1 as int32).
+	unsigned := int32Stats(statsOrderUnsigned, 1, -1)
+	require.True(t, Eq(nil, int32(100)).mightMatch(unsigned))
+	require.True(t, Gt(nil, uint32(100)).mightMatch(unsigned))
+	require.False(t, Lt(nil, int32(1)).mightMatch(unsigned))
+	require.False(t, Eq(nil, 0).mightMatch(unsigned))
+
+	signed := int32Stats(statsOrderSigned, -1, 1)
+	require.True(t, Eq(nil, int32(0)).mightMatch(signed))
+	require.False(t, Eq(nil, int64(100)).mightMatch(signed))
+	require.False(t, IsNull(nil).mightMatch(signed))
+
+	noStats := func(ColumnPath) *columnStatistics {
+		return &columnStatistics{col: &predicateColumn{typ: parquet.Type_INT32}}
+	}
+	require.True(t, Eq(nil, int32(0)).mightMatch(noStats))
+	require.True(t, IsNull(nil).mightMatch(noStats))
+
+	allNull := func(ColumnPath) *columnStatistics {
+		return &columnStatistics{col: &predicateColumn{typ: parquet.Type_INT32}, numValues: 10, nullCount: int64Ptr(10)}
+	}
+	require.False(t, Eq(nil, int32(0)).mightMatch(allNull))
+	require.True(t, IsNull(nil).mightMatch(allNull))
+
+	require.True(t, And().mightMatch(noStats))
+	require.False(t, Or().mightMatch(noStats))
+	require.False(t, In(nil).mightMatch(noStats))
+	require.False(t, And(nil, Eq(nil, int32(100)), nil).mightMatch(signed))
+}
+
+func TestPredicateColumnStatistics(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int32 a (INT(32, false));
+		required int64 b;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+	require.NoError(t, fw.AddData(map[string]interface{}{"a": int32(-1), "b": int64(5)}))
+	require.NoError(t, fw.Close())
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(buf.Bytes()), WithPredicate(And(
+		IsNull(ColumnPath{"a"}),
+		IsNull(ColumnPath{"b"}),
+	)))
+	require.NoError(t, err)
+
+	a, b := r.predicateColumns["a"], r.predicateColumns["b"]
+
+	s := r.columnStatistics(0, a)
+	require.Equal(t, statsOrderUnsigned, a.order)
+	require.Equal(t, uint64(math.MaxUint32), s.min)
+
+	// without column orders, the min and max values of unsigned columns are ignored.
+	r.meta.ColumnOrders = nil
+	s = r.columnStatistics(0, a)
+	require.Nil(t, s.min)
+	require.Nil(t, s.max)
+
+	s = r.columnStatistics(0, b)
+	require.Equal(t, int64(5), s.min)
+	require.Equal(t, int64(5), s.max)
+}
@@ -0,0 +1,233 @@
+package goparquet
+
+import (
+	"context"
+	"io"
+	"sort"
+)
+
+// byteRange is a range of bytes within a file.
+type byteRange struct {
+	offset int64
+	length int64
+}
+
+func (r byteRange) end() int64 {
+	return r.offset + r.length
+}
+
+// coalesceRanges sorts the byte ranges by their offset and merges all ranges that overlap, or
+// where the gap between the end of one range and the start of the next one is at most maxGap.
+func coalesceRanges(ranges []byteRange, maxGap int64) []byteRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := append([]byteRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].offset < sorted[j].offset
+	})
+
+	merged := []byteRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.offset-last.end() > maxGap {
+			merged = append(merged, r)
+			continue
+		}
+		if r.end() > last.end() {
+			last.length = r.end() - last.offset
+		}
+	}
+
+	return merged
+}
+
+// fetchedRange is a byte range whose data has been read.
+type fetchedRange struct {
+	offset int64
+	data   []byte
+}
+
+// bufferedReaderAt serves reads from the fetched ranges if they start within one of them,
+// and all other reads, or the remainder of reads that extend past a fetched range, from the
+// underlying io.ReaderAt. It is safe for concurrent use.
+type bufferedReaderAt struct {
+	r io.ReaderAt
+
+	// ranges are sorted by their offset and don't overlap.
+	ranges []fetchedRange
+}
+
+func (b *bufferedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	idx := sort.Search(len(b.ranges), func(i int) bool {
+		return b.ranges[i].offset > off
+	}) - 1
+	if idx < 0 || off >= b.ranges[idx].offset+int64(len(b.ranges[idx].data)) {
+		return b.r.ReadAt(p, off)
+	}
+
+	fr := b.ranges[idx]
+	n := copy(p, fr.data[off-fr.offset:])
+	if n == len(p) {
+		return n, nil
+	}
+
+	m, err := b.r.ReadAt(p[n:], off+int64(n))
+	return n + m, err
+}
+
+// readPlanner plans the reads of row groups. Instead of reading each column chunk page by page,
+// which results in many small reads, the byte ranges of all column chunks of a row group that are
+// read are merged and fetched using a few large reads, and the next row group can be fetched in
+// the background while the current one is being read. This matters most when reading from remote
+// storage, where every read is a separate request.
+type readPlanner struct {
+	maxGap   int64
+	prefetch bool
+
+	// prefetched is the fetch of the next row group that was started while reading the current one.
+	prefetched *rowGroupFetch
+}
+
+// rowGroupFetch is the fetch of the column chunks of a row group, which possibly runs in the background.
+type rowGroupFetch struct {
+	rowGroup int
+	done     chan struct{}
+	cancel   context.CancelFunc
+	reader   *bufferedReaderAt
+	err      error
+}
+
+// rowGroupReaderAt returns the io.ReaderAt to read the column chunks of the row group from. If no read
+// planner is configured, this is the file itself. Otherwise, the column chunks are fetched, or the
+// prefetched data is used, and the fetch of the next row group is started if prefetching is enabled.
+// The prefetch is cancelled if another row group is read next, or if the context of the file reader
+// is done.
+func (f *FileReader) rowGroupReaderAt(ctx context.Context, rowGroup int) (io.ReaderAt, error) {
+	if f.planner == nil {
+		return f.readerAt, nil
+	}
+
+	fetch := f.planner.prefetched
+	f.planner.prefetched = nil
+
+	if fetch != nil && fetch.rowGroup == rowGroup {
+		<-fetch.done
+		if fetch.err != nil {
+			// the fetch is retried without prefetching, which also reports the error if it persists.
+			fetch = nil
+		}
+	} else if fetch != nil {
+		fetch.cancel()
+		fetch = nil
+	}
+
+	if fetch == nil {
+		fetch = f.startFetch(ctx, rowGroup)
+		<-fetch.done
+		if fetch.err != nil {
+			return nil, fetch.err
+		}
+	}
+
+	if f.planner.prefetch {
+		for next := rowGroup + 1; next < len(f.meta.RowGroups); next++ {
+			if f.rowGroupMightMatch(next) {
+				// the prefetch outlives the current call, so it uses the context of the file reader.
+				f.planner.prefetched = f.startFetch(f.ctx, next)
+				break
+			}
+		}
+	}
+
+	return fetch.reader, nil
+}
+
+// startFetch starts fetching the merged byte ranges of all column chunks of the row group that are read.
+// The ranges are determined immediately, but fetched in the background, concurrently if configured. The
+// context is checked before every range is fetched, and the fetch can be cancelled using its cancel function.
+func (f *FileReader) startFetch(ctx context.Context, rowGroup int) *rowGroupFetch {
+	ranges := coalesceRanges(f.columnChunkRanges(rowGroup), f.planner.maxGap)
+
+	ctx, cancel := context.WithCancel(ctx)
+	fetch := &rowGroupFetch{
+		rowGroup: rowGroup,
+		done:     make(chan struct{}),
+		cancel:   cancel,
+		reader: &bufferedReaderAt{
+			r:      f.readerAt,
+			ranges: make([]fetchedRange, len(ranges)),
+		},
+	}
+
+	go func() {
+		defer close(fetch.done)
+		defer cancel()
+
+		fetch.err = forEachConcurrently(f.concurrency, len(ranges), func(i int) (err error) {
+			defer f.recover(&err)
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			f.allocTracker.test(uint64(ranges[i].length))
+
+			data := make([]byte, ranges[i].length)
+			n, err := f.readerAt.ReadAt(data, ranges[i].offset)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			// the meta data may point beyond the end of the file, which is reported when reading the pages.
+			data = data[:n]
+			f.allocTracker.register(data, uint64(n))
+
+			fetch.reader.ranges[i] = fetchedRange{offset: ranges[i].offset, data: data}
+			return nil
+		})
+	}()
+
+	return fetch
+}
+
+// columnChunkRanges returns the byte ranges of all column chunks of the row group that are read, and
+// of the offset indexes of the column chunks whose pages are skipped using them if a predicate is configured.
+func (f *FileReader) columnChunkRanges(rowGroup int) []byteRange {
+	rg := f.meta.RowGroups[rowGroup]
+
+	var ranges []byteRange
+	for _, c := range f.schemaReader.Columns() {
+		if !f.schemaReader.isSelectedByPath(c.path) && !f.isPredicateColumn(c.path) {
+			continue
+		}
+		if c.Index() >= len(rg.Columns) || f.decryptor.inaccessibleColumn(c.path) {
+			continue
+		}
+
+		meta := rg.Columns[c.Index()].MetaData
+		if meta == nil {
+			continue
+		}
+
+		offset := meta.DataPageOffset
+		if meta.DictionaryPageOffset != nil {
+			offset = *meta.DictionaryPageOffset
+		}
+		// invalid ranges are left to the column chunk reader to report.
+		if offset < 0 || meta.TotalCompressedSize <= 0 || offset+meta.TotalCompressedSize > f.size {
+			continue
+		}
+
+		ranges = append(ranges, byteRange{offset: offset, length: meta.TotalCompressedSize})
+
+		if chunk := rg.Columns[c.Index()]; f.predicate != nil && !f.isPredicateColumn(c.path) && chunk.OffsetIndexOffset != nil && chunk.OffsetIndexLength != nil {
+			r := byteRange{offset: *chunk.OffsetIndexOffset, length: int64(*chunk.OffsetIndexLength)}
+			if r.offset >= 0 && r.length > 0 && r.end() <= f.size {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+
+	return ranges
+}
@@ -0,0 +1,301 @@
+package goparquet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesceRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranges   []byteRange
+		maxGap   int64
+		expected []byteRange
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "adjacent",
+			ranges:   []byteRange{{offset: 10, length: 5}, {offset: 4, length: 6}},
+			expected: []byteRange{{offset: 4, length: 11}},
+		},
+		{
+			name:     "gap",
+			ranges:   []byteRange{{offset: 4, length: 6}, {offset: 12, length: 5}},
+			expected: []byteRange{{offset: 4, length: 6}, {offset: 12, length: 5}},
+		},
+		{
+			name:     "small_gap",
+			ranges:   []byteRange{{offset: 4, length: 6}, {offset: 12, length: 5}, {offset: 30, length: 1}},
+			maxGap:   2,
+			expected: []byteRange{{offset: 4, length: 13}, {offset: 30, length: 1}},
+		},
+		{
+			name:     "overlapping",
+			ranges:   []byteRange{{offset: 4, length: 20}, {offset: 12, length: 5}, {offset: 20, length: 10}},
+			expected: []byteRange{{offset: 4, length: 26}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, coalesceRanges(tt.ranges, tt.maxGap))
+		})
+	}
+}
+
+func TestBufferedReaderAt(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	r := &countingReaderAt{r: bytes.NewReader(data)}
+	b := &bufferedReaderAt{
+		r: r,
+		ranges: []fetchedRange{
+			{offset: 2, data: data[2:6]},
+			{offset: 10, data: data[10:15]},
+		},
+	}
+
+	for _, tt := range []struct {
+		off, length int64
+		reads       int64
+	}{
+		{off: 2, length: 4, reads: 0},
+		{off: 11, length: 3, reads: 0},
+		{off: 0, length: 3, reads: 1},
+		{off: 4, length: 6, reads: 1},
+		{off: 15, length: 5, reads: 1},
+	} {
+		atomic.StoreInt64(&r.reads, 0)
+		buf := make([]byte, tt.length)
+		n, err := b.ReadAt(buf, tt.off)
+		require.NoError(t, err)
+		require.Equal(t, int(tt.length), n)
+		require.Equal(t, data[tt.off:tt.off+tt.length], buf)
+		require.Equal(t, tt.reads, atomic.LoadInt64(&r.reads), "reads at offset %d", tt.off)
+	}
+
+	_, err := b.ReadAt(make([]byte, 5), 18)
+	require.Equal(t, io.EOF, err)
+}
+
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	atomic.AddInt64(&c.reads, 1)
+	return c.r.ReadAt(p, off)
+}
+
+// httpReaderAt reads from a file served by an HTTP server using range requests.
+type httpReaderAt struct {
+	url      string
+	requests int64
+}
+
+func (h *httpReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	atomic.AddInt64(&h.requests, 1)
+
+	req, err := http.NewRequest(http.MethodGet, h.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestReadPlanner(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 a;
+		optional binary b (STRING);
+		required double c;
+		required int32 d;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithMaxPageSize(256), WithCompressionCodec(parquet.CompressionCodec_SNAPPY), WithPageIndex(true))
+	for i := 0; i < 500; i++ {
+		row := map[string]interface{}{
+			"a": int64(i),
+			"c": float64(i) / 2,
+			"d": int32(i % 10),
+		}
+		if i%4 != 0 {
+			row["b"] = []byte(fmt.Sprintf("value-%d", i))
+		}
+		require.NoError(t, fw.AddData(row))
+		if i%100 == 99 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	data := buf.Bytes()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.parquet", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	r, err := NewFileReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	expected := readAllRows(t, r)
+	require.Len(t, expected, 500)
+
+	readRemote := func(opts ...FileReaderOption) ([]map[string]interface{}, int64) {
+		h := &httpReaderAt{url: srv.URL}
+		r, err := NewFileReaderAt(h, int64(len(data)), opts...)
+		require.NoError(t, err)
+		atomic.StoreInt64(&h.requests, 0)
+		return readAllRows(t, r), atomic.LoadInt64(&h.requests)
+	}
+
+	rows, unplannedRequests := readRemote()
+	require.Equal(t, expected, rows)
+
+	// all column chunks of a row group are adjacent, so every row group is fetched using a single request.
+	rows, requests := readRemote(WithCoalescedReads(0))
+	require.Equal(t, expected, rows)
+	require.Equal(t, int64(5), requests)
+	require.Greater(t, unplannedRequests, 10*requests)
+
+	rows, requests = readRemote(WithPrefetch(true), WithReaderConcurrency(2))
+	require.Equal(t, expected, rows)
+	require.Equal(t, int64(5), requests)
+
+	// column b is not read, so the column chunks of a and c are only merged if the gap is small enough.
+	selected := []FileReaderOption{WithColumnPaths(ColumnPath{"a"}, ColumnPath{"c"})}
+	rows, requests = readRemote(append(selected, WithCoalescedReads(0))...)
+	require.Len(t, rows, 500)
+	require.Equal(t, int64(10), requests)
+	for i, row := range rows {
+		require.Equal(t, expected[i]["a"], row["a"])
+		require.Equal(t, expected[i]["c"], row["c"])
+		require.NotContains(t, row, "b")
+	}
+
+	rows, requests = readRemote(append(selected, WithCoalescedReads(1<<20), WithPrefetch(true))...)
+	require.Len(t, rows, 500)
+	require.Equal(t, int64(5), requests)
+
+	// row groups that are skipped because of the predicate are neither fetched nor prefetched. The offset
+	// indexes of the columns the predicate doesn't refer to are fetched using a request of their own.
+	rows, requests = readRemote(WithCoalescedReads(0), WithPrefetch(true), WithPredicate(Gt(ColumnPath{"a"}, 349)))
+	require.Equal(t, expected[350:], rows)
+	require.Equal(t, int64(4), requests)
+
+	h := &httpReaderAt{url: srv.URL}
+	r, err = NewFileReaderAt(h, int64(len(data)), WithCoalescedReads(0), WithPrefetch(true))
+	require.NoError(t, err)
+	require.NoError(t, r.SeekToRow(270))
+	require.Equal(t, expected[270:], readAllRows(t, r))
+
+	_, err = NewFileReaderAt(h, int64(len(data)), WithCoalescedReads(-1))
+	require.Error(t, err)
+}
+
+// cancelingReaderAt counts the reads per offset, and calls cancel when reading at cancelOffset.
+type cancelingReaderAt struct {
+	r            io.ReaderAt
+	cancelOffset int64
+	cancel       func()
+
+	mu    sync.Mutex
+	reads map[int64]int
+}
+
+func (c *cancelingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	c.reads[off]++
+	c.mu.Unlock()
+
+	if off == c.cancelOffset {
+		c.cancel()
+	}
+	return c.r.ReadAt(p, off)
+}
+
+func TestReadPlannerCancelPrefetch(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 a;
+		required int64 b;
+		required int64 c;
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+	for i := 0; i < 30; i++ {
+		require.NoError(t, fw.AddData(map[string]interface{}{"a": int64(i), "b": int64(2 * i), "c": int64(3 * i)}))
+		if i%10 == 9 {
+			require.NoError(t, fw.FlushRowGroup())
+		}
+	}
+	require.NoError(t, fw.Close())
+
+	data := buf.Bytes()
+	meta, err := ReadFileMetaData(bytes.NewReader(data), true)
+	require.NoError(t, err)
+
+	// columns a and c aren't adjacent, so they are fetched using two reads per row group.
+	chunkOffset := func(col int) int64 {
+		m := meta.RowGroups[1].Columns[col].MetaData
+		if m.DictionaryPageOffset != nil {
+			return *m.DictionaryPageOffset
+		}
+		return m.DataPageOffset
+	}
+	offsetA, offsetC := chunkOffset(0), chunkOffset(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ra := &cancelingReaderAt{r: bytes.NewReader(data), cancelOffset: offsetA, cancel: cancel, reads: make(map[int64]int)}
+
+	r, err := NewFileReaderAt(ra, int64(len(data)), WithReaderContext(ctx), WithColumnPaths(ColumnPath{"a"}, ColumnPath{"c"}), WithCoalescedReads(0), WithPrefetch(true))
+	require.NoError(t, err)
+
+	// reading the first row group starts the prefetch of the second one, which is cancelled while it
+	// reads column a, so column c isn't read. The second row group is then fetched again.
+	var rows []map[string]interface{}
+	for {
+		row, err := r.NextRowWithContext(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+	require.Len(t, rows, 30)
+	require.Equal(t, map[string]interface{}{"a": int64(15), "c": int64(45)}, rows[15])
+
+	require.Equal(t, 2, ra.reads[offsetA])
+	require.Equal(t, 1, ra.reads[offsetC])
+}
@@ -2,7 +2,9 @@ package goparquet
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -132,6 +134,23 @@ func TestWriteThenReadFile(t *testing.T) {
 			},
 			ReadOpts: []FileReaderOption{WithCRC32Validation(true)},
 		},
+		{
+			Name: "datapagev2_lz4_raw",
+			WriteOpts: []FileWriterOption{
+				WithCompressionCodec(parquet.CompressionCodec_LZ4_RAW),
+				WithCreator("parquet-go-unittest"),
+				WithDataPageV2(),
+			},
+			ReadOpts: []FileReaderOption{},
+		},
+		{
+			Name: "datapagev1_zstd",
+			WriteOpts: []FileWriterOption{
+				WithCompressionCodec(parquet.CompressionCodec_ZSTD),
+				WithCreator("parquet-go-unittest"),
+			},
+			ReadOpts: []FileReaderOption{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1203,6 +1222,7 @@ func TestReadWriteFloatEncodings(t *testing.T) {
 	}{
 		{name: "plain_no_dict", enc: parquet.Encoding_PLAIN, useDict: false, input: 1.1111},
 		{name: "plain_with_dict", enc: parquet.Encoding_PLAIN, useDict: true, input: 2.2222},
+		{name: "byte_stream_split_no_dict", enc: parquet.Encoding_BYTE_STREAM_SPLIT, useDict: false, input: 3.3333},
 	}
 
 	for _, tt := range testData {
@@ -1250,6 +1270,7 @@ func TestReadWriteDoubleEncodings(t *testing.T) {
 	}{
 		{name: "plain_no_dict", enc: parquet.Encoding_PLAIN, useDict: false, input: 42.123456},
 		{name: "plain_with_dict", enc: parquet.Encoding_PLAIN, useDict: true, input: 32.98765},
+		{name: "byte_stream_split_no_dict", enc: parquet.Encoding_BYTE_STREAM_SPLIT, useDict: false, input: 12.3456789},
 	}
 
 	for _, tt := range testData {
@@ -1502,3 +1523,258 @@ func TestRepeatedInt32(t *testing.T) {
 		int32(9001),
 	}, row["foo"])
 }
+
+func TestWriteCompressionLevels(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required binary a (STRING);
+		required binary b (STRING);
+	}`)
+	require.NoError(t, err)
+
+	writeFile := func(opts ...FileWriterOption) ([]byte, error) {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, append([]FileWriterOption{WithSchemaDefinition(sd), WithCompressionCodec(parquet.CompressionCodec_GZIP)}, opts...)...)
+		for i := 0; i < 1000; i++ {
+			value := []byte(fmt.Sprintf("lorem ipsum dolor sit amet %d, consectetur adipiscing elit %d", i, i%7))
+			if err := fw.AddData(map[string]interface{}{"a": value, "b": value}); err != nil {
+				return nil, err
+			}
+		}
+		if err := fw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	chunkSizes := func(data []byte) []int64 {
+		r, err := NewFileReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		for i := 0; i < 1000; i++ {
+			_, err := r.NextRow()
+			require.NoError(t, err)
+		}
+		var sizes []int64
+		for _, chunk := range r.meta.RowGroups[0].Columns {
+			sizes = append(sizes, chunk.MetaData.TotalCompressedSize)
+		}
+		return sizes
+	}
+
+	data, err := writeFile(WithCompressionLevel(parquet.CompressionCodec_GZIP, gzip.NoCompression))
+	require.NoError(t, err)
+	uncompressed := chunkSizes(data)
+
+	data, err = writeFile(
+		WithCompressionLevel(parquet.CompressionCodec_GZIP, gzip.NoCompression),
+		WithColumnCompressionLevel(ColumnPath{"b"}, gzip.BestCompression),
+	)
+	require.NoError(t, err)
+	sizes := chunkSizes(data)
+	require.Equal(t, uncompressed[0], sizes[0])
+	require.True(t, sizes[1] < uncompressed[1], "column b was not compressed: %d >= %d", sizes[1], uncompressed[1])
+
+	// levels of other codecs are ignored.
+	_, err = writeFile(WithCompressionLevel(parquet.CompressionCodec_SNAPPY, 1))
+	require.NoError(t, err)
+
+	_, err = writeFile(WithColumnCompressionLevel(ColumnPath{"c"}, 1))
+	require.Error(t, err)
+
+	_, err = writeFile(WithCompressionCodec(parquet.CompressionCodec_SNAPPY), WithCompressionLevel(parquet.CompressionCodec_SNAPPY, 1))
+	require.Error(t, err)
+}
+
+func TestWriteSortedDictionaries(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required binary name (STRING);
+		required int32 num (UINT_32);
+		optional double value;
+	}`)
+	require.NoError(t, err)
+
+	names := []string{"pear", "apple", "zucchini", "banana", "apple", "cherry", "pear"}
+	nums := []uint32{7, math.MaxUint32, 0, 3, 7, 1, 3}
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithSortedDictionaries(true), WithDataPageV2())
+	for i := range names {
+		data := map[string]interface{}{
+			"name": []byte(names[i]),
+			"num":  int32(nums[i]),
+		}
+		if i%3 != 0 {
+			data["value"] = float64(len(names) - i)
+		}
+		require.NoError(t, fw.AddData(data))
+	}
+	require.NoError(t, fw.Close())
+
+	meta, err := ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+
+	readDictHeader := func(chunk *parquet.ColumnChunk) (*parquet.PageHeader, []byte) {
+		require.NotNil(t, chunk.MetaData.DictionaryPageOffset)
+		r := bytes.NewReader(buf.Bytes()[*chunk.MetaData.DictionaryPageOffset:])
+		ph := &parquet.PageHeader{}
+		require.NoError(t, readThrift(context.Background(), ph, r))
+		require.Equal(t, parquet.PageType_DICTIONARY_PAGE, ph.Type)
+		page := make([]byte, ph.CompressedPageSize)
+		_, err := io.ReadFull(r, page)
+		require.NoError(t, err)
+		return ph, page
+	}
+
+	ph, page := readDictHeader(meta.RowGroups[0].Columns[0])
+	require.True(t, ph.DictionaryPageHeader.GetIsSorted())
+	dec := &byteArrayPlainDecoder{}
+	require.NoError(t, dec.init(bytes.NewReader(page)))
+	dictNames := make([]interface{}, ph.DictionaryPageHeader.NumValues)
+	_, err = dec.decodeValues(dictNames)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{[]byte("apple"), []byte("banana"), []byte("cherry"), []byte("pear"), []byte("zucchini")}, dictNames)
+
+	ph, page = readDictHeader(meta.RowGroups[0].Columns[1])
+	require.True(t, ph.DictionaryPageHeader.GetIsSorted())
+	dictNums := make([]uint32, ph.DictionaryPageHeader.NumValues)
+	require.NoError(t, binary.Read(bytes.NewReader(page), binary.LittleEndian, dictNums))
+	require.Equal(t, []uint32{0, 1, 3, 7, math.MaxUint32}, dictNums)
+
+	fr, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	for i := range names {
+		row, err := fr.NextRow()
+		require.NoError(t, err)
+		require.Equal(t, []byte(names[i]), row["name"])
+		require.Equal(t, int32(nums[i]), row["num"])
+		if i%3 != 0 {
+			require.Equal(t, float64(len(names)-i), row["value"])
+		} else {
+			require.NotContains(t, row, "value")
+		}
+	}
+	_, err = fr.NextRow()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestSortDictValues(t *testing.T) {
+	values := []interface{}{3.0, math.NaN(), -1.0, 2.0}
+	remap := sortDictValues(parquet.Type_DOUBLE, statsOrderSigned, values)
+	require.Equal(t, []int32{2, 3, 0, 1}, remap)
+	require.Equal(t, []interface{}{-1.0, 2.0, 3.0}, values[:3])
+	require.True(t, math.IsNaN(values[3].(float64)))
+
+	decimals := []interface{}{[]byte{0x01, 0x00}, []byte{0xff, 0x00}, []byte{0x00, 0x10}}
+	remap = sortDictValues(parquet.Type_FIXED_LEN_BYTE_ARRAY, statsOrderSigned, decimals)
+	require.Equal(t, []int32{2, 0, 1}, remap)
+	require.Equal(t, []interface{}{[]byte{0xff, 0x00}, []byte{0x00, 0x10}, []byte{0x01, 0x00}}, decimals)
+
+	require.Nil(t, sortDictValues(parquet.Type_INT96, statsOrderSigned, []interface{}{[12]byte{}}))
+}
+
+func TestWriteConcurrency(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional binary name (STRING);
+		required double score;
+		optional group tags (LIST) {
+			repeated group list {
+				required int32 element;
+			}
+		}
+		required boolean flag;
+	}`)
+	require.NoError(t, err)
+
+	writeFile := func(opts ...FileWriterOption) []byte {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, append([]FileWriterOption{
+			WithSchemaDefinition(sd),
+			WithCompressionCodec(parquet.CompressionCodec_SNAPPY),
+			WithMaxPageSize(512),
+			WithPageIndex(true),
+			WithBloomFilter(ColumnPath{"name"}, 100, 0.01),
+			WithSortedDictionaries(true),
+		}, opts...)...)
+		for i := 0; i < 1000; i++ {
+			row := map[string]interface{}{
+				"id":    int64(i),
+				"score": float64(i % 17),
+				"flag":  i%2 == 0,
+			}
+			if i%3 != 0 {
+				row["name"] = []byte(fmt.Sprintf("name-%d", i%50))
+			}
+			if i%4 != 0 {
+				row["tags"] = map[string]interface{}{
+					"list": []map[string]interface{}{{"element": int32(i)}, {"element": int32(i % 10)}},
+				}
+			}
+			require.NoError(t, fw.AddData(row))
+			if i%300 == 299 {
+				require.NoError(t, fw.FlushRowGroup())
+			}
+		}
+		require.NoError(t, fw.Close())
+		return buf.Bytes()
+	}
+
+	expected := writeFile()
+	require.Equal(t, expected, writeFile(WithWriterConcurrency(4)))
+	require.Equal(t, expected, writeFile(WithWriterConcurrency(16)))
+
+	r, err := NewFileReader(bytes.NewReader(expected))
+	require.NoError(t, err)
+	expectedRows := readAllRows(t, r)
+	require.Len(t, expectedRows, 1000)
+
+	encrypted := writeFile(WithWriterConcurrency(4), WithFooterKey(testFooterKey, []byte("footer key")), WithColumnKey(ColumnPath{"name"}, testColumnKey, []byte("column key")))
+	r, err = NewFileReaderWithOptions(bytes.NewReader(encrypted), WithKeyRetriever(testKeyRetriever))
+	require.NoError(t, err)
+	require.Equal(t, expectedRows, readAllRows(t, r))
+
+	r, err = NewFileReaderWithOptions(bytes.NewReader(encrypted), WithKeyRetriever(testKeyRetriever))
+	require.NoError(t, err)
+	require.NoError(t, r.SeekToRow(650))
+	require.Equal(t, expectedRows[650:], readAllRows(t, r))
+}
+
+type panicCompressor struct{}
+
+func (panicCompressor) CompressBlock([]byte) ([]byte, error) {
+	panic("compression failed")
+}
+
+func (panicCompressor) DecompressBlock([]byte) ([]byte, error) {
+	panic("decompression failed")
+}
+
+func TestWriteConcurrencyPanic(t *testing.T) {
+	// the compressors are shared by all tests, so the previous LZO compressor is restored.
+	compressorLock.Lock()
+	prev, registered := compressors[parquet.CompressionCodec_LZO]
+	compressorLock.Unlock()
+	defer func() {
+		compressorLock.Lock()
+		defer compressorLock.Unlock()
+		if registered {
+			compressors[parquet.CompressionCodec_LZO] = prev
+		} else {
+			delete(compressors, parquet.CompressionCodec_LZO)
+		}
+	}()
+	RegisterBlockCompressor(parquet.CompressionCodec_LZO, panicCompressor{})
+
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		required binary name (STRING);
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd), WithCompressionCodec(parquet.CompressionCodec_LZO), WithWriterConcurrency(2))
+	require.NoError(t, fw.AddData(map[string]interface{}{"id": int64(1), "name": []byte("foo")}))
+
+	err = fw.FlushRowGroup()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "compression failed")
+}
@@ -319,11 +319,17 @@ type schema struct {
 
 	maxPageSize int64
 
+	statsTruncateLength int
+
 	// selected columns in reading. if the size is zero, it means all the columns
 	selectedColumns []ColumnPath
 
-	enableCRC   bool // if true, CRC32 checksums will be computed for pages upon writing.
-	validateCRC bool // if true, CRC32 checksums will be validated for pages upon reading.
+	// noColumnsSelected is true if no column is read at all, as an empty selectedColumns selects all columns.
+	noColumnsSelected bool
+
+	enableCRC        bool // if true, CRC32 checksums will be computed for pages upon writing.
+	validateCRC      bool // if true, CRC32 checksums will be validated for pages upon reading.
+	sortDictionaries bool // if true, dictionary values will be sorted upon writing.
 
 	alloc *allocTracker
 }
@@ -346,9 +352,19 @@ func (r *schema) ensureRoot() {
 
 func (r *schema) SetSelectedColumns(cols ...ColumnPath) {
 	r.selectedColumns = cols
+	r.noColumnsSelected = false
+}
+
+// selectNoColumns deselects all columns, so that no column is read.
+func (r *schema) selectNoColumns() {
+	r.selectedColumns = nil
+	r.noColumnsSelected = true
 }
 
 func (r *schema) isSelectedByPath(path ColumnPath) bool {
+	if r.noColumnsSelected {
+		return false
+	}
 	if len(r.selectedColumns) == 0 {
 		return true
 	}
@@ -475,6 +491,8 @@ func (r *schema) SetSchemaDefinition(sd *parquetschema.SchemaDefinition) error {
 		recursiveFix(c, ColumnPath{}, 0, 0, r.alloc)
 	}
 
+	r.sortIndex()
+
 	return nil
 }
 
@@ -553,6 +571,7 @@ func (r *schema) getColumnStore(elem *parquet.SchemaElement, params *ColumnParam
 	}
 
 	colStore.maxPageSize = r.maxPageSize
+	colStore.statsTruncateLength = r.statsTruncateLength
 
 	return colStore, nil
 }
@@ -779,11 +798,85 @@ func (r *schema) AddData(m map[string]interface{}) error {
 		return err
 	}
 
-	if err := r.recursiveFlushPages(r.root.children); err != nil {
-		return err
+	// the record counter needs to be increased before the pages are flushed, otherwise
+	// the number of rows in a data page is off by one.
+	r.numRecords++
+
+	return r.recursiveFlushPages(r.root.children)
+}
+
+// addColumnBatch appends the values and levels of the batch to the data column with the provided path.
+func (r *schema) addColumnBatch(path ColumnPath, batch *ColumnBatch) error {
+	r.readOnly = 1
+	r.ensureRoot()
+
+	col := r.GetColumnByPath(path)
+	if col == nil {
+		return fmt.Errorf("column %s not found", path.flatName())
+	}
+	if col.data == nil {
+		return fmt.Errorf("column %s is not a data column", path.flatName())
 	}
 
-	r.numRecords++
+	numValues, err := checkBatchValues(col.data.parquetType(), col.data.params().TypeLength, batch.Values)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", path.flatName(), err)
+	}
+
+	numLevels := numValues
+	if batch.DefinitionLevels != nil {
+		numLevels = len(batch.DefinitionLevels)
+	} else if batch.RepetitionLevels != nil {
+		numLevels = len(batch.RepetitionLevels)
+	}
+	if batch.RepetitionLevels != nil && len(batch.RepetitionLevels) != numLevels {
+		return fmt.Errorf("column %s: got %d repetition levels but %d definition levels", path.flatName(), len(batch.RepetitionLevels), numLevels)
+	}
+
+	numDefined := numLevels
+	if batch.DefinitionLevels != nil {
+		numDefined = 0
+		for _, dl := range batch.DefinitionLevels {
+			if dl < 0 || dl > int32(col.maxD) {
+				return fmt.Errorf("column %s: invalid definition level %d, max definition level is %d", path.flatName(), dl, col.maxD)
+			}
+			if dl == int32(col.maxD) {
+				numDefined++
+			}
+		}
+	}
+	if numDefined != numValues {
+		return fmt.Errorf("column %s: got %d values but %d defined values", path.flatName(), numValues, numDefined)
+	}
+
+	for i, rl := range batch.RepetitionLevels {
+		if rl < 0 || rl > int32(col.maxR) {
+			return fmt.Errorf("column %s: invalid repetition level %d, max repetition level is %d", path.flatName(), rl, col.maxR)
+		}
+		if i == 0 && rl != 0 {
+			return fmt.Errorf("column %s: the first repetition level needs to be 0", path.flatName())
+		}
+	}
+
+	if err := col.data.addBatch(batch.Values, batch.DefinitionLevels, batch.RepetitionLevels, numLevels, col.maxD); err != nil {
+		return fmt.Errorf("column %s: %w", path.flatName(), err)
+	}
+
+	if col.data.numRows > r.numRecords {
+		r.numRecords = col.data.numRows
+	}
+
+	return nil
+}
+
+// checkNumRecords returns an error if a data column doesn't contain the number of rows of the current
+// row group, which is only possible if columns have been added using addColumnBatch.
+func (r *schema) checkNumRecords() error {
+	for _, c := range r.Columns() {
+		if c.data.numRows != r.numRecords {
+			return fmt.Errorf("column %s contains %d rows, but the row group contains %d rows", c.path.flatName(), c.data.numRows, r.numRecords)
+		}
+	}
 	return nil
 }
 
@@ -821,7 +914,7 @@ func (r *schema) recursiveAddColumnNil(c []*Column, defLvl, maxRepLvl uint16, re
 func (r *schema) recursiveFlushPages(c []*Column) error {
 	for i := range c {
 		if c[i].data != nil {
-			if err := c[i].data.flushPage(r, false); err != nil {
+			if err := c[i].data.flushPage(false); err != nil {
 				return err
 			}
 		}
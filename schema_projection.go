@@ -0,0 +1,360 @@
+package goparquet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+)
+
+// schemaProjection projects the rows read from a file onto a target schema. Columns that
+// are missing in the file are filled with their default value or null, columns that are
+// missing in the target schema are dropped, and values of columns whose type was promoted
+// are converted to the target type.
+type schemaProjection struct {
+	target  *parquetschema.SchemaDefinition
+	columns []*projectedColumn
+}
+
+type projectedColumn struct {
+	name     string
+	rep      parquet.FieldRepetitionType
+	children []*projectedColumn // nil for data columns.
+
+	// inFile is false if the column doesn't exist in the file.
+	inFile bool
+
+	// promote converts the value read from the file to the target type. It is nil
+	// if the file and the target schema have the same type.
+	promote func(interface{}) interface{}
+
+	defaultValue interface{} // nil if the column has no default value.
+}
+
+// newSchemaProjection resolves the schema of a file against the target schema. It returns the
+// projection and the paths of all data columns of the file that are part of the target schema.
+func newSchemaProjection(file, target *parquetschema.SchemaDefinition, defaults map[string]interface{}) (*schemaProjection, []ColumnPath, error) {
+	if target == nil || target.RootColumn == nil {
+		return nil, nil, errors.New("target schema definition is empty")
+	}
+
+	p := &schemaProjection{target: target}
+
+	var fileCols []*parquetschema.ColumnDefinition
+	if file != nil && file.RootColumn != nil {
+		fileCols = file.RootColumn.Children
+	}
+
+	var (
+		selected []ColumnPath
+		err      error
+	)
+	p.columns, err = resolveColumns(ColumnPath{}, fileCols, target.RootColumn.Children, defaults, &selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for path := range defaults {
+		col := findColumnDefinition(target.RootColumn, parseColumnPath(path))
+		if col == nil || len(col.Children) > 0 {
+			return nil, nil, fmt.Errorf("default value configured for unknown column %s", path)
+		}
+	}
+
+	return p, selected, nil
+}
+
+func resolveColumns(parent ColumnPath, fileCols, targetCols []*parquetschema.ColumnDefinition, defaults map[string]interface{}, selected *[]ColumnPath) ([]*projectedColumn, error) {
+	cols := make([]*projectedColumn, 0, len(targetCols))
+	for _, targetCol := range targetCols {
+		var fileCol *parquetschema.ColumnDefinition
+		for _, c := range fileCols {
+			if c.SchemaElement.GetName() == targetCol.SchemaElement.GetName() {
+				fileCol = c
+				break
+			}
+		}
+
+		col, err := resolveColumn(append(parent[:len(parent):len(parent)], targetCol.SchemaElement.GetName()), fileCol, targetCol, defaults, selected)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func resolveColumn(path ColumnPath, fileCol, targetCol *parquetschema.ColumnDefinition, defaults map[string]interface{}, selected *[]ColumnPath) (*projectedColumn, error) {
+	te := targetCol.SchemaElement
+	col := &projectedColumn{
+		name:   te.GetName(),
+		rep:    te.GetRepetitionType(),
+		inFile: fileCol != nil,
+	}
+
+	isGroup := len(targetCol.Children) > 0
+
+	if def, ok := defaults[path.flatName()]; ok && !isGroup {
+		if err := checkDefaultValue(te, def); err != nil {
+			return nil, fmt.Errorf("invalid default value for column %s: %w", path.flatName(), err)
+		}
+		col.defaultValue = def
+	}
+
+	if fileCol == nil {
+		switch {
+		case isGroup && col.rep != parquet.FieldRepetitionType_REQUIRED:
+			// missing groups that aren't required are null, regardless of their children.
+			col.children = []*projectedColumn{}
+		case isGroup:
+			var err error
+			col.children, err = resolveColumns(path, nil, targetCol.Children, defaults, selected)
+			if err != nil {
+				return nil, err
+			}
+		case col.rep == parquet.FieldRepetitionType_REQUIRED && col.defaultValue == nil:
+			return nil, fmt.Errorf("column %s is required in the target schema but missing in the file and has no default value", path.flatName())
+		}
+		return col, nil
+	}
+
+	fe := fileCol.SchemaElement
+	if isGroup != (len(fileCol.Children) > 0) {
+		return nil, fmt.Errorf("column %s is a group in only one of the file and the target schema", path.flatName())
+	}
+
+	if err := checkRepetitionType(fe.GetRepetitionType(), te.GetRepetitionType()); err != nil {
+		return nil, fmt.Errorf("column %s: %w", path.flatName(), err)
+	}
+
+	if isGroup {
+		var err error
+		col.children, err = resolveColumns(path, fileCol.Children, targetCol.Children, defaults, selected)
+		if err != nil {
+			return nil, err
+		}
+		return col, nil
+	}
+
+	promote, err := typePromotion(fe, te)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", path.flatName(), err)
+	}
+	col.promote = promote
+
+	*selected = append(*selected, path)
+
+	return col, nil
+}
+
+func checkRepetitionType(file, target parquet.FieldRepetitionType) error {
+	if file == target {
+		return nil
+	}
+	if file == parquet.FieldRepetitionType_REQUIRED && target == parquet.FieldRepetitionType_OPTIONAL {
+		return nil
+	}
+	return fmt.Errorf("repetition type %s can't be read as %s", file, target)
+}
+
+// typePromotion returns the function to convert values of the file's column type to the target
+// column type, or nil if no conversion is needed. Which types can be read as which other types
+// is decided by parquetschema.CanPromote.
+func typePromotion(file, target *parquet.SchemaElement) (func(interface{}) interface{}, error) {
+	if !parquetschema.CanPromote(file, target) {
+		return nil, fmt.Errorf("type %s can't be read as %s", elementTypeString(file), elementTypeString(target))
+	}
+
+	switch {
+	case file.GetType() == parquet.Type_INT32 && target.GetType() == parquet.Type_INT64:
+		return promoteInt32ToInt64, nil
+	case file.GetType() == parquet.Type_FLOAT && target.GetType() == parquet.Type_DOUBLE:
+		return promoteFloatToDouble, nil
+	}
+
+	return nil, nil
+}
+
+func elementTypeString(elem *parquet.SchemaElement) string {
+	s := elem.GetType().String()
+	switch {
+	case elem.LogicalType != nil:
+		s += fmt.Sprintf(" (%s)", elem.LogicalType)
+	case elem.ConvertedType != nil:
+		s += fmt.Sprintf(" (%s)", elem.ConvertedType)
+	}
+	return s
+}
+
+func promoteInt32ToInt64(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int32:
+		return int64(x)
+	case []int32:
+		ret := make([]int64, len(x))
+		for i := range x {
+			ret[i] = int64(x[i])
+		}
+		return ret
+	}
+	return v
+}
+
+func promoteFloatToDouble(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float32:
+		return float64(x)
+	case []float32:
+		ret := make([]float64, len(x))
+		for i := range x {
+			ret[i] = float64(x[i])
+		}
+		return ret
+	}
+	return v
+}
+
+// checkDefaultValue checks that the default value has the Go type that is used for
+// values of the column's physical type.
+func checkDefaultValue(elem *parquet.SchemaElement, v interface{}) error {
+	if elem.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+		return fmt.Errorf("default values are not supported for repeated columns")
+	}
+
+	var ok bool
+	switch elem.GetType() {
+	case parquet.Type_BOOLEAN:
+		_, ok = v.(bool)
+	case parquet.Type_INT32:
+		_, ok = v.(int32)
+	case parquet.Type_INT64:
+		_, ok = v.(int64)
+	case parquet.Type_INT96:
+		_, ok = v.([12]byte)
+	case parquet.Type_FLOAT:
+		_, ok = v.(float32)
+	case parquet.Type_DOUBLE:
+		_, ok = v.(float64)
+	case parquet.Type_BYTE_ARRAY:
+		_, ok = v.([]byte)
+	case parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		var b []byte
+		if b, ok = v.([]byte); ok && int32(len(b)) != elem.GetTypeLength() {
+			return fmt.Errorf("the size of the value should be %d but is %d", elem.GetTypeLength(), len(b))
+		}
+	}
+	if !ok {
+		return fmt.Errorf("unsupported type %T for type %s", v, elem.GetType())
+	}
+	return nil
+}
+
+func findColumnDefinition(col *parquetschema.ColumnDefinition, path ColumnPath) *parquetschema.ColumnDefinition {
+	for _, name := range path {
+		var next *parquetschema.ColumnDefinition
+		for _, c := range col.Children {
+			if c.SchemaElement.GetName() == name {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		col = next
+	}
+	return col
+}
+
+// project projects a row read from the file onto the target schema.
+func (p *schemaProjection) project(row map[string]interface{}) map[string]interface{} {
+	return projectGroup(row, p.columns)
+}
+
+func projectGroup(data map[string]interface{}, cols []*projectedColumn) map[string]interface{} {
+	ret := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		if !col.inFile {
+			if v := col.missingValue(); v != nil {
+				ret[col.name] = v
+			}
+			continue
+		}
+
+		v, ok := data[col.name]
+		if !ok {
+			continue
+		}
+
+		if col.children == nil {
+			if col.promote != nil {
+				v = col.promote(v)
+			}
+			ret[col.name] = v
+			continue
+		}
+
+		switch x := v.(type) {
+		case map[string]interface{}:
+			ret[col.name] = projectGroup(x, col.children)
+		case []map[string]interface{}:
+			groups := make([]map[string]interface{}, len(x))
+			for i := range x {
+				groups[i] = projectGroup(x[i], col.children)
+			}
+			ret[col.name] = groups
+		}
+	}
+	return ret
+}
+
+// missingValue returns the value of a column that doesn't exist in the file. For data columns, this
+// is the default value. Required groups consist of the missing values of their children, all
+// other groups are null.
+func (col *projectedColumn) missingValue() interface{} {
+	if col.children == nil {
+		return col.defaultValue
+	}
+
+	if col.rep != parquet.FieldRepetitionType_REQUIRED {
+		return nil
+	}
+
+	ret := make(map[string]interface{}, len(col.children))
+	for _, c := range col.children {
+		if v := c.missingValue(); v != nil {
+			ret[c.name] = v
+		}
+	}
+	return ret
+}
+
+// defaultValue returns the default value of the data column with the provided path, or nil if it has none.
+func (p *schemaProjection) defaultValue(path ColumnPath) interface{} {
+	if p == nil {
+		return nil
+	}
+
+	cols := p.columns
+	for i, name := range path {
+		var col *projectedColumn
+		for _, c := range cols {
+			if c.name == name {
+				col = c
+				break
+			}
+		}
+		if col == nil {
+			return nil
+		}
+		if i == len(path)-1 {
+			if col.children != nil {
+				return nil
+			}
+			return col.defaultValue
+		}
+		cols = col.children
+	}
+	return nil
+}
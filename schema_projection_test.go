@@ -0,0 +1,190 @@
+package goparquet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSchemaProjectionTestFile(t *testing.T) []byte {
+	sd, err := parquetschema.ParseSchemaDefinition(`message v1 {
+		required int64 id;
+		required int32 count;
+		optional float score;
+		required binary legacy (STRING);
+		optional group info {
+			required binary name (STRING);
+			repeated int32 tags;
+		}
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+	require.NoError(t, fw.AddData(map[string]interface{}{
+		"id":     int64(1),
+		"count":  int32(10),
+		"score":  float32(1.5),
+		"legacy": []byte("foo"),
+		"info": map[string]interface{}{
+			"name": []byte("alice"),
+			"tags": []int32{1, 2},
+		},
+	}))
+	require.NoError(t, fw.AddData(map[string]interface{}{
+		"id":     int64(2),
+		"count":  int32(-20),
+		"legacy": []byte("bar"),
+	}))
+	require.NoError(t, fw.Close())
+
+	return buf.Bytes()
+}
+
+func TestReadWithTargetSchemaDefinition(t *testing.T) {
+	data := writeSchemaProjectionTestFile(t)
+
+	target, err := parquetschema.ParseSchemaDefinition(`message v2 {
+		required int64 id;
+		optional int64 count;
+		optional double score;
+		required binary country (STRING);
+		optional int32 age;
+		optional group info {
+			optional binary name (STRING);
+			repeated int64 tags;
+			optional binary email (STRING);
+		}
+		required group extra {
+			required boolean flag;
+			optional int32 x;
+		}
+		optional group address {
+			required binary city (STRING);
+		}
+	}`)
+	require.NoError(t, err)
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(data),
+		WithTargetSchemaDefinition(target),
+		WithDefaultValue(ColumnPath{"country"}, []byte("unknown")),
+		WithDefaultValue(ColumnPath{"extra", "flag"}, true),
+	)
+	require.NoError(t, err)
+	require.Equal(t, target, r.GetSchemaDefinition())
+
+	row, err := r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"id":      int64(1),
+		"count":   int64(10),
+		"score":   float64(1.5),
+		"country": []byte("unknown"),
+		"info": map[string]interface{}{
+			"name": []byte("alice"),
+			"tags": []int64{1, 2},
+		},
+		"extra": map[string]interface{}{
+			"flag": true,
+		},
+	}, row)
+
+	row, err = r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"id":      int64(2),
+		"count":   int64(-20),
+		"country": []byte("unknown"),
+		"extra": map[string]interface{}{
+			"flag": true,
+		},
+	}, row)
+
+	_, err = r.NextRow()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestReadWithTargetSchemaDefinitionAndColumns(t *testing.T) {
+	data := writeSchemaProjectionTestFile(t)
+
+	target, err := parquetschema.ParseSchemaDefinition(`message v2 {
+		required int64 id;
+		required int32 count;
+	}`)
+	require.NoError(t, err)
+
+	r, err := NewFileReaderWithOptions(bytes.NewReader(data), WithTargetSchemaDefinition(target), WithColumnPaths(ColumnPath{"count"}, ColumnPath{"legacy"}))
+	require.NoError(t, err)
+
+	row, err := r.NextRow()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"count": int32(10)}, row)
+}
+
+func TestReadWithTargetSchemaDefinitionWithoutFileColumns(t *testing.T) {
+	data := writeSchemaProjectionTestFile(t)
+
+	target, err := parquetschema.ParseSchemaDefinition(`message v2 {
+		required binary country (STRING);
+		optional int32 age;
+	}`)
+	require.NoError(t, err)
+
+	for _, opts := range [][]FileReaderOption{
+		{WithTargetSchemaDefinition(target), WithDefaultValue(ColumnPath{"country"}, []byte("unknown"))},
+		// the selected columns are not part of the target schema.
+		{WithTargetSchemaDefinition(target), WithDefaultValue(ColumnPath{"country"}, []byte("unknown")), WithColumnPaths(ColumnPath{"id"})},
+	} {
+		r, err := NewFileReaderWithOptions(bytes.NewReader(data), opts...)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			row, err := r.NextRow()
+			require.NoError(t, err)
+			require.Equal(t, map[string]interface{}{"country": []byte("unknown")}, row)
+		}
+		_, err = r.NextRow()
+		require.Equal(t, io.EOF, err)
+
+		// none of the column chunks of the file have been read.
+		for _, col := range r.schemaReader.Columns() {
+			require.True(t, col.data.skipped, col.path.flatName())
+		}
+	}
+}
+
+func TestReadWithTargetSchemaDefinitionErrors(t *testing.T) {
+	data := writeSchemaProjectionTestFile(t)
+
+	tests := []struct {
+		schema string
+		opts   []FileReaderOption
+	}{
+		{`message v2 { required binary id; }`, nil},
+		{`message v2 { required int32 id; }`, nil},
+		{`message v2 { optional int64 id (TIMESTAMP(MILLIS, true)); }`, nil},
+		{`message v2 { required float count; }`, nil},
+		{`message v2 { required float score; }`, nil},
+		{`message v2 { repeated int64 id; }`, nil},
+		{`message v2 { required int64 legacy; }`, nil},
+		{`message v2 { required group id { required int64 x; } }`, nil},
+		{`message v2 { optional group info { required binary name (STRING); required int32 tags; } }`, nil},
+		{`message v2 { required int64 id; required int32 age; }`, nil},
+		{`message v2 { required int64 id; required int32 age; }`, []FileReaderOption{WithDefaultValue(ColumnPath{"age"}, int64(3))}},
+		{`message v2 { required int64 id; }`, []FileReaderOption{WithDefaultValue(ColumnPath{"age"}, int32(3))}},
+	}
+
+	for idx, tt := range tests {
+		target, err := parquetschema.ParseSchemaDefinition(tt.schema)
+		require.NoError(t, err, "%d. parsing schema failed", idx)
+
+		_, err = NewFileReaderWithOptions(bytes.NewReader(data), append(tt.opts, WithTargetSchemaDefinition(target))...)
+		require.Error(t, err, "%d. expected error", idx)
+	}
+
+	_, err := NewFileReaderWithOptions(bytes.NewReader(data), WithDefaultValue(ColumnPath{"age"}, int32(3)))
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package goparquet
+
+import (
+	"fmt"
+
+	"github.com/fraugster/parquet-go/parquet"
+)
+
+// SortingColumn describes a column that the rows of a row group are sorted by. Sorting columns
+// are declared in the order of their precedence, i.e. rows are sorted by the first sorting column,
+// rows with equal values in the first sorting column are sorted by the second sorting column and
+// so on. The declaration only describes the sort order, it is up to the caller to actually add
+// the rows in this order.
+type SortingColumn struct {
+	// Path is the path of the column.
+	Path ColumnPath
+	// Descending is true if the column is sorted in descending order.
+	Descending bool
+	// NullsFirst is true if null values are sorted before all other values.
+	NullsFirst bool
+}
+
+// toParquetSortingColumns validates the sorting columns against the schema and converts them
+// to their representation in the row group meta data.
+func toParquetSortingColumns(sch *schema, cols []SortingColumn) ([]*parquet.SortingColumn, error) {
+	if len(cols) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	result := make([]*parquet.SortingColumn, 0, len(cols))
+	for _, sc := range cols {
+		col := sch.GetColumnByPath(sc.Path)
+		if col == nil || !col.DataColumn() {
+			return nil, fmt.Errorf("sorting column %s not found", sc.Path.flatName())
+		}
+
+		if seen[sc.Path.flatName()] {
+			return nil, fmt.Errorf("sorting column %s declared multiple times", sc.Path.flatName())
+		}
+		seen[sc.Path.flatName()] = true
+
+		result = append(result, &parquet.SortingColumn{
+			ColumnIdx:  int32(col.Index()),
+			Descending: sc.Descending,
+			NullsFirst: sc.NullsFirst,
+		})
+	}
+
+	return result, nil
+}
+
+// fromParquetSortingColumns converts the sorting columns of the row group meta data to SortingColumns.
+func fromParquetSortingColumns(sch *schema, cols []*parquet.SortingColumn) ([]SortingColumn, error) {
+	if len(cols) == 0 {
+		return nil, nil
+	}
+
+	dataCols := sch.Columns()
+	result := make([]SortingColumn, 0, len(cols))
+	for _, sc := range cols {
+		if sc.ColumnIdx < 0 || int(sc.ColumnIdx) >= len(dataCols) {
+			return nil, fmt.Errorf("sorting column index %d is out of range", sc.ColumnIdx)
+		}
+
+		result = append(result, SortingColumn{
+			Path:       dataCols[sc.ColumnIdx].Path(),
+			Descending: sc.Descending,
+			NullsFirst: sc.NullsFirst,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package goparquet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSortingColumns(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional group info {
+			optional binary name (STRING);
+			required int32 age;
+		}
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd),
+		WithSortingColumns(
+			SortingColumn{Path: ColumnPath{"id"}},
+			SortingColumn{Path: ColumnPath{"info", "age"}, Descending: true, NullsFirst: true},
+		),
+	)
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, fw.AddData(map[string]interface{}{
+			"id": int64(i),
+			"info": map[string]interface{}{
+				"age": int32(30 - i),
+			},
+		}))
+		switch i {
+		case 9:
+			require.NoError(t, fw.FlushRowGroup())
+		case 19:
+			require.NoError(t, fw.FlushRowGroup(WithRowGroupSortingColumns(SortingColumn{Path: ColumnPath{"info", "name"}})))
+		}
+	}
+	require.NoError(t, fw.FlushRowGroup(WithRowGroupSortingColumns()))
+	require.NoError(t, fw.Close())
+
+	r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 3, r.RowGroupCount())
+
+	cols, err := r.CurrentRowGroupSortingColumns()
+	require.NoError(t, err)
+	require.Nil(t, cols)
+
+	expected := [][]SortingColumn{
+		{
+			{Path: ColumnPath{"id"}},
+			{Path: ColumnPath{"info", "age"}, Descending: true, NullsFirst: true},
+		},
+		{
+			{Path: ColumnPath{"info", "name"}},
+		},
+		nil,
+	}
+
+	for i, exp := range expected {
+		cols, err := r.RowGroupSortingColumns(i)
+		require.NoError(t, err)
+		require.Equal(t, exp, cols, "row group %d", i)
+
+		for j := int64(0); j < r.NumRows()/3; j++ {
+			_, err := r.NextRow()
+			require.NoError(t, err)
+		}
+
+		cols, err = r.CurrentRowGroupSortingColumns()
+		require.NoError(t, err)
+		require.Equal(t, exp, cols, "current row group %d", i)
+	}
+
+	_, err = r.RowGroupSortingColumns(3)
+	require.Error(t, err)
+}
+
+func TestWriteSortingColumnsInvalid(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int64 id;
+		optional group info {
+			optional binary name (STRING);
+		}
+	}`)
+	require.NoError(t, err)
+
+	for _, cols := range [][]SortingColumn{
+		{{Path: ColumnPath{"foo"}}},
+		{{Path: ColumnPath{"info"}}},
+		{{Path: ColumnPath{"id"}}, {Path: ColumnPath{"id"}, Descending: true}},
+	} {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+		require.NoError(t, fw.AddData(map[string]interface{}{"id": int64(1)}))
+		require.Error(t, fw.FlushRowGroup(WithRowGroupSortingColumns(cols...)))
+	}
+}
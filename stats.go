@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"math"
+
+	"github.com/fraugster/parquet-go/parquet"
 )
 
 type nilStats struct{}
@@ -19,9 +21,112 @@ func (s *nilStats) maxValue() []byte {
 func (s *nilStats) reset() {
 }
 
+// statsOrder is the order in which the min and max values of a column are
+// determined. The order depends on the physical type and the logical type of the
+// column, as defined by the TypeDefinedOrder in the parquet specification.
+type statsOrder int
+
+const (
+	// statsOrderSigned compares integers as signed values, and byte arrays as
+	// big endian two's complement numbers, as they are used for decimals.
+	statsOrderSigned statsOrder = iota
+	// statsOrderUnsigned compares integers as unsigned values, and byte arrays
+	// lexicographically as unsigned bytes.
+	statsOrderUnsigned
+)
+
+// columnStatsOrder returns the order of the min and max values of a column with the
+// provided physical type and column parameters.
+func columnStatsOrder(typ parquet.Type, params *ColumnParameters) statsOrder {
+	switch typ {
+	case parquet.Type_INT32, parquet.Type_INT64:
+		if params.isUnsignedInteger() {
+			return statsOrderUnsigned
+		}
+		return statsOrderSigned
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		if params.isDecimal() {
+			return statsOrderSigned
+		}
+		return statsOrderUnsigned
+	}
+	return statsOrderSigned
+}
+
+// defaultStatsTruncateLength is the default maximum length of the min and max values of byte array columns.
+const defaultStatsTruncateLength = 64
+
+// truncateMinValue returns a prefix of at most length bytes of the min value of a byte array column, which
+// is still a lower bound of all values. Only byte arrays that are compared as unsigned bytes are truncated,
+// fixed length byte arrays are bounded by their type length. If length is not positive, v is returned.
+func truncateMinValue(typ parquet.Type, order statsOrder, v []byte, length int) []byte {
+	if typ != parquet.Type_BYTE_ARRAY || order != statsOrderUnsigned || length <= 0 || len(v) <= length {
+		return v
+	}
+	return append([]byte(nil), v[:length]...)
+}
+
+// truncateMaxValue returns a value of at most length bytes that is still an upper bound of all values, by
+// incrementing the last byte of the prefix that can be incremented. If all bytes of the prefix are 0xff, v
+// is returned, as are values that truncateMinValue doesn't truncate.
+func truncateMaxValue(typ parquet.Type, order statsOrder, v []byte, length int) []byte {
+	if typ != parquet.Type_BYTE_ARRAY || order != statsOrderUnsigned || length <= 0 || len(v) <= length {
+		return v
+	}
+	for i := length - 1; i >= 0; i-- {
+		if v[i] != 0xff {
+			max := append([]byte(nil), v[:i+1]...)
+			max[i]++
+			return max
+		}
+	}
+	return v
+}
+
+// typeDefinedColumnOrders returns the column orders of all data columns of the schema. The
+// min and max values of all columns are determined by the order defined by their types. The order
+// of INT96 columns is undefined, which is why no statistics are written for them.
+func typeDefinedColumnOrders(sch *schema) []*parquet.ColumnOrder {
+	cols := sch.Columns()
+	orders := make([]*parquet.ColumnOrder, 0, len(cols))
+	for range cols {
+		orders = append(orders, &parquet.ColumnOrder{
+			TYPE_ORDER: parquet.NewTypeDefinedOrder(),
+		})
+	}
+	return orders
+}
+
+func (p *ColumnParameters) isUnsignedInteger() bool {
+	if p == nil {
+		return false
+	}
+	if p.LogicalType != nil && p.LogicalType.IsSetINTEGER() {
+		return !p.LogicalType.INTEGER.IsSigned
+	}
+	if p.ConvertedType != nil {
+		switch *p.ConvertedType {
+		case parquet.ConvertedType_UINT_8, parquet.ConvertedType_UINT_16, parquet.ConvertedType_UINT_32, parquet.ConvertedType_UINT_64:
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ColumnParameters) isDecimal() bool {
+	if p == nil {
+		return false
+	}
+	if p.LogicalType != nil && p.LogicalType.IsSetDECIMAL() {
+		return true
+	}
+	return p.ConvertedType != nil && *p.ConvertedType == parquet.ConvertedType_DECIMAL
+}
+
 type statistics struct {
-	min []byte
-	max []byte
+	min   []byte
+	max   []byte
+	order statsOrder
 }
 
 func (s *statistics) minValue() []byte {
@@ -43,17 +148,21 @@ func (s *statistics) setMinMax(j []byte) {
 		return
 	}
 
-	if bytes.Compare(j, s.min) < 0 {
+	if compareByteArrays(s.order, j, s.min) < 0 {
 		s.min = j
 	}
-	if bytes.Compare(j, s.max) > 0 {
+	if compareByteArrays(s.order, j, s.max) > 0 {
 		s.max = j
 	}
 }
 
+// floatStats ignores NaN values, as they can't be ordered. As required by the
+// parquet specification, a min value of zero is written as -0.0 and a max value
+// of zero as +0.0, so that readers don't have to care about the sign of zero.
 type floatStats struct {
-	min float32
-	max float32
+	min   float32
+	max   float32
+	valid bool
 }
 
 func newFloatStats() *floatStats {
@@ -63,29 +172,43 @@ func newFloatStats() *floatStats {
 }
 
 func (s *floatStats) reset() {
-	s.min = math.MaxFloat32
-	s.max = -math.MaxFloat32
+	s.min, s.max, s.valid = 0, 0, false
 }
 
 func (s *floatStats) minValue() []byte {
-	if s.min == math.MaxFloat32 {
+	if !s.valid {
 		return nil
 	}
+	min := s.min
+	if min == 0 {
+		min = float32(math.Copysign(0, -1))
+	}
 	ret := make([]byte, 4)
-	binary.LittleEndian.PutUint32(ret, math.Float32bits(s.min))
+	binary.LittleEndian.PutUint32(ret, math.Float32bits(min))
 	return ret
 }
 
 func (s *floatStats) maxValue() []byte {
-	if s.max == -math.MaxFloat32 {
+	if !s.valid {
 		return nil
 	}
+	max := s.max
+	if max == 0 {
+		max = 0 // turns -0.0 into +0.0
+	}
 	ret := make([]byte, 4)
-	binary.LittleEndian.PutUint32(ret, math.Float32bits(s.max))
+	binary.LittleEndian.PutUint32(ret, math.Float32bits(max))
 	return ret
 }
 
 func (s *floatStats) setMinMax(j float32) {
+	if math.IsNaN(float64(j)) {
+		return
+	}
+	if !s.valid {
+		s.min, s.max, s.valid = j, j, true
+		return
+	}
 	if j < s.min {
 		s.min = j
 	}
@@ -94,9 +217,11 @@ func (s *floatStats) setMinMax(j float32) {
 	}
 }
 
+// doubleStats handles NaN values and zeros the same way as floatStats.
 type doubleStats struct {
-	min float64
-	max float64
+	min   float64
+	max   float64
+	valid bool
 }
 
 func newDoubleStats() *doubleStats {
@@ -106,29 +231,43 @@ func newDoubleStats() *doubleStats {
 }
 
 func (s *doubleStats) reset() {
-	s.min = math.MaxFloat64
-	s.max = -math.MaxFloat64
+	s.min, s.max, s.valid = 0, 0, false
 }
 
 func (s *doubleStats) minValue() []byte {
-	if s.min == math.MaxFloat64 {
+	if !s.valid {
 		return nil
 	}
+	min := s.min
+	if min == 0 {
+		min = math.Copysign(0, -1)
+	}
 	ret := make([]byte, 8)
-	binary.LittleEndian.PutUint64(ret, math.Float64bits(s.min))
+	binary.LittleEndian.PutUint64(ret, math.Float64bits(min))
 	return ret
 }
 
 func (s *doubleStats) maxValue() []byte {
-	if s.max == -math.MaxFloat64 {
+	if !s.valid {
 		return nil
 	}
+	max := s.max
+	if max == 0 {
+		max = 0 // turns -0.0 into +0.0
+	}
 	ret := make([]byte, 8)
-	binary.LittleEndian.PutUint64(ret, math.Float64bits(s.max))
+	binary.LittleEndian.PutUint64(ret, math.Float64bits(max))
 	return ret
 }
 
 func (s *doubleStats) setMinMax(j float64) {
+	if math.IsNaN(j) {
+		return
+	}
+	if !s.valid {
+		s.min, s.max, s.valid = j, j, true
+		return
+	}
 	if j < s.min {
 		s.min = j
 	}
@@ -138,8 +277,10 @@ func (s *doubleStats) setMinMax(j float64) {
 }
 
 type int32Stats struct {
-	min int32
-	max int32
+	min   int32
+	max   int32
+	valid bool
+	order statsOrder
 }
 
 func newInt32Stats() *int32Stats {
@@ -149,12 +290,11 @@ func newInt32Stats() *int32Stats {
 }
 
 func (s *int32Stats) reset() {
-	s.min = math.MaxInt32
-	s.max = math.MinInt32
+	s.min, s.max, s.valid = 0, 0, false
 }
 
 func (s *int32Stats) minValue() []byte {
-	if s.min == math.MaxInt32 {
+	if !s.valid {
 		return nil
 	}
 	ret := make([]byte, 4)
@@ -163,7 +303,7 @@ func (s *int32Stats) minValue() []byte {
 }
 
 func (s *int32Stats) maxValue() []byte {
-	if s.max == math.MinInt32 {
+	if !s.valid {
 		return nil
 	}
 	ret := make([]byte, 4)
@@ -171,18 +311,31 @@ func (s *int32Stats) maxValue() []byte {
 	return ret
 }
 
+func (s *int32Stats) less(a, b int32) bool {
+	if s.order == statsOrderUnsigned {
+		return uint32(a) < uint32(b)
+	}
+	return a < b
+}
+
 func (s *int32Stats) setMinMax(j int32) {
-	if j < s.min {
+	if !s.valid {
+		s.min, s.max, s.valid = j, j, true
+		return
+	}
+	if s.less(j, s.min) {
 		s.min = j
 	}
-	if j > s.max {
+	if s.less(s.max, j) {
 		s.max = j
 	}
 }
 
 type int64Stats struct {
-	min int64
-	max int64
+	min   int64
+	max   int64
+	valid bool
+	order statsOrder
 }
 
 func newInt64Stats() *int64Stats {
@@ -192,12 +345,11 @@ func newInt64Stats() *int64Stats {
 }
 
 func (s *int64Stats) reset() {
-	s.min = math.MaxInt64
-	s.max = math.MinInt64
+	s.min, s.max, s.valid = 0, 0, false
 }
 
 func (s *int64Stats) minValue() []byte {
-	if s.min == math.MaxInt64 {
+	if !s.valid {
 		return nil
 	}
 	ret := make([]byte, 8)
@@ -206,7 +358,7 @@ func (s *int64Stats) minValue() []byte {
 }
 
 func (s *int64Stats) maxValue() []byte {
-	if s.min == math.MinInt64 {
+	if !s.valid {
 		return nil
 	}
 	ret := make([]byte, 8)
@@ -214,11 +366,136 @@ func (s *int64Stats) maxValue() []byte {
 	return ret
 }
 
+func (s *int64Stats) less(a, b int64) bool {
+	if s.order == statsOrderUnsigned {
+		return uint64(a) < uint64(b)
+	}
+	return a < b
+}
+
 func (s *int64Stats) setMinMax(j int64) {
-	if j < s.min {
+	if !s.valid {
+		s.min, s.max, s.valid = j, j, true
+		return
+	}
+	if s.less(j, s.min) {
 		s.min = j
 	}
-	if j > s.max {
+	if s.less(s.max, j) {
 		s.max = j
 	}
 }
+
+// compareStatsValues compares two min or max values as they are stored in the
+// statistics of the provided physical type, using the provided order. It returns
+// -1 if a < b, 0 if a == b and +1 if a > b.
+func compareStatsValues(typ parquet.Type, order statsOrder, a, b []byte) int {
+	switch typ {
+	case parquet.Type_INT32:
+		if len(a) < 4 || len(b) < 4 {
+			break
+		}
+		x, y := binary.LittleEndian.Uint32(a), binary.LittleEndian.Uint32(b)
+		if order == statsOrderUnsigned {
+			return compareUint64(uint64(x), uint64(y))
+		}
+		return compareInt64(int64(int32(x)), int64(int32(y)))
+	case parquet.Type_INT64:
+		if len(a) < 8 || len(b) < 8 {
+			break
+		}
+		x, y := binary.LittleEndian.Uint64(a), binary.LittleEndian.Uint64(b)
+		if order == statsOrderUnsigned {
+			return compareUint64(x, y)
+		}
+		return compareInt64(int64(x), int64(y))
+	case parquet.Type_FLOAT:
+		if len(a) < 4 || len(b) < 4 {
+			break
+		}
+		return compareFloat64(float64(math.Float32frombits(binary.LittleEndian.Uint32(a))), float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case parquet.Type_DOUBLE:
+		if len(a) < 8 || len(b) < 8 {
+			break
+		}
+		return compareFloat64(math.Float64frombits(binary.LittleEndian.Uint64(a)), math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return compareByteArrays(order, a, b)
+	}
+
+	return bytes.Compare(a, b)
+}
+
+// compareByteArrays compares two byte arrays either lexicographically as unsigned bytes,
+// or as big endian two's complement numbers of possibly different lengths.
+func compareByteArrays(order statsOrder, a, b []byte) int {
+	if order == statsOrderUnsigned {
+		return bytes.Compare(a, b)
+	}
+
+	negA := len(a) > 0 && a[0]&0x80 != 0
+	negB := len(b) > 0 && b[0]&0x80 != 0
+	switch {
+	case negA && !negB:
+		return -1
+	case !negA && negB:
+		return 1
+	}
+
+	// both numbers have the same sign, so the shorter one is sign-extended
+	// and the remaining bytes are compared as unsigned bytes.
+	var ext byte
+	if negA {
+		ext = 0xff
+	}
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		x, y := ext, ext
+		if j := i - (n - len(a)); j >= 0 {
+			x = a[j]
+		}
+		if j := i - (n - len(b)); j >= 0 {
+			y = b[j]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,247 @@
+package goparquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnStatsOrder(t *testing.T) {
+	uint32Type := parquet.ConvertedType_UINT_32
+	decimalType := parquet.ConvertedType_DECIMAL
+
+	tests := []struct {
+		typ      parquet.Type
+		params   *ColumnParameters
+		expected statsOrder
+	}{
+		{parquet.Type_INT32, &ColumnParameters{}, statsOrderSigned},
+		{parquet.Type_INT32, &ColumnParameters{ConvertedType: &uint32Type}, statsOrderUnsigned},
+		{parquet.Type_INT64, &ColumnParameters{LogicalType: &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 64, IsSigned: false}}}, statsOrderUnsigned},
+		{parquet.Type_INT64, &ColumnParameters{LogicalType: &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 64, IsSigned: true}}}, statsOrderSigned},
+		{parquet.Type_BYTE_ARRAY, &ColumnParameters{}, statsOrderUnsigned},
+		{parquet.Type_BYTE_ARRAY, nil, statsOrderUnsigned},
+		{parquet.Type_FIXED_LEN_BYTE_ARRAY, &ColumnParameters{ConvertedType: &decimalType}, statsOrderSigned},
+		{parquet.Type_FIXED_LEN_BYTE_ARRAY, &ColumnParameters{LogicalType: &parquet.LogicalType{DECIMAL: &parquet.DecimalType{Precision: 10, Scale: 2}}}, statsOrderSigned},
+	}
+
+	for idx, tt := range tests {
+		require.Equal(t, tt.expected, columnStatsOrder(tt.typ, tt.params), "%d. order doesn't match", idx)
+	}
+}
+
+func TestCompareByteArrays(t *testing.T) {
+	require.Equal(t, -1, compareByteArrays(statsOrderUnsigned, []byte{0x01}, []byte{0xff}))
+
+	tests := []struct {
+		a, b     []byte
+		expected int
+	}{
+		{[]byte{0x01}, []byte{0xff}, 1},
+		{[]byte{0xff}, []byte{0x01}, -1},
+		{[]byte{0xff, 0xfe}, []byte{0xff, 0xff}, -1},
+		{[]byte{0x00, 0x01}, []byte{0x01}, 0},
+		{[]byte{0xff}, []byte{0xff, 0xff}, 0},
+		{[]byte{0x01, 0x00}, []byte{0x7f}, 1},
+		{[]byte{0xfe}, []byte{0xff, 0x00}, 1},
+		{[]byte{}, []byte{0x00}, 0},
+	}
+
+	for idx, tt := range tests {
+		require.Equal(t, tt.expected, compareByteArrays(statsOrderSigned, tt.a, tt.b), "%d. comparison of %x and %x doesn't match", idx, tt.a, tt.b)
+	}
+}
+
+func TestIntStatsOrder(t *testing.T) {
+	s := newInt32Stats()
+	require.Nil(t, s.minValue())
+	require.Nil(t, s.maxValue())
+
+	for _, v := range []int32{math.MaxInt32, -1, 5} {
+		s.setMinMax(v)
+	}
+	require.Equal(t, int32(-1), int32(binary.LittleEndian.Uint32(s.minValue())))
+	require.Equal(t, int32(math.MaxInt32), int32(binary.LittleEndian.Uint32(s.maxValue())))
+
+	u := newInt64Stats()
+	u.order = statsOrderUnsigned
+	for _, v := range []int64{-1, 5, math.MinInt64} {
+		u.setMinMax(v)
+	}
+	require.Equal(t, uint64(5), binary.LittleEndian.Uint64(u.minValue()))
+	require.Equal(t, uint64(math.MaxUint64), binary.LittleEndian.Uint64(u.maxValue()))
+
+	u.reset()
+	require.Nil(t, u.minValue())
+	require.Nil(t, u.maxValue())
+	require.Equal(t, statsOrderUnsigned, u.order)
+}
+
+func TestFloatStatsSpecialValues(t *testing.T) {
+	s := newDoubleStats()
+	s.setMinMax(math.NaN())
+	require.Nil(t, s.minValue())
+	require.Nil(t, s.maxValue())
+
+	s.setMinMax(0)
+	s.setMinMax(math.NaN())
+	require.Equal(t, math.Float64bits(math.Copysign(0, -1)), binary.LittleEndian.Uint64(s.minValue()))
+	require.Equal(t, uint64(0), binary.LittleEndian.Uint64(s.maxValue()))
+
+	f := newFloatStats()
+	f.setMinMax(float32(math.Copysign(0, -1)))
+	f.setMinMax(math.MaxFloat32)
+	require.Equal(t, math.Float32bits(float32(math.Copysign(0, -1))), binary.LittleEndian.Uint32(f.minValue()))
+	require.Equal(t, math.Float32bits(math.MaxFloat32), binary.LittleEndian.Uint32(f.maxValue()))
+}
+
+func TestWriteTypeDefinedOrderStats(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required int32 u32 (UINT_32);
+		required int64 u64 (INT(64, false));
+		required int64 i64;
+		required fixed_len_byte_array(2) dec (DECIMAL(4,2));
+		required binary name (STRING);
+	}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fw := NewFileWriter(&buf, WithSchemaDefinition(sd))
+
+	rows := []struct {
+		u32  uint32
+		u64  uint64
+		i64  int64
+		dec  []byte
+		name string
+	}{
+		{1, math.MaxUint64, math.MaxInt64, []byte{0x00, 0x10}, "foo"},
+		{math.MaxUint32, 2, -3, []byte{0xff, 0x00}, "bar"},
+		{7, 0, 4, []byte{0x7f, 0xff}, "zzz"},
+	}
+	for _, row := range rows {
+		require.NoError(t, fw.AddData(map[string]interface{}{
+			"u32":  int32(row.u32),
+			"u64":  int64(row.u64),
+			"i64":  row.i64,
+			"dec":  row.dec,
+			"name": []byte(row.name),
+		}))
+	}
+	require.NoError(t, fw.Close())
+
+	meta, err := ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+
+	require.Len(t, meta.ColumnOrders, 5)
+	for _, order := range meta.ColumnOrders {
+		require.NotNil(t, order.TYPE_ORDER)
+	}
+
+	stats := func(idx int) *parquet.Statistics {
+		return meta.RowGroups[0].Columns[idx].MetaData.Statistics
+	}
+
+	require.Equal(t, uint32(1), binary.LittleEndian.Uint32(stats(0).MinValue))
+	require.Equal(t, uint32(math.MaxUint32), binary.LittleEndian.Uint32(stats(0).MaxValue))
+
+	require.Equal(t, uint64(0), binary.LittleEndian.Uint64(stats(1).MinValue))
+	require.Equal(t, uint64(math.MaxUint64), binary.LittleEndian.Uint64(stats(1).MaxValue))
+
+	require.Equal(t, int64(-3), int64(binary.LittleEndian.Uint64(stats(2).MinValue)))
+	require.Equal(t, int64(math.MaxInt64), int64(binary.LittleEndian.Uint64(stats(2).MaxValue)))
+
+	require.Equal(t, []byte{0xff, 0x00}, stats(3).MinValue)
+	require.Equal(t, []byte{0x7f, 0xff}, stats(3).MaxValue)
+
+	require.Equal(t, []byte("bar"), stats(4).MinValue)
+	require.Equal(t, []byte("zzz"), stats(4).MaxValue)
+}
+
+func TestTruncateStatsValues(t *testing.T) {
+	long := []byte("abcdefghij")
+	tests := []struct {
+		typ      parquet.Type
+		order    statsOrder
+		value    []byte
+		length   int
+		min, max []byte
+	}{
+		{parquet.Type_BYTE_ARRAY, statsOrderUnsigned, long, 4, []byte("abcd"), []byte("abce")},
+		{parquet.Type_BYTE_ARRAY, statsOrderUnsigned, long, 10, long, long},
+		{parquet.Type_BYTE_ARRAY, statsOrderUnsigned, long, -1, long, long},
+		{parquet.Type_BYTE_ARRAY, statsOrderUnsigned, []byte{0x01, 0xff, 0xff, 0x00}, 3, []byte{0x01, 0xff, 0xff}, []byte{0x02}},
+		{parquet.Type_BYTE_ARRAY, statsOrderUnsigned, []byte{0xff, 0xff, 0x00}, 2, []byte{0xff, 0xff}, []byte{0xff, 0xff, 0x00}},
+		{parquet.Type_BYTE_ARRAY, statsOrderSigned, long, 4, long, long},
+		{parquet.Type_FIXED_LEN_BYTE_ARRAY, statsOrderUnsigned, long, 4, long, long},
+	}
+
+	for _, tt := range tests {
+		min := truncateMinValue(tt.typ, tt.order, tt.value, tt.length)
+		max := truncateMaxValue(tt.typ, tt.order, tt.value, tt.length)
+		require.Equal(t, tt.min, min)
+		require.Equal(t, tt.max, max)
+		require.LessOrEqual(t, compareByteArrays(tt.order, min, tt.value), 0)
+		require.GreaterOrEqual(t, compareByteArrays(tt.order, max, tt.value), 0)
+	}
+}
+
+func TestWriteTruncatedStats(t *testing.T) {
+	sd, err := parquetschema.ParseSchemaDefinition(`message test {
+		required binary blob;
+		required int96 ts;
+	}`)
+	require.NoError(t, err)
+
+	blobs := [][]byte{
+		bytes.Repeat([]byte("b"), 1000),
+		bytes.Repeat([]byte("a"), 100),
+		append(bytes.Repeat([]byte("c"), 63), bytes.Repeat([]byte{0xff}, 100)...),
+	}
+
+	write := func(opts ...FileWriterOption) *parquet.FileMetaData {
+		var buf bytes.Buffer
+		fw := NewFileWriter(&buf, append([]FileWriterOption{WithSchemaDefinition(sd), WithPageIndex(true)}, opts...)...)
+		for i, blob := range blobs {
+			require.NoError(t, fw.AddData(map[string]interface{}{"blob": blob, "ts": [12]byte{byte(i)}}))
+		}
+		require.NoError(t, fw.Close())
+
+		r, err := NewFileReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+
+		// INT96 values have no defined order, so there are neither statistics nor a column index.
+		ci, err := r.ColumnIndex(0, ColumnPath{"ts"})
+		require.NoError(t, err)
+		require.Nil(t, ci)
+
+		ci, err = r.ColumnIndex(0, ColumnPath{"blob"})
+		require.NoError(t, err)
+		require.NotNil(t, ci)
+
+		fileMeta, err := ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+		require.NoError(t, err)
+		require.Nil(t, fileMeta.RowGroups[0].Columns[1].MetaData.Statistics.MinValue)
+		require.Nil(t, fileMeta.RowGroups[0].Columns[1].MetaData.Statistics.MaxValue)
+		require.Equal(t, fileMeta.RowGroups[0].Columns[0].MetaData.Statistics.MinValue, ci.MinValues[0])
+		require.Equal(t, fileMeta.RowGroups[0].Columns[0].MetaData.Statistics.MaxValue, ci.MaxValues[0])
+		return fileMeta
+	}
+
+	stats := write().RowGroups[0].Columns[0].MetaData.Statistics
+	require.Equal(t, bytes.Repeat([]byte("a"), 64), stats.MinValue)
+	require.Equal(t, append(bytes.Repeat([]byte("c"), 62), 'd'), stats.MaxValue)
+
+	stats = write(WithStatisticsTruncateLength(8)).RowGroups[0].Columns[0].MetaData.Statistics
+	require.Equal(t, bytes.Repeat([]byte("a"), 8), stats.MinValue)
+	require.Equal(t, []byte("cccccccd"), stats.MaxValue)
+
+	stats = write(WithStatisticsTruncateLength(-1)).RowGroups[0].Columns[0].MetaData.Statistics
+	require.Equal(t, blobs[1], stats.MinValue)
+	require.Equal(t, blobs[2], stats.MaxValue)
+}
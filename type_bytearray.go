@@ -341,6 +341,9 @@ func (is *byteArrayStore) reset(repetitionType parquet.FieldRepetitionType) {
 
 	is.stats.reset()
 	is.pageStats.reset()
+
+	order := columnStatsOrder(is.parquetType(), is.ColumnParameters)
+	is.stats.order, is.pageStats.order = order, order
 }
 
 func (is *byteArrayStore) setMinMax(j []byte) error {
@@ -362,6 +365,9 @@ func (is *byteArrayStore) getValues(v interface{}) ([]interface{}, error) {
 	var vals []interface{}
 	switch typed := v.(type) {
 	case []byte:
+		if err := is.setMinMax(typed); err != nil {
+			return nil, err
+		}
 		vals = []interface{}{typed}
 	case [][]byte:
 		if is.repTyp != parquet.FieldRepetitionType_REPEATED {
@@ -369,17 +375,28 @@ func (is *byteArrayStore) getValues(v interface{}) ([]interface{}, error) {
 		}
 		vals = make([]interface{}, len(typed))
 		for j := range typed {
+			if err := is.setMinMax(typed[j]); err != nil {
+				return nil, err
+			}
 			vals[j] = typed[j]
 		}
 	case string:
-		vals = []interface{}{[]byte(typed)}
+		b := []byte(typed)
+		if err := is.setMinMax(b); err != nil {
+			return nil, err
+		}
+		vals = []interface{}{b}
 	case []string:
 		if is.repTyp != parquet.FieldRepetitionType_REPEATED {
 			return nil, fmt.Errorf("the value is not repeated but it is an array")
 		}
 		vals = make([]interface{}, len(typed))
 		for j := range typed {
-			vals[j] = []byte(typed[j])
+			b := []byte(typed[j])
+			if err := is.setMinMax(b); err != nil {
+				return nil, err
+			}
+			vals[j] = b
 		}
 	default:
 		return nil, fmt.Errorf("unsupported type for storing in []byte column %T => %+v", v, v)
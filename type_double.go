@@ -31,6 +31,17 @@ func (d *doublePlainDecoder) decodeValues(dst []interface{}) (int, error) {
 	return len(dst), nil
 }
 
+func (d *doublePlainDecoder) decodeTypedValues(dst interface{}) (int, error) {
+	values, ok := dst.([]float64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %T values for double decoder", dst)
+	}
+	if err := binary.Read(d.r, binary.LittleEndian, values); err != nil {
+		return 0, err
+	}
+	return len(values), nil
+}
+
 type doublePlainEncoder struct {
 	w io.Writer
 }
@@ -54,6 +65,44 @@ func (d *doublePlainEncoder) encodeValues(values []interface{}) error {
 	return binary.Write(d.w, binary.LittleEndian, data)
 }
 
+type doubleByteStreamSplitDecoder struct {
+	byteStreamSplitDecoder
+}
+
+func (d *doubleByteStreamSplitDecoder) init(r io.Reader) error {
+	return d.byteStreamSplitDecoder.init(r, 8)
+}
+
+func (d *doubleByteStreamSplitDecoder) decodeValues(dst []interface{}) (int, error) {
+	var buf [8]byte
+	for i := range dst {
+		if !d.next(buf[:]) {
+			return i, io.EOF
+		}
+		dst[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+	}
+
+	return len(dst), nil
+}
+
+type doubleByteStreamSplitEncoder struct {
+	byteStreamSplitEncoder
+}
+
+func (e *doubleByteStreamSplitEncoder) init(w io.Writer) error {
+	return e.byteStreamSplitEncoder.init(w, 8)
+}
+
+func (e *doubleByteStreamSplitEncoder) encodeValues(values []interface{}) error {
+	var buf [8]byte
+	for i := range values {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(values[i].(float64)))
+		e.buf.Write(buf[:])
+	}
+
+	return nil
+}
+
 type doubleStore struct {
 	repTyp parquet.FieldRepetitionType
 
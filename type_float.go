@@ -31,6 +31,17 @@ func (f *floatPlainDecoder) decodeValues(dst []interface{}) (int, error) {
 	return len(dst), nil
 }
 
+func (f *floatPlainDecoder) decodeTypedValues(dst interface{}) (int, error) {
+	values, ok := dst.([]float32)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %T values for float decoder", dst)
+	}
+	if err := binary.Read(f.r, binary.LittleEndian, values); err != nil {
+		return 0, err
+	}
+	return len(values), nil
+}
+
 type floatPlainEncoder struct {
 	w io.Writer
 }
@@ -54,6 +65,44 @@ func (d *floatPlainEncoder) encodeValues(values []interface{}) error {
 	return binary.Write(d.w, binary.LittleEndian, data)
 }
 
+type floatByteStreamSplitDecoder struct {
+	byteStreamSplitDecoder
+}
+
+func (d *floatByteStreamSplitDecoder) init(r io.Reader) error {
+	return d.byteStreamSplitDecoder.init(r, 4)
+}
+
+func (d *floatByteStreamSplitDecoder) decodeValues(dst []interface{}) (int, error) {
+	var buf [4]byte
+	for i := range dst {
+		if !d.next(buf[:]) {
+			return i, io.EOF
+		}
+		dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[:]))
+	}
+
+	return len(dst), nil
+}
+
+type floatByteStreamSplitEncoder struct {
+	byteStreamSplitEncoder
+}
+
+func (e *floatByteStreamSplitEncoder) init(w io.Writer) error {
+	return e.byteStreamSplitEncoder.init(w, 4)
+}
+
+func (e *floatByteStreamSplitEncoder) encodeValues(values []interface{}) error {
+	var buf [4]byte
+	for i := range values {
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(values[i].(float32)))
+		e.buf.Write(buf[:])
+	}
+
+	return nil
+}
+
 type floatStore struct {
 	repTyp parquet.FieldRepetitionType
 
@@ -30,6 +30,17 @@ func (i *int32PlainDecoder) decodeValues(dst []interface{}) (int, error) {
 	return len(dst), nil
 }
 
+func (i *int32PlainDecoder) decodeTypedValues(dst interface{}) (int, error) {
+	values, ok := dst.([]int32)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %T values for int32 decoder", dst)
+	}
+	if err := binary.Read(i.r, binary.LittleEndian, values); err != nil {
+		return 0, err
+	}
+	return len(values), nil
+}
+
 type int32PlainEncoder struct {
 	w io.Writer
 }
@@ -68,6 +79,21 @@ func (d *int32DeltaBPDecoder) decodeValues(dst []interface{}) (int, error) {
 	return len(dst), nil
 }
 
+func (d *int32DeltaBPDecoder) decodeTypedValues(dst interface{}) (int, error) {
+	values, ok := dst.([]int32)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %T values for int32 decoder", dst)
+	}
+	for i := range values {
+		u, err := d.next()
+		if err != nil {
+			return i, err
+		}
+		values[i] = u
+	}
+	return len(values), nil
+}
+
 type int32DeltaBPEncoder struct {
 	deltaBitPackEncoder32
 }
@@ -125,6 +151,9 @@ func (is *int32Store) reset(rep parquet.FieldRepetitionType) {
 	is.repTyp = rep
 	is.stats.reset()
 	is.pageStats.reset()
+
+	order := columnStatsOrder(parquet.Type_INT32, is.ColumnParameters)
+	is.stats.order, is.pageStats.order = order, order
 }
 
 func (is *int32Store) setMinMax(j int32) {
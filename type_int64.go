@@ -30,6 +30,17 @@ func (i *int64PlainDecoder) decodeValues(dst []interface{}) (int, error) {
 	return len(dst), nil
 }
 
+func (i *int64PlainDecoder) decodeTypedValues(dst interface{}) (int, error) {
+	values, ok := dst.([]int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %T values for int64 decoder", dst)
+	}
+	if err := binary.Read(i.r, binary.LittleEndian, values); err != nil {
+		return 0, err
+	}
+	return len(values), nil
+}
+
 type int64PlainEncoder struct {
 	w io.Writer
 }
@@ -68,6 +79,21 @@ func (d *int64DeltaBPDecoder) decodeValues(dst []interface{}) (int, error) {
 	return len(dst), nil
 }
 
+func (d *int64DeltaBPDecoder) decodeTypedValues(dst interface{}) (int, error) {
+	values, ok := dst.([]int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected %T values for int64 decoder", dst)
+	}
+	for i := range values {
+		u, err := d.next()
+		if err != nil {
+			return i, err
+		}
+		values[i] = u
+	}
+	return len(values), nil
+}
+
 type int64DeltaBPEncoder struct {
 	deltaBitPackEncoder64
 }
@@ -125,6 +151,9 @@ func (is *int64Store) reset(rep parquet.FieldRepetitionType) {
 	is.repTyp = rep
 	is.stats.reset()
 	is.pageStats.reset()
+
+	order := columnStatsOrder(parquet.Type_INT64, is.ColumnParameters)
+	is.stats.order, is.pageStats.order = order, order
 }
 
 func (is *int64Store) setMinMax(j int64) {
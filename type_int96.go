@@ -76,6 +76,16 @@ func (*int96Store) sizeOf(v interface{}) int {
 	return 12
 }
 
+// getStats returns no statistics, as the sort order of INT96 values is undefined.
+func (is *int96Store) getStats() minMaxValues {
+	return &nilStats{}
+}
+
+// getPageStats returns no statistics, see getStats.
+func (is *int96Store) getPageStats() minMaxValues {
+	return &nilStats{}
+}
+
 func (is *int96Store) parquetType() parquet.Type {
 	return parquet.Type_INT96
 }
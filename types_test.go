@@ -92,6 +92,22 @@ var (
 				return rand.Float32()
 			},
 		},
+		{
+			name: "DoubleByteStreamSplit",
+			enc:  &doubleByteStreamSplitEncoder{},
+			dec:  &doubleByteStreamSplitDecoder{},
+			rand: func() interface{} {
+				return rand.Float64()
+			},
+		},
+		{
+			name: "FloatByteStreamSplit",
+			enc:  &floatByteStreamSplitEncoder{},
+			dec:  &floatByteStreamSplitDecoder{},
+			rand: func() interface{} {
+				return rand.Float32()
+			},
+		},
 		{
 			name: "BooleanRLE",
 			enc:  &booleanRLEEncoder{},